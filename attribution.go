@@ -0,0 +1,135 @@
+package main
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+	"github.com/OpticalFlyer/goliath/ui"
+)
+
+// Layout of the attribution bar, in logical pixels
+const (
+	attributionPadding   = 4
+	attributionSeparator = " | "
+)
+
+var attributionLinkColor = color.RGBA{160, 200, 255, 255}
+
+// attributionBar credits the data of the visible layers in the bottom right
+// corner of the map. Clicking a credit opens its link.
+type attributionBar struct {
+	// Credits where they were last drawn, for hit testing
+	boxes []attributionBox
+}
+
+// attributionBox is one credit and where it was drawn
+type attributionBox struct {
+	x, y, width int
+	attribution tilemap.Attribution
+}
+
+// draw lays out and draws the credits on the logical-size screen. They share
+// one line when it fits, otherwise each gets a line of its own.
+func (b *attributionBar) draw(screen *ebiten.Image, attrs []tilemap.Attribution) {
+	b.boxes = b.boxes[:0]
+	if len(attrs) == 0 {
+		return
+	}
+	texts := make([]string, len(attrs))
+	lineWidth := (len(attrs) - 1) * len(attributionSeparator) * charWidth
+	for i, a := range attrs {
+		texts[i] = latin1(a.Text)
+		lineWidth += textWidth(texts[i])
+	}
+
+	sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+	oneLine := lineWidth+2*attributionPadding <= sw
+	if oneLine {
+		x := sw - attributionPadding - lineWidth
+		y := sh - attributionPadding - lineHeight
+		b.drawBackground(screen, x, y, lineWidth, 1)
+		for i, a := range attrs {
+			if i > 0 {
+				ebitenutil.DebugPrintAt(screen, attributionSeparator, x, y)
+				x += textWidth(attributionSeparator)
+			}
+			b.drawCredit(screen, texts[i], a, x, y)
+			x += textWidth(texts[i])
+		}
+		return
+	}
+
+	widest := 0
+	for _, text := range texts {
+		widest = max(widest, textWidth(text))
+	}
+	top := sh - attributionPadding - len(attrs)*lineHeight
+	b.drawBackground(screen, sw-attributionPadding-widest, top, widest, len(attrs))
+	for i, a := range attrs {
+		x := sw - attributionPadding - textWidth(texts[i])
+		b.drawCredit(screen, texts[i], a, x, top+i*lineHeight)
+	}
+}
+
+// drawBackground darkens the area behind the credits so they read on any map
+func (b *attributionBar) drawBackground(screen *ebiten.Image, x, y, width, lines int) {
+	vector.DrawFilledRect(screen,
+		float32(x-attributionPadding), float32(y-attributionPadding/2),
+		float32(width+2*attributionPadding), float32(lines*lineHeight+attributionPadding),
+		labelBackground, false)
+}
+
+// drawCredit draws one credit, underlining it if it links somewhere
+func (b *attributionBar) drawCredit(screen *ebiten.Image, text string, a tilemap.Attribution, x, y int) {
+	ebitenutil.DebugPrintAt(screen, text, x, y)
+	width := textWidth(text)
+	if a.URL != "" {
+		underline := float32(y + lineHeight - 2)
+		vector.StrokeLine(screen, float32(x), underline, float32(x+width), underline, 1, attributionLinkColor, false)
+	}
+	b.boxes = append(b.boxes, attributionBox{x: x, y: y, width: width, attribution: a})
+}
+
+// at returns the credit drawn at a point
+func (b *attributionBar) at(x, y int) (tilemap.Attribution, bool) {
+	for _, box := range b.boxes {
+		if x >= box.x && x < box.x+box.width && y >= box.y && y < box.y+lineHeight {
+			return box.attribution, true
+		}
+	}
+	return tilemap.Attribution{}, false
+}
+
+// clicked reports whether a click or tap this frame landed on a credit, and
+// opens the credit's link if it has one
+func (b *attributionBar) clicked() bool {
+	var points [][2]int
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x, y := ui.CursorPosition()
+		points = append(points, [2]int{x, y})
+	}
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		x, y := ui.TouchPosition(id)
+		points = append(points, [2]int{x, y})
+	}
+
+	for _, p := range points {
+		a, ok := b.at(p[0], p[1])
+		if !ok {
+			continue
+		}
+		if a.URL != "" {
+			if err := openURL(a.URL); err != nil {
+				log.Printf("Opening %s failed: %v", a.URL, err)
+			}
+		}
+		return true
+	}
+	return false
+}
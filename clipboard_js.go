@@ -0,0 +1,18 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// copyToClipboard puts text on the clipboard with the browser's Clipboard API
+func copyToClipboard(text string) error {
+	clipboard := js.Global().Get("navigator").Get("clipboard")
+	if clipboard.IsUndefined() {
+		return errors.New("the clipboard needs a secure (https) page")
+	}
+	clipboard.Call("writeText", text)
+	return nil
+}
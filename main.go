@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
 	"log"
-	"time"
+	"math"
+	"os"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -19,25 +22,48 @@ import (
 const (
 	initialLat  = 39.8333 // Approx. center of contiguous US (Kansas)
 	initialLon  = -98.5833
-	initialZoom = 4 // Start with a bit more zoom to see the US
+	initialZoom = 4.0 // Start with a bit more zoom to see the US
+
+	// Zoom levels per unit of mouse wheel delta. Trackpads report fractional
+	// deltas, which zoom proportionally.
+	wheelZoomRate = 1.0
 )
 
 // Goliath implements ebiten.Game interface.
 type Goliath struct {
 	tileMap   *tilemap.TileMap
+	diskCache *tilemap.DiskCache
 	debugMode bool
 	ui        *ui.Controller
 
+	// hud holds everything drawn over the map in logical pixels; it is
+	// scaled up to the device resolution of the screen
+	hud *ebiten.Image
+
+	// Credits for the visible layers' data
+	attribution attributionBar
+
+	// Distance and area measurement
+	measure *measureTool
+
 	// Mouse panning state
 	isDragging bool
 	lastMouseX int
 	lastMouseY int
 
-	lastZoomTime float64 // Track last zoom time
-
 	// Touch state for multi-touch interactions
-	lastTouchX map[ebiten.TouchID]float64
-	lastTouchY map[ebiten.TouchID]float64
+	lastTouchX   map[ebiten.TouchID]float64
+	lastTouchY   map[ebiten.TouchID]float64
+	touchPanning bool
+
+	// Area download state
+	selectingArea bool
+	areaDragging  bool
+	areaStartX    int
+	areaStartY    int
+	areaEndX      int
+	areaEndY      int
+	seed          *seedJob
 }
 
 func (g *Goliath) Update() error {
@@ -62,13 +88,24 @@ func (g *Goliath) Update() error {
 			g.tileMap.ZoomOut()
 		}
 
-		// Handle mouse wheel zooming with time-based throttling
-		currentTime := float64(time.Now().UnixNano()) / 1e9 // Current time in seconds
+		// Reset rotation to north-up
+		if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+			g.tileMap.ResetNorth()
+		}
+
+		// Number keys show and hide the layers above the basemap
+		layers := g.tileMap.Layers()
+		for i := 1; i < len(layers) && i <= 9; i++ {
+			if inpututil.IsKeyJustPressed(ebiten.KeyDigit0 + ebiten.Key(i)) {
+				layers[i].Visible = !layers[i].Visible
+			}
+		}
+
+		// Handle mouse wheel zooming
 		_, wheelY := ebiten.Wheel()
-		if wheelY != 0 && (currentTime-g.lastZoomTime) > 0.1 { // 100ms between zooms
-			x, y := ebiten.CursorPosition()
-			g.tileMap.ZoomAtPoint(wheelY > 0, float64(x), float64(y))
-			g.lastZoomTime = currentTime
+		if wheelY != 0 {
+			x, y := ui.CursorPosition()
+			g.tileMap.ZoomBy(wheelY*wheelZoomRate, float64(x), float64(y))
 		}
 
 		// Handle keyboard panning
@@ -85,27 +122,50 @@ func (g *Goliath) Update() error {
 			g.tileMap.Pan(tilemap.PanDown)
 		}
 
+		// Drag out an area to download, if selecting one
+		g.updateAreaSelection()
+
+		// Clicking a credit opens its link rather than panning
+		onCredit := !g.selectingArea && g.attribution.clicked()
+
+		// Click out points to measure
+		if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+			g.startMeasure()
+		}
+		if !g.selectingArea && !onCredit {
+			g.measure.update(g.tileMap)
+		}
+
 		// Handle mouse panning
-		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !g.selectingArea && !g.areaDragging && !onCredit {
 			// Start dragging
 			g.isDragging = true
-			g.lastMouseX, g.lastMouseY = ebiten.CursorPosition()
-		} else if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-			// Stop dragging
+			g.lastMouseX, g.lastMouseY = ui.CursorPosition()
+			g.tileMap.BeginDrag()
+		} else if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) && g.isDragging {
+			// Stop dragging and let the map glide
 			g.isDragging = false
+			g.tileMap.EndDrag()
 		}
 
 		if g.isDragging {
 			// Get current mouse position
-			currentX, currentY := ebiten.CursorPosition()
+			currentX, currentY := ui.CursorPosition()
 
 			// Calculate the difference from last position
 			dx := float64(currentX - g.lastMouseX)
 			dy := float64(currentY - g.lastMouseY)
 
-			// Pan the map
-			if dx != 0 || dy != 0 {
-				g.tileMap.PanBy(dx, dy)
+			if ebiten.IsKeyPressed(ebiten.KeyAlt) {
+				// Alt+drag rotates the map around the screen center
+				centerX := float64(g.tileMap.ScreenWidth) / 2
+				centerY := float64(g.tileMap.ScreenHeight) / 2
+				prevAngle := math.Atan2(float64(g.lastMouseY)-centerY, float64(g.lastMouseX)-centerX)
+				angle := math.Atan2(float64(currentY)-centerY, float64(currentX)-centerX)
+				g.tileMap.RotateBy(angleDelta(prevAngle, angle))
+			} else {
+				// Pan the map, tracking velocity for kinetic panning
+				g.tileMap.DragBy(dx, dy)
 			}
 
 			// Update last position
@@ -117,15 +177,26 @@ func (g *Goliath) Update() error {
 		g.handleTouchEvents()
 	}
 
+	// Advance zoom animations
+	g.tileMap.Update()
+
 	return nil
 }
 
 func (g *Goliath) Draw(screen *ebiten.Image) {
-	// Draw the tile map and get the visible range for debug info
+	// Draw the tile map at device resolution and get the visible range for debug info
 	tileRange := g.tileMap.Draw(screen, g.debugMode)
 
+	// Everything else is laid out in logical pixels
+	hud := g.hudImage()
+	hud.Clear()
+
+	g.attribution.draw(hud, g.tileMap.Attributions())
+	g.measure.draw(hud, g.tileMap)
+	g.drawAreaSelection(hud)
+
 	// Draw UI
-	g.ui.Draw(screen)
+	g.ui.Draw(hud)
 
 	// Draw debug overlay if enabled
 	if g.debugMode {
@@ -137,31 +208,103 @@ func (g *Goliath) Draw(screen *ebiten.Image) {
 		centerY := float32(g.tileMap.ScreenHeight / 2)
 		crosshairSize := float32(10.0)
 
-		vector.StrokeLine(screen,
+		vector.StrokeLine(hud,
 			centerX-crosshairSize, centerY,
 			centerX+crosshairSize, centerY,
 			strokeWidth, redColor, false)
-		vector.StrokeLine(screen,
+		vector.StrokeLine(hud,
 			centerX, centerY-crosshairSize,
 			centerX, centerY+crosshairSize,
 			strokeWidth, redColor, false)
 
 		// Draw debug text
-		debugText := fmt.Sprintf("Lat: %.4f\nLon: %.4f\nZoom: %d\nTiles: %d,%d - %d,%d",
-			g.tileMap.CenterLat, g.tileMap.CenterLon, g.tileMap.Zoom,
-			tileRange.MinX, tileRange.MinY, tileRange.MaxX, tileRange.MaxY)
-		ebitenutil.DebugPrint(screen, debugText)
+		stats := g.tileMap.CacheStats()
+		debugText := fmt.Sprintf("Lat: %.4f\nLon: %.4f\nZoom: %.2f\nBearing: %.1f\nTiles: %d,%d - %d,%d\n"+
+			"Cache: %d tiles, %.1f MB\nHits: %d Misses: %d Evictions: %d",
+			g.tileMap.CenterLat, g.tileMap.CenterLon, g.tileMap.Zoom, g.tileMap.Bearing,
+			tileRange.MinX, tileRange.MinY, tileRange.MaxX, tileRange.MaxY,
+			stats.Tiles, float64(stats.Bytes)/(1<<20), stats.Hits, stats.Misses, stats.Evictions)
+		ebitenutil.DebugPrint(hud, debugText)
 	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(g.tileMap.DeviceScale(), g.tileMap.DeviceScale())
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(hud, op)
 }
 
+// Layout sizes the screen in device pixels so tiles stay sharp on HiDPI
+// displays. The map and UI keep working in logical pixels.
 func (g *Goliath) Layout(outsideWidth, outsideHeight int) (int, int) {
+	scale := ebiten.Monitor().DeviceScaleFactor()
+	g.tileMap.SetDeviceScale(scale)
+	ui.SetDeviceScale(scale)
+
 	g.tileMap.ScreenWidth = outsideWidth
 	g.tileMap.ScreenHeight = outsideHeight
 	g.ui.UpdateWindowSize(outsideWidth, outsideHeight)
-	return outsideWidth, outsideHeight
+	return int(math.Ceil(float64(outsideWidth) * scale)), int(math.Ceil(float64(outsideHeight) * scale))
+}
+
+// hudImage returns the logical-size image drawn over the map
+func (g *Goliath) hudImage() *ebiten.Image {
+	w, h := g.tileMap.ScreenWidth, g.tileMap.ScreenHeight
+	if g.hud == nil || g.hud.Bounds().Dx() != w || g.hud.Bounds().Dy() != h {
+		if g.hud != nil {
+			g.hud.Dispose()
+		}
+		g.hud = ebiten.NewImage(w, h)
+	}
+	return g.hud
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		if err := runSeed(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	mbtilesPath := flag.String("mbtiles", "", "path to an MBTiles file to use as an offline basemap")
+	pmtilesPath := flag.String("pmtiles", "", "path or URL of a PMTiles archive to use as the basemap")
+	styleName := flag.String("style", "light", "style for vector basemaps: light, dark or high-contrast")
+	wmsURL := flag.String("wms", "", "URL of a WMS service to draw over the basemap")
+	wmsLayers := flag.String("wms-layers", "", "comma-separated WMS layers; lists the available layers if empty")
+	wmsStyles := flag.String("wms-styles", "", "comma-separated WMS styles, one per layer (default styles if empty)")
+	wmsVersion := flag.String("wms-version", "", "WMS version, 1.1.1 or 1.3.0 (default from the service)")
+	wmsOpacity := flag.Float64("wms-opacity", 0.7, "opacity of the WMS overlay, from 0 to 1")
+	wmtsURL := flag.String("wmts", "", "URL of a WMTS service or capabilities document to draw over the basemap")
+	wmtsLayers := flag.String("wmts-layers", "", "comma-separated WMTS layers; lists the available layers if empty")
+	wmtsOpacity := flag.Float64("wmts-opacity", 1, "opacity of the WMTS layers, from 0 to 1")
+	overlayURL := flag.String("overlay", "", "XYZ URL template of a raster layer to draw over the basemap, such as a hillshade")
+	overlayOpacity := flag.Float64("overlay-opacity", 1, "opacity of the -overlay layer, from 0 to 1")
+	overlayBlend := flag.String("overlay-blend", "normal", "blend mode of the -overlay layer: normal, multiply, screen or add")
+	overlayAttribution := flag.String("overlay-attribution", "", "credit shown on the map for the -overlay layer's data")
+	overlayTileSize := flag.Int("overlay-tile-size", 0, "pixel size of -overlay tiles, 256 or 512 (default 512 on HiDPI screens if the URL has {r})")
+	flag.Parse()
+
+	if *wmsURL != "" && *wmsLayers == "" {
+		if err := printWMSLayers(*wmsURL, *wmsVersion); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	var wmtsSources []*tilemap.WMTSSource
+	if *wmtsURL != "" {
+		caps, err := fetchWMTSCapabilities(*wmtsURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *wmtsLayers == "" {
+			printWMTSLayers(caps)
+			return
+		}
+		if wmtsSources, err = newWMTSSources(caps, *wmtsLayers); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	uiController := ui.NewController()
 
 	// Create main map control panel
@@ -171,13 +314,97 @@ func main() {
 		fmt.Printf("Button clicked!\n")
 	})
 	mapPanel.AddChild(testButton)
+
+	var app *Goliath
+	downloadButton := ui.NewButton(20, 80, "Download Area", func() {
+		app.startAreaSelection()
+	})
+	mapPanel.AddChild(downloadButton)
+	measureButton := ui.NewButton(20, 120, "Measure", func() {
+		app.startMeasure()
+	})
+	mapPanel.AddChild(measureButton)
 	uiController.AddChild(mapPanel)
 
-	app := &Goliath{
-		tileMap:      tilemap.New(800, 600, initialLat, initialLon, initialZoom),
-		debugMode:    false,
-		ui:           uiController,
-		lastZoomTime: float64(time.Now().UnixNano()) / 1e9,
+	var source tilemap.TileSource = tilemap.NewOpenStreetMapSource()
+	var mbtiles *tilemap.MBTilesSource
+	if *mbtilesPath != "" {
+		var err error
+		if mbtiles, err = tilemap.OpenMBTiles(*mbtilesPath); err != nil {
+			log.Fatal(err)
+		}
+		defer mbtiles.Close()
+		source = mbtiles
+	}
+	var pmtiles *tilemap.PMTilesSource
+	if *pmtilesPath != "" {
+		var err error
+		if pmtiles, err = tilemap.OpenPMTiles(*pmtilesPath); err != nil {
+			log.Fatal(err)
+		}
+		defer pmtiles.Close()
+		source = pmtiles
+	}
+
+	tileMap := tilemap.New(800, 600, initialLat, initialLon, initialZoom, source)
+	if mbtiles != nil {
+		// Start on the area covered by the offline basemap
+		tileMap.Viewport = mbtiles.Metadata.InitialView(tileMap.Viewport)
+	}
+	if pmtiles != nil {
+		tileMap.Viewport = pmtiles.InitialView(tileMap.Viewport)
+	}
+	style, ok := tilemap.VectorStyleByName(*styleName)
+	if !ok {
+		log.Fatalf("Unknown style %q", *styleName)
+	}
+	tileMap.SetVectorStyle(style)
+	// Request sharper tiles where sources offer them
+	hiDPI := ebiten.Monitor().DeviceScaleFactor() > 1
+	if *overlayURL != "" {
+		blend, ok := tilemap.BlendModeByName(*overlayBlend)
+		if !ok {
+			log.Fatalf("Unknown blend mode %q", *overlayBlend)
+		}
+		overlay := tilemap.NewXYZSource("overlay", *overlayURL)
+		overlay.TilePixels = *overlayTileSize
+		overlay.Credit = tilemap.Attribution{Text: *overlayAttribution}
+		if overlay.TilePixels == 0 && hiDPI && strings.Contains(*overlayURL, "{r}") {
+			overlay.TilePixels = 2 * tilemap.TileSize
+		}
+		layer := tileMap.AddLayer(overlay)
+		layer.Opacity = *overlayOpacity
+		layer.Blend = blend
+	}
+	for _, src := range wmtsSources {
+		tileMap.AddLayer(src).Opacity = *wmtsOpacity
+	}
+	if *wmsURL != "" {
+		wmsSource, err := newWMSSource(*wmsURL, *wmsLayers, *wmsStyles, *wmsVersion)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if hiDPI {
+			// WMS renders any size, so match the screen
+			wmsSource.TilePixels = 2 * tilemap.TileSize
+		}
+		tileMap.AddLayer(wmsSource).Opacity = *wmsOpacity
+	}
+	var diskCache *tilemap.DiskCache
+	if cacheDir, err := tilemap.DefaultDiskCacheDir(); err != nil {
+		log.Printf("Tile disk cache disabled: %v", err)
+	} else if diskCache, err = tilemap.NewDiskCache(cacheDir, tilemap.DefaultDiskCacheSize); err != nil {
+		log.Printf("Tile disk cache disabled: %v", err)
+	} else {
+		tileMap.SetDiskCache(diskCache)
+	}
+
+	app = &Goliath{
+		tileMap:   tileMap,
+		diskCache: diskCache,
+		debugMode: false,
+		ui:        uiController,
+		measure:   newMeasureTool(uiController),
 	}
 
 	ebiten.SetWindowSize(800, 600)
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/OpticalFlyer/goliath/proj"
+	"github.com/OpticalFlyer/goliath/tilemap"
+	"github.com/OpticalFlyer/goliath/ui"
+)
+
+// Measure tool settings
+const (
+	doubleClickTime = 400 * time.Millisecond
+	// clickSlop is how far in pixels the mouse may move between press and
+	// release for a click; further is a pan
+	clickSlop = 4
+	// geodesicStep is the spacing in meters of the points used to draw long
+	// geodesics, which curve on the map
+	geodesicStep     = 20000.0
+	maxGeodesicSteps = 256
+
+	metersPerFoot       = 0.3048
+	metersPerMile       = 1609.344
+	squareMetersPerAcre = 4046.8564224
+)
+
+var (
+	measureLineColor = color.RGBA{255, 120, 0, 255}
+	measureFillColor = color.RGBA{255, 120, 0, 50}
+)
+
+// measureTool measures geodesic lengths and areas between points clicked on
+// the map
+type measureTool struct {
+	points []proj.LatLon
+
+	// adding is set while clicks add points. A finished measurement stays on
+	// the map until it is cleared.
+	adding   bool
+	imperial bool // Label the map in feet and miles rather than meters
+
+	// Click tracking
+	pressed                bool
+	pressX, pressY         int
+	lastClick              time.Time
+	lastClickX, lastClickY int
+
+	panel      *ui.Panel
+	label      *ui.Label
+	panelShown bool
+	controller *ui.Controller
+}
+
+// newMeasureTool creates the tool and its results panel, which is shown on
+// the controller while there is a measurement
+func newMeasureTool(controller *ui.Controller) *measureTool {
+	m := &measureTool{controller: controller}
+	m.panel = ui.NewPanel(10, 320, 200, 224, "Measure")
+	m.label = ui.NewLabel(10, 30, "")
+	m.panel.AddChild(m.label)
+	m.panel.AddChild(ui.NewButton(10, 184, "Copy", m.copyReport))
+	return m
+}
+
+// startMeasure begins a new measurement, abandoning any area selection
+func (g *Goliath) startMeasure() {
+	g.selectingArea = false
+	g.areaDragging = false
+	g.measure.start()
+}
+
+// active reports whether there is a measurement in progress or on the map
+func (m *measureTool) active() bool {
+	return m.adding || len(m.points) > 0
+}
+
+// start clears any measurement and begins a new one
+func (m *measureTool) start() {
+	m.points = nil
+	m.adding = true
+	m.pressed = false
+	m.lastClick = time.Time{}
+	m.updatePanel()
+	if !m.panelShown {
+		m.controller.AddChild(m.panel)
+		m.panelShown = true
+	}
+}
+
+// clear removes the measurement and its panel
+func (m *measureTool) clear() {
+	m.points = nil
+	m.adding = false
+	m.pressed = false
+	if m.panelShown {
+		m.controller.RemoveChild(m.panel)
+		m.panelShown = false
+	}
+}
+
+// update adds points on clicks and finishes on double-click or Enter. Escape
+// is handled with the other cancellations in updateAreaSelection.
+func (m *measureTool) update(tm *tilemap.TileMap) {
+	if !m.active() {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyU) {
+		m.imperial = !m.imperial
+	}
+	if !m.adding {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		m.adding = false
+		return
+	}
+
+	x, y := ui.CursorPosition()
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft):
+		m.pressed = true
+		m.pressX, m.pressY = x, y
+	case inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) && m.pressed:
+		m.pressed = false
+		if abs(x-m.pressX) > clickSlop || abs(y-m.pressY) > clickSlop {
+			return // The map was panned
+		}
+
+		// The first click of a double-click already added the last point
+		now := time.Now()
+		if now.Sub(m.lastClick) < doubleClickTime && abs(x-m.lastClickX) <= clickSlop && abs(y-m.lastClickY) <= clickSlop {
+			m.adding = false
+			m.lastClick = time.Time{}
+			return
+		}
+		m.lastClick = now
+		m.lastClickX, m.lastClickY = x, y
+
+		lat, lon := tm.Unproject(float64(x), float64(y))
+		m.points = append(m.points, proj.LatLon{Lat: lat, Lon: lon})
+		m.updatePanel()
+	}
+}
+
+// updatePanel shows the current results in the panel
+func (m *measureTool) updatePanel() {
+	m.label.SetText(m.summary())
+}
+
+// summary describes the measurement in every unit
+func (m *measureTool) summary() string {
+	var b strings.Builder
+	length := proj.PathLength(m.points)
+	fmt.Fprintf(&b, "Points: %d\n", len(m.points))
+	fmt.Fprintf(&b, "Length: %.2f m\n        %.3f km\n        %.2f ft\n        %.3f mi",
+		length, length/1000, length/metersPerFoot, length/metersPerMile)
+	if len(m.points) >= 3 {
+		area := polygonArea(m.points)
+		fmt.Fprintf(&b, "\nArea:   %.1f m²\n        %.4f ha\n        %.0f ft²\n        %.3f ac",
+			area, area/10000, area/(metersPerFoot*metersPerFoot), area/squareMetersPerAcre)
+	}
+	return b.String()
+}
+
+// report is the summary followed by the length of each segment, for copying
+func (m *measureTool) report() string {
+	var b strings.Builder
+	b.WriteString(m.summary())
+	if len(m.points) >= 2 {
+		b.WriteString("\nSegments:")
+		for i := 1; i < len(m.points); i++ {
+			s := segmentLength(m.points[i-1], m.points[i])
+			fmt.Fprintf(&b, "\n  %d: %.2f m (%.2f ft)", i, s, s/metersPerFoot)
+		}
+	}
+	b.WriteString("\nPoints (lat, lon):")
+	for _, p := range m.points {
+		fmt.Fprintf(&b, "\n  %.6f, %.6f", p.Lat, p.Lon)
+	}
+	return b.String() + "\n"
+}
+
+// copyReport puts the report on the clipboard, or in the log if that fails
+func (m *measureTool) copyReport() {
+	report := m.report()
+	if err := copyToClipboard(report); err != nil {
+		log.Printf("Copying the measurement failed: %v\n%s", err, report)
+	}
+}
+
+// draw draws the measured lines, the polygon they enclose and their labels.
+// While adding points, a rubber band line follows the cursor.
+func (m *measureTool) draw(screen *ebiten.Image, tm *tilemap.TileMap) {
+	if !m.active() {
+		return
+	}
+	if m.adding {
+		ebitenutil.DebugPrintAt(screen, "Click to add points, double-click to finish (U for units, Esc to cancel)",
+			10, tm.ScreenHeight-36)
+	}
+
+	points := m.points
+	if m.adding && len(points) > 0 {
+		x, y := ui.CursorPosition()
+		lat, lon := tm.Unproject(float64(x), float64(y))
+		points = append(slices.Clone(points), proj.LatLon{Lat: lat, Lon: lon})
+	}
+	if len(points) == 0 {
+		return
+	}
+
+	if len(points) >= 3 {
+		xs, ys := tm.ProjectPath(geodesicPath(points, true))
+		var path vector.Path
+		appendScreenPath(&path, xs, ys)
+		path.Close()
+		tilemap.FillPath(screen, &path, measureFillColor)
+	}
+
+	xs, ys := tm.ProjectPath(geodesicPath(points, false))
+	var path vector.Path
+	appendScreenPath(&path, xs, ys)
+	tilemap.StrokePath(screen, &path, measureLineColor, 2)
+
+	vertexLats := make([]float64, len(points))
+	vertexLons := make([]float64, len(points))
+	for i, p := range points {
+		vertexLats[i], vertexLons[i] = p.Lat, p.Lon
+	}
+	vxs, vys := tm.ProjectPath(vertexLats, vertexLons)
+	for i := range m.points {
+		vector.DrawFilledCircle(screen, float32(vxs[i]), float32(vys[i]), 4, measureLineColor, true)
+	}
+
+	// Label each segment at its middle, and the total at the last point
+	var total float64
+	for i := 1; i < len(points); i++ {
+		s := segmentLength(points[i-1], points[i])
+		total += s
+		drawLabel(screen, m.formatLength(s), (vxs[i-1]+vxs[i])/2, (vys[i-1]+vys[i])/2)
+	}
+	last := len(points) - 1
+	if last > 0 {
+		drawLabel(screen, "Total "+m.formatLength(total), vxs[last], vys[last]+2*lineHeight)
+	}
+
+	if len(points) >= 3 {
+		var cx, cy float64
+		for i := range points {
+			cx += vxs[i]
+			cy += vys[i]
+		}
+		n := float64(len(points))
+		drawLabel(screen, "Area "+m.formatArea(polygonArea(points)), cx/n, cy/n)
+	}
+}
+
+// formatLength formats a length in meters in the chosen units, switching to
+// kilometers or miles for long lengths
+func (m *measureTool) formatLength(meters float64) string {
+	if m.imperial {
+		if feet := meters / metersPerFoot; feet < 5280 {
+			return fmt.Sprintf("%.1f ft", feet)
+		}
+		return fmt.Sprintf("%.3f mi", meters/metersPerMile)
+	}
+	if meters < 1000 {
+		return fmt.Sprintf("%.1f m", meters)
+	}
+	return fmt.Sprintf("%.3f km", meters/1000)
+}
+
+// formatArea formats an area in square meters in the chosen units
+func (m *measureTool) formatArea(sqm float64) string {
+	if m.imperial {
+		acres := sqm / squareMetersPerAcre
+		switch {
+		case acres < 1:
+			return fmt.Sprintf("%.0f ft²", sqm/(metersPerFoot*metersPerFoot))
+		case acres < 640:
+			return fmt.Sprintf("%.2f ac", acres)
+		}
+		return fmt.Sprintf("%.3f mi²", sqm/(metersPerMile*metersPerMile))
+	}
+	if sqm < 1e6 {
+		return fmt.Sprintf("%.1f m²", sqm)
+	}
+	return fmt.Sprintf("%.3f km²", sqm/1e6)
+}
+
+// segmentLength returns the geodesic distance between two points in meters
+func segmentLength(a, b proj.LatLon) float64 {
+	return proj.GeodesicDistance(a.Lat, a.Lon, b.Lat, b.Lon)
+}
+
+// polygonArea returns the area enclosed by points in square meters, whichever
+// way round they were clicked
+func polygonArea(points []proj.LatLon) float64 {
+	area, _ := proj.PolygonArea(points)
+	return math.Abs(area)
+}
+
+// geodesicPath returns points along the geodesics joining points, close
+// enough together to draw as straight lines on the map
+func geodesicPath(points []proj.LatLon, closed bool) (lats, lons []float64) {
+	edges := len(points) - 1
+	if closed {
+		edges = len(points)
+	}
+	lats = append(lats, points[0].Lat)
+	lons = append(lons, points[0].Lon)
+	for i := 0; i < edges; i++ {
+		a, b := points[i], points[(i+1)%len(points)]
+		s12, azi1, _ := proj.WGS84Geodesic.Inverse(a.Lat, a.Lon, b.Lat, b.Lon)
+		steps := int(math.Ceil(s12 / geodesicStep))
+		steps = max(1, min(steps, maxGeodesicSteps))
+		for k := 1; k < steps; k++ {
+			lat, lon, _ := proj.WGS84Geodesic.Direct(a.Lat, a.Lon, azi1, s12*float64(k)/float64(steps))
+			lats = append(lats, lat)
+			lons = append(lons, lon)
+		}
+		lats = append(lats, b.Lat)
+		lons = append(lons, b.Lon)
+	}
+	return lats, lons
+}
+
+// appendScreenPath adds a line through screen points to a path
+func appendScreenPath(path *vector.Path, xs, ys []float64) {
+	for i := range xs {
+		if i == 0 {
+			path.MoveTo(float32(xs[i]), float32(ys[i]))
+		} else {
+			path.LineTo(float32(xs[i]), float32(ys[i]))
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
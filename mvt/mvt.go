@@ -0,0 +1,379 @@
+// Package mvt decodes Mapbox Vector Tiles (version 2): layers of features with
+// geometry in tile-local integer coordinates and attribute tags.
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultExtent is the tile coordinate range used when a layer doesn't set one
+const DefaultExtent = 4096
+
+// GeomType is the kind of geometry a feature holds
+type GeomType int
+
+const (
+	GeomUnknown GeomType = iota
+	GeomPoint
+	GeomLineString
+	GeomPolygon
+)
+
+// String returns the geometry type name
+func (g GeomType) String() string {
+	switch g {
+	case GeomPoint:
+		return "Point"
+	case GeomLineString:
+		return "LineString"
+	case GeomPolygon:
+		return "Polygon"
+	}
+	return "Unknown"
+}
+
+// Point is a position in tile coordinates, from 0 to the layer extent.
+// Geometry may extend past the tile edge into the buffer.
+type Point struct {
+	X, Y int32
+}
+
+// Tile is a decoded vector tile
+type Tile struct {
+	Layers []*Layer
+}
+
+// Layer returns the layer with the given name, or nil
+func (t *Tile) Layer(name string) *Layer {
+	for _, l := range t.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Layer is a named set of features sharing a coordinate extent
+type Layer struct {
+	Name     string
+	Version  int
+	Extent   int
+	Features []*Feature
+}
+
+// Feature is one geometry with its attributes.
+//
+// Geometry holds one slice per part: each point of a point feature, each line
+// of a line feature, or each ring of a polygon feature. Polygon rings are
+// closed implicitly; exterior rings wind clockwise in tile coordinates (Y
+// down) and holes counterclockwise.
+type Feature struct {
+	ID         uint64
+	HasID      bool
+	Type       GeomType
+	Properties map[string]any // Values are string, float64, int64, uint64 or bool
+	Geometry   [][]Point
+}
+
+// String returns a property as a string, or "" if it is missing or not a string
+func (f *Feature) String(key string) string {
+	s, _ := f.Properties[key].(string)
+	return s
+}
+
+// Number returns a numeric property as a float64
+func (f *Feature) Number(key string) (float64, bool) {
+	switch v := f.Properties[key].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Geometry command IDs
+const (
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+)
+
+var errTruncated = errors.New("mvt: truncated data")
+
+// Decode parses a vector tile. Unknown fields are skipped.
+func Decode(data []byte) (*Tile, error) {
+	tile := &Tile{}
+	r := reader{data: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		if field == 3 && wire == wireBytes {
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			layer, err := decodeLayer(b)
+			if err != nil {
+				return nil, err
+			}
+			tile.Layers = append(tile.Layers, layer)
+			continue
+		}
+		if err := r.skip(wire); err != nil {
+			return nil, err
+		}
+	}
+	return tile, nil
+}
+
+// rawFeature holds a feature's encoded tags and geometry until the layer's
+// keys and values have been read
+type rawFeature struct {
+	id       uint64
+	hasID    bool
+	geomType GeomType
+	tags     []uint32
+	geometry []uint32
+}
+
+func decodeLayer(data []byte) (*Layer, error) {
+	layer := &Layer{Version: 1, Extent: DefaultExtent}
+	var keys []string
+	var values []any
+	var raw []rawFeature
+
+	r := reader{data: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			layer.Name = string(b)
+		case field == 2 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			f, err := decodeRawFeature(b)
+			if err != nil {
+				return nil, err
+			}
+			raw = append(raw, f)
+		case field == 3 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, string(b))
+		case field == 4 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeValue(b)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		case field == 5 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			layer.Extent = int(v)
+		case field == 15 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			layer.Version = int(v)
+		default:
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	layer.Features = make([]*Feature, 0, len(raw))
+	for _, rf := range raw {
+		f := &Feature{
+			ID:         rf.id,
+			HasID:      rf.hasID,
+			Type:       rf.geomType,
+			Properties: make(map[string]any, len(rf.tags)/2),
+		}
+		if len(rf.tags)%2 != 0 {
+			return nil, fmt.Errorf("mvt: layer %s has a feature with an odd number of tags", layer.Name)
+		}
+		for i := 0; i < len(rf.tags); i += 2 {
+			k, v := int(rf.tags[i]), int(rf.tags[i+1])
+			if k >= len(keys) || v >= len(values) {
+				return nil, fmt.Errorf("mvt: layer %s has a tag outside its key or value table", layer.Name)
+			}
+			f.Properties[keys[k]] = values[v]
+		}
+
+		geometry, err := decodeGeometry(rf.geometry)
+		if err != nil {
+			return nil, fmt.Errorf("mvt: layer %s: %w", layer.Name, err)
+		}
+		f.Geometry = geometry
+		layer.Features = append(layer.Features, f)
+	}
+
+	return layer, nil
+}
+
+func decodeRawFeature(data []byte) (rawFeature, error) {
+	var f rawFeature
+	r := reader{data: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return f, err
+		}
+		switch {
+		case field == 1 && wire == wireVarint:
+			if f.id, err = r.varint(); err != nil {
+				return f, err
+			}
+			f.hasID = true
+		case field == 2:
+			if f.tags, err = r.packedUint32(wire, f.tags); err != nil {
+				return f, err
+			}
+		case field == 3 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return f, err
+			}
+			f.geomType = GeomType(v)
+		case field == 4:
+			if f.geometry, err = r.packedUint32(wire, f.geometry); err != nil {
+				return f, err
+			}
+		default:
+			if err := r.skip(wire); err != nil {
+				return f, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// decodeValue reads a Value message, which holds exactly one typed field
+func decodeValue(data []byte) (any, error) {
+	var value any
+	r := reader{data: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		case field == 2 && wire == wireFixed32:
+			v, err := r.fixed32()
+			if err != nil {
+				return nil, err
+			}
+			value = float64(math.Float32frombits(v))
+		case field == 3 && wire == wireFixed64:
+			v, err := r.fixed64()
+			if err != nil {
+				return nil, err
+			}
+			value = math.Float64frombits(v)
+		case field == 4 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = int64(v)
+		case field == 5 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		case field == 6 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = zigzag(v)
+		case field == 7 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = v != 0
+		default:
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// decodeGeometry runs a feature's geometry commands. Each MoveTo starts a new
+// part; coordinates are zigzag-encoded deltas from the previous cursor position.
+func decodeGeometry(cmds []uint32) ([][]Point, error) {
+	var parts [][]Point
+	var cursor Point
+	for i := 0; i < len(cmds); {
+		id := cmds[i] & 0x7
+		count := int(cmds[i] >> 3)
+		i++
+
+		switch id {
+		case cmdMoveTo, cmdLineTo:
+			if i+2*count > len(cmds) {
+				return nil, errTruncated
+			}
+			for j := 0; j < count; j++ {
+				cursor.X += int32(zigzag(uint64(cmds[i])))
+				cursor.Y += int32(zigzag(uint64(cmds[i+1])))
+				i += 2
+
+				if id == cmdMoveTo {
+					parts = append(parts, []Point{cursor})
+				} else {
+					if len(parts) == 0 {
+						return nil, errors.New("LineTo before MoveTo")
+					}
+					parts[len(parts)-1] = append(parts[len(parts)-1], cursor)
+				}
+			}
+		case cmdClosePath:
+			// Rings are closed implicitly
+		default:
+			return nil, fmt.Errorf("unknown geometry command %d", id)
+		}
+	}
+	return parts, nil
+}
+
+// zigzag decodes a zigzag-encoded signed integer
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
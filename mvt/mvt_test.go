@@ -0,0 +1,185 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Helpers for building protocol buffer messages by hand
+
+func pbKey(field, wire int) []byte {
+	return binary.AppendUvarint(nil, uint64(field<<3|wire))
+}
+
+func pbVarint(field int, v uint64) []byte {
+	return binary.AppendUvarint(pbKey(field, wireVarint), v)
+}
+
+func pbBytes(field int, b []byte) []byte {
+	out := binary.AppendUvarint(pbKey(field, wireBytes), uint64(len(b)))
+	return append(out, b...)
+}
+
+func pbPacked(field int, values []uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.AppendUvarint(b, uint64(v))
+	}
+	return pbBytes(field, b)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func command(id, count uint32) uint32 {
+	return id | count<<3
+}
+
+func zz(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+func TestDecodeGeometry(t *testing.T) {
+	tests := []struct {
+		name string
+		cmds []uint32
+		want [][]Point
+	}{
+		{
+			name: "Point",
+			cmds: []uint32{command(cmdMoveTo, 1), zz(25), zz(17)},
+			want: [][]Point{{{25, 17}}},
+		},
+		{
+			name: "MultiPoint",
+			cmds: []uint32{command(cmdMoveTo, 2), zz(5), zz(7), zz(-2), zz(1)},
+			want: [][]Point{{{5, 7}}, {{3, 8}}},
+		},
+		{
+			name: "LineString",
+			cmds: []uint32{command(cmdMoveTo, 1), zz(2), zz(2), command(cmdLineTo, 2), zz(0), zz(8), zz(8), zz(0)},
+			want: [][]Point{{{2, 2}, {2, 10}, {10, 10}}},
+		},
+		{
+			name: "Polygon with hole",
+			cmds: []uint32{
+				command(cmdMoveTo, 1), zz(11), zz(11),
+				command(cmdLineTo, 3), zz(20), zz(0), zz(0), zz(20), zz(-20), zz(0),
+				command(cmdClosePath, 1),
+				command(cmdMoveTo, 1), zz(2), zz(-5),
+				command(cmdLineTo, 3), zz(0), zz(-10), zz(10), zz(0), zz(0), zz(10),
+				command(cmdClosePath, 1),
+			},
+			want: [][]Point{
+				{{11, 11}, {31, 11}, {31, 31}, {11, 31}},
+				{{13, 26}, {13, 16}, {23, 16}, {23, 26}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeGeometry(tt.cmds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeGeometryErrors(t *testing.T) {
+	if _, err := decodeGeometry([]uint32{command(cmdLineTo, 1), 0, 0}); err == nil {
+		t.Error("LineTo before MoveTo: expected an error")
+	}
+	if _, err := decodeGeometry([]uint32{command(cmdMoveTo, 2), 0, 0}); err == nil {
+		t.Error("truncated MoveTo: expected an error")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	double := make([]byte, 8)
+	binary.LittleEndian.PutUint64(double, math.Float64bits(2.5))
+
+	feature := concat(
+		pbVarint(1, 42),
+		pbPacked(2, []uint32{0, 0, 1, 1, 2, 2}),
+		pbVarint(3, uint64(GeomPolygon)),
+		pbPacked(4, []uint32{
+			command(cmdMoveTo, 1), zz(0), zz(0),
+			command(cmdLineTo, 2), zz(10), zz(0), zz(0), zz(10),
+			command(cmdClosePath, 1),
+		}),
+	)
+	layer := concat(
+		pbVarint(15, 2),
+		pbBytes(1, []byte("water")),
+		pbBytes(2, feature),
+		pbBytes(3, []byte("class")),
+		pbBytes(3, []byte("height")),
+		pbBytes(3, []byte("intermittent")),
+		pbBytes(4, pbBytes(1, []byte("lake"))),
+		pbBytes(4, append(pbKey(3, wireFixed64), double...)),
+		pbBytes(4, pbVarint(7, 1)),
+		pbVarint(5, 512),
+	)
+	data := concat(pbBytes(3, layer), pbVarint(99, 7)) // Unknown field is skipped
+
+	tile, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := tile.Layer("water")
+	if l == nil {
+		t.Fatal("layer water not found")
+	}
+	if l.Version != 2 || l.Extent != 512 || len(l.Features) != 1 {
+		t.Fatalf("layer = %+v", l)
+	}
+
+	f := l.Features[0]
+	if !f.HasID || f.ID != 42 || f.Type != GeomPolygon {
+		t.Errorf("feature = %+v", f)
+	}
+	if f.String("class") != "lake" {
+		t.Errorf("class = %q; want lake", f.String("class"))
+	}
+	if h, ok := f.Number("height"); !ok || h != 2.5 {
+		t.Errorf("height = %v, %v; want 2.5", h, ok)
+	}
+	if f.Properties["intermittent"] != true {
+		t.Errorf("intermittent = %v; want true", f.Properties["intermittent"])
+	}
+	want := [][]Point{{{0, 0}, {10, 0}, {10, 10}}}
+	if !reflect.DeepEqual(f.Geometry, want) {
+		t.Errorf("geometry = %v; want %v", f.Geometry, want)
+	}
+}
+
+func TestDecodeDefaults(t *testing.T) {
+	tile, err := Decode(pbBytes(3, pbBytes(1, []byte("empty"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := tile.Layer("empty")
+	if l == nil || l.Extent != DefaultExtent || l.Version != 1 {
+		t.Errorf("layer = %+v; want extent %d, version 1", l, DefaultExtent)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	data := pbBytes(3, pbBytes(1, []byte("roads")))
+	if _, err := Decode(data[:len(data)-2]); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
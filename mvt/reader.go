@@ -0,0 +1,119 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// reader is a minimal protocol buffer decoder over a byte slice
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+// key reads a field tag
+func (r *reader) key() (field int, wire int, err error) {
+	v, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(v >> 3), int(v & 0x7), nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *reader) fixed32() (uint32, error) {
+	if len(r.data)-r.pos < 4 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *reader) fixed64() (uint64, error) {
+	if len(r.data)-r.pos < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.data[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+
+// packedUint32 appends a repeated uint32 field, which may be packed or not
+func (r *reader) packedUint32(wire int, dst []uint32) ([]uint32, error) {
+	if wire == wireVarint {
+		v, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		return append(dst, uint32(v)), nil
+	}
+	if wire != wireBytes {
+		return nil, fmt.Errorf("mvt: unexpected wire type %d for repeated uint32", wire)
+	}
+
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	packed := reader{data: b}
+	for !packed.done() {
+		v, err := packed.varint()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, uint32(v))
+	}
+	return dst, nil
+}
+
+// skip passes over a field's value
+func (r *reader) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("mvt: unsupported wire type %d", wire)
+	}
+	return err
+}
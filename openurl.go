@@ -0,0 +1,32 @@
+//go:build !js
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+)
+
+// openURL opens an http or https link in the default web browser
+func openURL(url string) error {
+	if !tilemap.IsWebURL(url) {
+		return fmt.Errorf("refusing to open %q: not an http or https URL", url)
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
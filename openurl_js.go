@@ -0,0 +1,22 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+)
+
+// openURL opens an http or https link in a new browser tab
+func openURL(url string) error {
+	if !tilemap.IsWebURL(url) {
+		return fmt.Errorf("refusing to open %q: not an http or https URL", url)
+	}
+	if w := js.Global().Call("open", url, "_blank"); w.IsNull() || w.IsUndefined() {
+		return errors.New("the browser blocked the new tab")
+	}
+	return nil
+}
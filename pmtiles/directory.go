@@ -0,0 +1,190 @@
+package pmtiles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Entry is one record of a PMTiles directory. A RunLength of zero marks a
+// pointer to a leaf directory; otherwise the entry covers RunLength consecutive
+// tile IDs that share the same tile data.
+type Entry struct {
+	TileID    uint64
+	Offset    uint64 // Relative to the tile data or leaf directory section
+	Length    uint32
+	RunLength uint32
+}
+
+// ZxyToID converts a tile address to its PMTiles tile ID: the number of tiles
+// at all lower zooms plus the tile's position along a Hilbert curve.
+func ZxyToID(z uint8, x, y uint32) uint64 {
+	acc := (uint64(1)<<(2*uint(z)) - 1) / 3
+	for s := uint32(1) << z >> 1; s > 0; s >>= 1 {
+		var rx, ry uint32
+		if x&s != 0 {
+			rx = 1
+		}
+		if y&s != 0 {
+			ry = 1
+		}
+		acc += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+
+		// Rotate the quadrant so the curve stays continuous
+		if ry == 0 {
+			if rx == 1 {
+				x = s - 1 - x%s
+				y = s - 1 - y%s
+			}
+			x, y = y, x
+		}
+	}
+	return acc
+}
+
+// IDToZxy converts a PMTiles tile ID back to a tile address
+func IDToZxy(id uint64) (z uint8, x, y uint32) {
+	var acc uint64
+	for z = 0; z < 32; z++ {
+		count := uint64(1) << (2 * uint(z))
+		if id < acc+count {
+			break
+		}
+		acc += count
+	}
+
+	d := id - acc
+	for s := uint32(1); s < uint32(1)<<z; s <<= 1 {
+		rx := uint32(1 & (d / 2))
+		ry := uint32(1 & (d ^ uint64(rx)))
+		if ry == 0 {
+			if rx == 1 {
+				x = s - 1 - x
+				y = s - 1 - y
+			}
+			x, y = y, x
+		}
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return z, x, y
+}
+
+// errDirectoryCorrupt is returned when a directory can't be decoded
+var errDirectoryCorrupt = errors.New("pmtiles: corrupt directory")
+
+// DeserializeEntries decodes an uncompressed directory. Entries are stored
+// column-wise as varints: delta-coded tile IDs, run lengths, lengths, then
+// offsets, where an offset of zero means "directly after the previous entry".
+func DeserializeEntries(data []byte) ([]Entry, error) {
+	r := bytes.NewReader(data)
+	readVarint := func() (uint64, error) {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, errDirectoryCorrupt
+		}
+		return v, nil
+	}
+
+	n, err := readVarint()
+	if err != nil {
+		return nil, err
+	}
+	// Every entry takes at least four bytes
+	if n > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: %d entries in %d bytes", errDirectoryCorrupt, n, len(data))
+	}
+	entries := make([]Entry, n)
+
+	var lastID uint64
+	for i := range entries {
+		delta, err := readVarint()
+		if err != nil {
+			return nil, err
+		}
+		lastID += delta
+		entries[i].TileID = lastID
+	}
+	for i := range entries {
+		v, err := readVarint()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].RunLength = uint32(v)
+	}
+	for i := range entries {
+		v, err := readVarint()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Length = uint32(v)
+	}
+	for i := range entries {
+		v, err := readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if v == 0 && i > 0 {
+			entries[i].Offset = entries[i-1].Offset + uint64(entries[i-1].Length)
+		} else {
+			entries[i].Offset = v - 1
+		}
+	}
+
+	return entries, nil
+}
+
+// SerializeEntries encodes a directory in the format read by DeserializeEntries.
+// The result is uncompressed.
+func SerializeEntries(entries []Entry) []byte {
+	var buf []byte
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+
+	var lastID uint64
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, e.TileID-lastID)
+		lastID = e.TileID
+	}
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(e.RunLength))
+	}
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(e.Length))
+	}
+	for i, e := range entries {
+		if i > 0 && e.Offset == entries[i-1].Offset+uint64(entries[i-1].Length) {
+			buf = binary.AppendUvarint(buf, 0)
+		} else {
+			buf = binary.AppendUvarint(buf, e.Offset+1)
+		}
+	}
+	return buf
+}
+
+// findEntry returns the entry covering a tile ID: either a run containing it
+// or the leaf directory pointer that may contain it. Entries must be sorted.
+func findEntry(entries []Entry, tileID uint64) (Entry, bool) {
+	lo, hi := 0, len(entries)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case tileID > entries[mid].TileID:
+			lo = mid + 1
+		case tileID < entries[mid].TileID:
+			hi = mid - 1
+		default:
+			return entries[mid], true
+		}
+	}
+
+	// hi is now the last entry starting before tileID
+	if hi >= 0 {
+		e := entries[hi]
+		if e.RunLength == 0 || tileID-e.TileID < uint64(e.RunLength) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,173 @@
+package pmtiles
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HeaderSize is the size of a PMTiles v3 header in bytes
+const HeaderSize = 127
+
+// Compression identifies how directories, metadata or tiles are compressed
+type Compression uint8
+
+const (
+	CompressionUnknown Compression = iota
+	CompressionNone
+	CompressionGzip
+	CompressionBrotli
+	CompressionZstd
+)
+
+// TileType identifies the format of the tiles in an archive
+type TileType uint8
+
+const (
+	TileTypeUnknown TileType = iota
+	TileTypeMVT
+	TileTypePNG
+	TileTypeJPEG
+	TileTypeWebP
+	TileTypeAVIF
+)
+
+// String returns the conventional file extension for the tile type
+func (t TileType) String() string {
+	switch t {
+	case TileTypeMVT:
+		return "mvt"
+	case TileTypePNG:
+		return "png"
+	case TileTypeJPEG:
+		return "jpg"
+	case TileTypeWebP:
+		return "webp"
+	case TileTypeAVIF:
+		return "avif"
+	}
+	return "unknown"
+}
+
+// IsRaster reports whether tiles of this type are images
+func (t TileType) IsRaster() bool {
+	return t == TileTypePNG || t == TileTypeJPEG || t == TileTypeWebP || t == TileTypeAVIF
+}
+
+// Header is the fixed-size header at the start of a PMTiles v3 archive.
+// Offsets are absolute positions in the archive.
+type Header struct {
+	RootOffset          uint64
+	RootLength          uint64
+	MetadataOffset      uint64
+	MetadataLength      uint64
+	LeafDirectoryOffset uint64
+	LeafDirectoryLength uint64
+	TileDataOffset      uint64
+	TileDataLength      uint64
+
+	AddressedTilesCount uint64
+	TileEntriesCount    uint64
+	TileContentsCount   uint64
+
+	Clustered           bool
+	InternalCompression Compression
+	TileCompression     Compression
+	TileType            TileType
+
+	MinZoom uint8
+	MaxZoom uint8
+
+	// Bounds and center in degrees
+	MinLon, MinLat float64
+	MaxLon, MaxLat float64
+	CenterZoom     uint8
+	CenterLon      float64
+	CenterLat      float64
+}
+
+// ErrNotPMTiles is returned when data doesn't start with a PMTiles v3 header
+var ErrNotPMTiles = errors.New("pmtiles: not a PMTiles v3 archive")
+
+// ParseHeader decodes a PMTiles v3 header
+func ParseHeader(b []byte) (Header, error) {
+	if len(b) < HeaderSize || string(b[0:7]) != "PMTiles" {
+		return Header{}, ErrNotPMTiles
+	}
+	if b[7] != 3 {
+		return Header{}, fmt.Errorf("pmtiles: unsupported spec version %d", b[7])
+	}
+
+	le := binary.LittleEndian
+	e7 := func(off int) float64 {
+		return float64(int32(le.Uint32(b[off:off+4]))) / 1e7
+	}
+
+	return Header{
+		RootOffset:          le.Uint64(b[8:16]),
+		RootLength:          le.Uint64(b[16:24]),
+		MetadataOffset:      le.Uint64(b[24:32]),
+		MetadataLength:      le.Uint64(b[32:40]),
+		LeafDirectoryOffset: le.Uint64(b[40:48]),
+		LeafDirectoryLength: le.Uint64(b[48:56]),
+		TileDataOffset:      le.Uint64(b[56:64]),
+		TileDataLength:      le.Uint64(b[64:72]),
+		AddressedTilesCount: le.Uint64(b[72:80]),
+		TileEntriesCount:    le.Uint64(b[80:88]),
+		TileContentsCount:   le.Uint64(b[88:96]),
+		Clustered:           b[96] == 1,
+		InternalCompression: Compression(b[97]),
+		TileCompression:     Compression(b[98]),
+		TileType:            TileType(b[99]),
+		MinZoom:             b[100],
+		MaxZoom:             b[101],
+		MinLon:              e7(102),
+		MinLat:              e7(106),
+		MaxLon:              e7(110),
+		MaxLat:              e7(114),
+		CenterZoom:          b[118],
+		CenterLon:           e7(119),
+		CenterLat:           e7(123),
+	}, nil
+}
+
+// Bytes encodes the header in PMTiles v3 format
+func (h Header) Bytes() []byte {
+	b := make([]byte, HeaderSize)
+	copy(b[0:7], "PMTiles")
+	b[7] = 3
+
+	le := binary.LittleEndian
+	e7 := func(off int, v float64) {
+		le.PutUint32(b[off:off+4], uint32(int32(v*1e7)))
+	}
+
+	le.PutUint64(b[8:16], h.RootOffset)
+	le.PutUint64(b[16:24], h.RootLength)
+	le.PutUint64(b[24:32], h.MetadataOffset)
+	le.PutUint64(b[32:40], h.MetadataLength)
+	le.PutUint64(b[40:48], h.LeafDirectoryOffset)
+	le.PutUint64(b[48:56], h.LeafDirectoryLength)
+	le.PutUint64(b[56:64], h.TileDataOffset)
+	le.PutUint64(b[64:72], h.TileDataLength)
+	le.PutUint64(b[72:80], h.AddressedTilesCount)
+	le.PutUint64(b[80:88], h.TileEntriesCount)
+	le.PutUint64(b[88:96], h.TileContentsCount)
+	if h.Clustered {
+		b[96] = 1
+	}
+	b[97] = byte(h.InternalCompression)
+	b[98] = byte(h.TileCompression)
+	b[99] = byte(h.TileType)
+	b[100] = h.MinZoom
+	b[101] = h.MaxZoom
+	e7(102, h.MinLon)
+	e7(106, h.MinLat)
+	e7(110, h.MaxLon)
+	e7(114, h.MaxLat)
+	b[118] = h.CenterZoom
+	e7(119, h.CenterLon)
+	e7(123, h.CenterLat)
+
+	return b
+}
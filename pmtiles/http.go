@@ -0,0 +1,86 @@
+package pmtiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPReaderAt reads byte ranges of a remote file with HTTP range requests.
+// In WASM builds the requests go through the browser's fetch API.
+type HTTPReaderAt struct {
+	URL       string
+	UserAgent string
+	Client    *http.Client
+}
+
+var (
+	_ io.ReaderAt     = (*HTTPReaderAt)(nil)
+	_ ReaderAtContext = (*HTTPReaderAt)(nil)
+)
+
+// NewHTTPReaderAt creates a reader for a remote file
+func NewHTTPReaderAt(url string) *HTTPReaderAt {
+	return &HTTPReaderAt{URL: url, Client: &http.Client{}}
+}
+
+// OpenURL opens a remote PMTiles archive
+func OpenURL(url string) (*Reader, error) {
+	reader, err := NewReader(NewHTTPReaderAt(url))
+	if err != nil {
+		return nil, fmt.Errorf("reading PMTiles %s failed: %w", url, err)
+	}
+	return reader, nil
+}
+
+// ReadAt implements io.ReaderAt
+func (h *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return h.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext implements ReaderAtContext, aborting the request when ctx is cancelled
+func (h *HTTPReaderAt) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", h.URL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating range request failed: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	if h.UserAgent != "" {
+		req.Header.Set("User-Agent", h.UserAgent)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("range request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := io.Reader(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// Server ignored the range; skip to the requested offset
+		if _, err := io.CopyN(io.Discard, body, off); err != nil {
+			return 0, io.EOF
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, fmt.Errorf("range request returned status: %d", resp.StatusCode)
+	}
+
+	n, err := io.ReadFull(body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,283 @@
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestZxyToID(t *testing.T) {
+	tests := []struct {
+		z    uint8
+		x, y uint32
+		want uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 0, 1, 2},
+		{1, 1, 1, 3},
+		{1, 1, 0, 4},
+		{2, 0, 0, 5},
+		{3, 0, 0, 21},
+		{3, 7, 0, 84},
+		{20, 0, 0, 366503875925},
+	}
+
+	for _, tt := range tests {
+		got := ZxyToID(tt.z, tt.x, tt.y)
+		if got != tt.want {
+			t.Errorf("ZxyToID(%d, %d, %d) = %d; want %d", tt.z, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestIDRoundTrip(t *testing.T) {
+	for z := uint8(0); z <= 6; z++ {
+		n := uint32(1) << z
+		seen := make(map[uint64]bool)
+		for x := uint32(0); x < n; x++ {
+			for y := uint32(0); y < n; y++ {
+				id := ZxyToID(z, x, y)
+				if seen[id] {
+					t.Fatalf("duplicate id %d at %d/%d/%d", id, z, x, y)
+				}
+				seen[id] = true
+
+				gotZ, gotX, gotY := IDToZxy(id)
+				if gotZ != z || gotX != x || gotY != y {
+					t.Fatalf("IDToZxy(%d) = %d/%d/%d; want %d/%d/%d", id, gotZ, gotX, gotY, z, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestEntriesRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{TileID: 0, Offset: 0, Length: 10, RunLength: 1},
+		{TileID: 1, Offset: 10, Length: 20, RunLength: 3},
+		{TileID: 7, Offset: 0, Length: 10, RunLength: 1}, // Deduplicated tile
+		{TileID: 100, Offset: 500, Length: 64, RunLength: 0},
+	}
+
+	got, err := DeserializeEntries(SerializeEntries(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d entries; want %d", len(got), len(entries))
+	}
+	for i := range entries {
+		if got[i] != entries[i] {
+			t.Errorf("entry %d = %+v; want %+v", i, got[i], entries[i])
+		}
+	}
+}
+
+func TestFindEntry(t *testing.T) {
+	entries := []Entry{
+		{TileID: 5, RunLength: 1},
+		{TileID: 10, RunLength: 3},
+		{TileID: 20, RunLength: 0},
+	}
+
+	tests := []struct {
+		id     uint64
+		want   uint64
+		wantOK bool
+	}{
+		{4, 0, false},
+		{5, 5, true},
+		{6, 0, false},
+		{12, 10, true},
+		{13, 0, false},
+		{25, 20, true}, // Covered by the leaf pointer
+	}
+
+	for _, tt := range tests {
+		got, ok := findEntry(entries, tt.id)
+		if ok != tt.wantOK || (ok && got.TileID != tt.want) {
+			t.Errorf("findEntry(%d) = %d, %v; want %d, %v", tt.id, got.TileID, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// buildArchive assembles a gzip-compressed archive with zoom 0-1 in the root
+// directory and zoom 2 behind a leaf directory. Zoom 2 is one run of identical tiles.
+func buildArchive(t *testing.T) []byte {
+	t.Helper()
+
+	gz := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(b)
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	var tileData []byte
+	var rootEntries []Entry
+	for id := uint64(0); id < 5; id++ {
+		z, x, y := IDToZxy(id)
+		tile := gz([]byte{'t', byte('0' + z), byte('0' + x), byte('0' + y)})
+		rootEntries = append(rootEntries, Entry{
+			TileID: id, Offset: uint64(len(tileData)), Length: uint32(len(tile)), RunLength: 1,
+		})
+		tileData = append(tileData, tile...)
+	}
+
+	ocean := gz([]byte("ocean"))
+	leaf := gz(SerializeEntries([]Entry{
+		{TileID: 5, Offset: uint64(len(tileData)), Length: uint32(len(ocean)), RunLength: 16},
+	}))
+	tileData = append(tileData, ocean...)
+	rootEntries = append(rootEntries, Entry{TileID: 5, Offset: 0, Length: uint32(len(leaf)), RunLength: 0})
+
+	root := gz(SerializeEntries(rootEntries))
+	metadata := gz([]byte(`{"name":"test","attribution":"Test data"}`))
+
+	h := Header{
+		RootOffset:          HeaderSize,
+		RootLength:          uint64(len(root)),
+		InternalCompression: CompressionGzip,
+		TileCompression:     CompressionGzip,
+		TileType:            TileTypePNG,
+		MinZoom:             0,
+		MaxZoom:             2,
+		MinLon:              -180,
+		MinLat:              -85,
+		MaxLon:              180,
+		MaxLat:              85,
+		CenterZoom:          1,
+		CenterLon:           12.5,
+		CenterLat:           -3.25,
+	}
+	h.MetadataOffset = h.RootOffset + h.RootLength
+	h.MetadataLength = uint64(len(metadata))
+	h.LeafDirectoryOffset = h.MetadataOffset + h.MetadataLength
+	h.LeafDirectoryLength = uint64(len(leaf))
+	h.TileDataOffset = h.LeafDirectoryOffset + h.LeafDirectoryLength
+	h.TileDataLength = uint64(len(tileData))
+
+	var archive []byte
+	archive = append(archive, h.Bytes()...)
+	archive = append(archive, root...)
+	archive = append(archive, metadata...)
+	archive = append(archive, leaf...)
+	archive = append(archive, tileData...)
+	return archive
+}
+
+func TestReader(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(buildArchive(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Header.TileType != TileTypePNG || r.Header.MaxZoom != 2 {
+		t.Errorf("header = %+v", r.Header)
+	}
+	if r.Header.CenterLon != 12.5 || r.Header.CenterLat != -3.25 {
+		t.Errorf("center = %v, %v; want 12.5, -3.25", r.Header.CenterLon, r.Header.CenterLat)
+	}
+
+	tests := []struct {
+		z    uint8
+		x, y uint32
+		want string
+	}{
+		{0, 0, 0, "t000"},
+		{1, 0, 1, "t101"},
+		{1, 1, 0, "t110"},
+		{2, 0, 0, "ocean"},
+		{2, 3, 3, "ocean"},
+	}
+	for _, tt := range tests {
+		got, err := r.Tile(context.Background(), tt.z, tt.x, tt.y)
+		if err != nil {
+			t.Errorf("Tile(%d, %d, %d) failed: %v", tt.z, tt.x, tt.y, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Tile(%d, %d, %d) = %q; want %q", tt.z, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	if _, err := r.Tile(context.Background(), 3, 0, 0); !errors.Is(err, ErrTileNotFound) {
+		t.Errorf("Tile(3, 0, 0) error = %v; want ErrTileNotFound", err)
+	}
+	if _, err := r.Tile(context.Background(), 1, 2, 0); !errors.Is(err, ErrTileNotFound) {
+		t.Errorf("Tile(1, 2, 0) error = %v; want ErrTileNotFound", err)
+	}
+
+	meta, err := r.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["name"] != "test" {
+		t.Errorf("metadata name = %v; want test", meta["name"])
+	}
+}
+
+func TestHTTPReader(t *testing.T) {
+	archive := buildArchive(t)
+	var stall atomic.Bool
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stall.Load() {
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		http.ServeContent(w, r, "test.pmtiles", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer server.Close()
+
+	r, err := OpenURL(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Tile(context.Background(), 1, 0, 1)
+	if err != nil || string(got) != "t101" {
+		t.Fatalf("Tile(1, 0, 1) = %q, %v; want t101", got, err)
+	}
+
+	// Cancelling the context abandons a slow range request
+	stall.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Tile(ctx, 1, 1, 0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled Tile error = %v; want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tile ignored the cancelled context")
+	}
+
+	// An already cancelled context reads nothing
+	if _, err := r.Tile(ctx, 0, 0, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Tile with a cancelled context error = %v; want context.Canceled", err)
+	}
+}
+
+func TestNotPMTiles(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("SQLite format 3\x00")))
+	if !errors.Is(err, ErrNotPMTiles) {
+		t.Errorf("error = %v; want ErrNotPMTiles", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package pmtiles reads PMTiles v3 archives: single-file tilesets addressed by
+// byte ranges, readable from a local file or over HTTP range requests.
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxDirectoryDepth bounds how many leaf directory levels are followed. The
+// spec allows root plus leaves; a little slack guards against malformed files.
+const maxDirectoryDepth = 4
+
+// maxCachedDirectories is how many decoded leaf directories are kept in memory
+const maxCachedDirectories = 64
+
+// ErrTileNotFound is returned when an archive has no data for a tile
+var ErrTileNotFound = errors.New("pmtiles: tile not found")
+
+// ReaderAtContext is implemented by readers whose reads can be cancelled,
+// such as HTTPReaderAt. Other io.ReaderAt implementations are only checked
+// for cancellation before each read.
+type ReaderAtContext interface {
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
+}
+
+// Reader reads tiles from a PMTiles v3 archive. It is safe for concurrent use.
+type Reader struct {
+	Header Header
+
+	r      io.ReaderAt
+	closer io.Closer
+	root   []Entry
+
+	mu      sync.Mutex
+	leaves  map[uint64][]Entry // Decoded leaf directories by offset
+	leafAge []uint64           // Leaf offsets in insertion order, oldest first
+}
+
+// NewReader reads the header and root directory of an archive
+func NewReader(r io.ReaderAt) (*Reader, error) {
+	// The root directory must lie within the first 16 KiB, so read both at once
+	buf := make([]byte, 16384)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading PMTiles header failed: %w", err)
+	}
+	buf = buf[:n]
+
+	header, err := ParseHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{
+		Header: header,
+		r:      r,
+		leaves: make(map[uint64][]Entry),
+	}
+
+	var rootData []byte
+	if header.RootOffset+header.RootLength <= uint64(len(buf)) {
+		rootData = buf[header.RootOffset : header.RootOffset+header.RootLength]
+	} else {
+		rootData, err = reader.readRange(context.Background(), header.RootOffset, header.RootLength)
+		if err != nil {
+			return nil, fmt.Errorf("reading PMTiles root directory failed: %w", err)
+		}
+	}
+
+	reader.root, err = reader.decodeDirectory(rootData)
+	if err != nil {
+		return nil, fmt.Errorf("decoding PMTiles root directory failed: %w", err)
+	}
+	return reader, nil
+}
+
+// Open opens a local PMTiles file
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening PMTiles %s failed: %w", path, err)
+	}
+	reader, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading PMTiles %s failed: %w", path, err)
+	}
+	reader.closer = f
+	return reader, nil
+}
+
+// Close releases the underlying file, if the reader owns one
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// Tile returns the decompressed data for a tile, or ErrTileNotFound. Reads
+// stop when ctx is cancelled.
+func (r *Reader) Tile(ctx context.Context, z uint8, x, y uint32) ([]byte, error) {
+	if z < r.Header.MinZoom || z > r.Header.MaxZoom {
+		return nil, ErrTileNotFound
+	}
+	if n := uint32(1) << z; x >= n || y >= n {
+		return nil, ErrTileNotFound
+	}
+
+	tileID := ZxyToID(z, x, y)
+	entries := r.root
+	for depth := 0; depth < maxDirectoryDepth; depth++ {
+		entry, ok := findEntry(entries, tileID)
+		if !ok {
+			return nil, ErrTileNotFound
+		}
+
+		if entry.RunLength > 0 {
+			data, err := r.readRange(ctx, r.Header.TileDataOffset+entry.Offset, uint64(entry.Length))
+			if err != nil {
+				return nil, fmt.Errorf("reading tile %d/%d/%d failed: %w", z, x, y, err)
+			}
+			return decompress(data, r.Header.TileCompression)
+		}
+
+		var err error
+		entries, err = r.leafDirectory(ctx, entry.Offset, uint64(entry.Length))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrTileNotFound
+}
+
+// Metadata returns the archive's JSON metadata
+func (r *Reader) Metadata() (map[string]any, error) {
+	if r.Header.MetadataLength == 0 {
+		return map[string]any{}, nil
+	}
+
+	data, err := r.readRange(context.Background(), r.Header.MetadataOffset, r.Header.MetadataLength)
+	if err != nil {
+		return nil, fmt.Errorf("reading PMTiles metadata failed: %w", err)
+	}
+	data, err = decompress(data, r.Header.InternalCompression)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing PMTiles metadata failed: %w", err)
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parsing PMTiles metadata failed: %w", err)
+	}
+	return meta, nil
+}
+
+// leafDirectory returns a decoded leaf directory, reading it on first use
+func (r *Reader) leafDirectory(ctx context.Context, offset, length uint64) ([]Entry, error) {
+	r.mu.Lock()
+	entries, ok := r.leaves[offset]
+	r.mu.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	data, err := r.readRange(ctx, r.Header.LeafDirectoryOffset+offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("reading PMTiles leaf directory failed: %w", err)
+	}
+	entries, err = r.decodeDirectory(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding PMTiles leaf directory failed: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.leaves[offset]; !ok {
+		if len(r.leafAge) >= maxCachedDirectories {
+			delete(r.leaves, r.leafAge[0])
+			r.leafAge = r.leafAge[1:]
+		}
+		r.leaves[offset] = entries
+		r.leafAge = append(r.leafAge, offset)
+	}
+	return entries, nil
+}
+
+// decodeDirectory decompresses and deserializes a directory
+func (r *Reader) decodeDirectory(data []byte) ([]Entry, error) {
+	data, err := decompress(data, r.Header.InternalCompression)
+	if err != nil {
+		return nil, err
+	}
+	return DeserializeEntries(data)
+}
+
+// readRange reads length bytes at offset
+func (r *Reader) readRange(ctx context.Context, offset, length uint64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	var n int
+	var err error
+	if rc, ok := r.r.(ReaderAtContext); ok {
+		n, err = rc.ReadAtContext(ctx, buf, int64(offset))
+	} else {
+		n, err = r.r.ReadAt(buf, int64(offset))
+	}
+	if n == len(buf) {
+		return buf, nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// decompress undoes the given compression. Brotli and zstd have no decoder in
+// the standard library and are reported as unsupported.
+func decompress(data []byte, c Compression) ([]byte, error) {
+	switch c {
+	case CompressionNone, CompressionUnknown:
+		return data, nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	}
+	return nil, fmt.Errorf("pmtiles: unsupported compression %d", c)
+}
@@ -0,0 +1,171 @@
+package proj
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CRS is a coordinate reference system. Forward and Inverse convert between
+// its coordinates and WGS84 latitude and longitude, so any two systems can be
+// chained through WGS84.
+//
+// Coordinates are always in east, north order: x is the easting or longitude
+// and y the northing or latitude. AxisOrder reports the order the defining
+// authority uses, for formats such as WMS 1.3 and GML that follow it.
+type CRS interface {
+	// Name describes the system
+	Name() string
+	// Forward converts WGS84 degrees to coordinates in Unit
+	Forward(lat, lon float64) (x, y float64)
+	// Inverse converts coordinates in Unit to WGS84 degrees
+	Inverse(x, y float64) (lat, lon float64)
+	// Unit is the unit of the coordinates
+	Unit() Unit
+	// AxisOrder is the authority's order of the axes
+	AxisOrder() AxisOrder
+	// Bounds is the area in which the system is meant to be used
+	Bounds() Bounds
+}
+
+// AxisOrder is the order in which a CRS's definition lists its axes
+type AxisOrder int
+
+const (
+	EastNorth AxisOrder = iota // Easting or longitude first
+	NorthEast                  // Northing or latitude first
+)
+
+// Unit is a unit of coordinates
+type Unit struct {
+	Name   string
+	Meters float64 // Length in meters; zero for angular units
+}
+
+// Units of coordinates
+var (
+	Degree           = Unit{Name: "degree"}
+	Meter            = Unit{Name: "metre", Meters: 1}
+	Kilometer        = Unit{Name: "kilometre", Meters: 1000}
+	Foot             = Unit{Name: "foot", Meters: 0.3048}
+	USSurveyFootUnit = Unit{Name: "US survey foot", Meters: USSurveyFoot}
+)
+
+// Bounds is an area in WGS84 degrees
+type Bounds struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// WorldBounds covers the whole world. It is the bounds of systems without a
+// known area of use.
+var WorldBounds = Bounds{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}
+
+// Contains reports whether a point is inside the bounds
+func (b Bounds) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// crs implements CRS for geographic coordinates, when proj is nil, and for
+// projections. Positions on datums other than WGS84 go through a datum
+// shift; a nil shift means the datum is taken to be WGS84.
+type crs struct {
+	name   string
+	datum  DatumShift
+	proj   projection
+	unit   Unit
+	axes   AxisOrder
+	bounds Bounds
+}
+
+// WGS84 is geographic WGS84, EPSG:4326
+var WGS84 CRS = &crs{name: "WGS 84", unit: Degree, axes: NorthEast, bounds: WorldBounds}
+
+// CRS84 is geographic WGS84 with longitude first, as used by OGC services
+// and GeoJSON
+var CRS84 CRS = &crs{name: "WGS 84 (CRS84)", unit: Degree, bounds: WorldBounds}
+
+// WebMercator is the projection of web maps, EPSG:3857
+var WebMercator CRS = &crs{
+	name:   "WGS 84 / Pseudo-Mercator",
+	proj:   webMercator{},
+	unit:   Meter,
+	bounds: Bounds{MinLat: minLat, MinLon: -180, MaxLat: maxLat, MaxLon: 180},
+}
+
+func (c *crs) Name() string         { return c.name }
+func (c *crs) Unit() Unit           { return c.unit }
+func (c *crs) AxisOrder() AxisOrder { return c.axes }
+func (c *crs) Bounds() Bounds       { return c.bounds }
+
+func (c *crs) Forward(lat, lon float64) (x, y float64) {
+	if c.datum != nil {
+		lat, lon = c.datum.FromWGS84(lat, lon)
+	}
+	if c.proj == nil {
+		return lon, lat
+	}
+	x, y = c.proj.Forward(lat, lon)
+	return x / c.unit.Meters, y / c.unit.Meters
+}
+
+func (c *crs) Inverse(x, y float64) (lat, lon float64) {
+	if c.proj == nil {
+		lat, lon = y, x
+	} else {
+		lat, lon = c.proj.Inverse(x*c.unit.Meters, y*c.unit.Meters)
+	}
+	if c.datum != nil {
+		lat, lon = c.datum.ToWGS84(lat, lon)
+	}
+	return lat, lon
+}
+
+// Transformer converts coordinates from one CRS to another through WGS84
+type Transformer struct {
+	From, To CRS
+}
+
+// NewTransformer returns a transformer between two systems
+func NewTransformer(from, to CRS) *Transformer {
+	return &Transformer{From: from, To: to}
+}
+
+// Transform converts a point
+func (t *Transformer) Transform(x, y float64) (float64, float64) {
+	if t.From == t.To {
+		return x, y
+	}
+	lat, lon := t.From.Inverse(x, y)
+	return t.To.Forward(lat, lon)
+}
+
+// TransformPoints converts points in place
+func (t *Transformer) TransformPoints(xs, ys []float64) {
+	for i := range xs {
+		xs[i], ys[i] = t.Transform(xs[i], ys[i])
+	}
+}
+
+// Lookup returns the CRS named by an identifier: an EPSG code such as
+// EPSG:26914, an OGC URN or URI for one, CRS:84, or a PROJ string such as
+// "+proj=utm +zone=14 +datum=NAD83"
+func Lookup(id string) (CRS, error) {
+	id = strings.TrimSpace(id)
+	if strings.HasPrefix(id, "+") {
+		return ParsePROJ(id)
+	}
+	upper := strings.ToUpper(id)
+	switch upper {
+	case "CRS:84", "URN:OGC:DEF:CRS:OGC:1.3:CRS84", "HTTP://WWW.OPENGIS.NET/DEF/CRS/OGC/1.3/CRS84":
+		return CRS84, nil
+	}
+	if !strings.HasPrefix(upper, "EPSG:") && !strings.Contains(upper, ":EPSG:") && !strings.Contains(upper, "/EPSG/") {
+		return nil, fmt.Errorf("unknown CRS %q", id)
+	}
+	code, err := strconv.Atoi(id[strings.LastIndexAny(id, ":/")+1:])
+	if err != nil {
+		return nil, fmt.Errorf("unknown CRS %q", id)
+	}
+	return LookupEPSG(code)
+}
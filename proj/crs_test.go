@@ -0,0 +1,104 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+		axes AxisOrder
+	}{
+		{"EPSG:26914", "NAD83 / UTM zone 14N", EastNorth},
+		{"urn:ogc:def:crs:EPSG::26914", "NAD83 / UTM zone 14N", EastNorth},
+		{"urn:ogc:def:crs:EPSG:6.18.3:26914", "NAD83 / UTM zone 14N", EastNorth},
+		{"http://www.opengis.net/def/crs/EPSG/0/26914", "NAD83 / UTM zone 14N", EastNorth},
+		{"EPSG:4326", "WGS 84", NorthEast},
+		{"CRS:84", "WGS 84 (CRS84)", EastNorth},
+		{"EPSG:900913", "WGS 84 / Pseudo-Mercator", EastNorth},
+		{"EPSG:2278", "NAD83 / Texas South Central (ftUS)", EastNorth},
+		{"+proj=utm +zone=14 +datum=NAD83", "+proj=utm +zone=14 +datum=NAD83", EastNorth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			c, err := Lookup(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Name() != tt.want || c.AxisOrder() != tt.axes {
+				t.Errorf("got %q, %v; want %q, %v", c.Name(), c.AxisOrder(), tt.want, tt.axes)
+			}
+		})
+	}
+
+	for _, id := range []string{"", "EPSG:1", "EPSG:abc", "ESRI:102003", "+proj=foo"} {
+		if _, err := Lookup(id); err == nil {
+			t.Errorf("Lookup(%q) succeeded; want an error", id)
+		}
+	}
+}
+
+func TestCRSUnitsAndBounds(t *testing.T) {
+	c, err := LookupEPSG(2278)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Unit() != USSurveyFootUnit {
+		t.Errorf("Unit = %v; want US survey feet", c.Unit())
+	}
+
+	utm14, err := LookupEPSG(32614)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := utm14.Bounds(); !b.Contains(30, -99) || b.Contains(30, -90) || b.Contains(-1, -99) {
+		t.Errorf("Bounds = %+v; want 84W to 102W north of the equator", b)
+	}
+	if WGS84.Unit() != Degree {
+		t.Errorf("WGS84 unit = %v; want degrees", WGS84.Unit())
+	}
+}
+
+func TestTransformer(t *testing.T) {
+	utm14, err := Lookup("EPSG:26914")
+	if err != nil {
+		t.Fatal(err)
+	}
+	toMap := NewTransformer(utm14, WebMercator)
+	xs := []float64{630000, 500000}
+	ys := []float64{3300000, 4000000}
+	toMap.TransformPoints(xs, ys)
+
+	fromMap := NewTransformer(WebMercator, utm14)
+	for i, want := range [][2]float64{{630000, 3300000}, {500000, 4000000}} {
+		x, y := fromMap.Transform(xs[i], ys[i])
+		if math.Abs(x-want[0]) > 1e-6 || math.Abs(y-want[1]) > 1e-6 {
+			t.Errorf("round trip gives (%.6f, %.6f); want (%.0f, %.0f)", x, y, want[0], want[1])
+		}
+	}
+
+	// The central meridian of zone 14 is 99W
+	lat, lon := utm14.Inverse(500000, 4000000)
+	if x, y := NewTransformer(utm14, CRS84).Transform(500000, 4000000); x != lon || y != lat {
+		t.Errorf("CRS84 = (%f, %f); want (%f, %f)", x, y, lon, lat)
+	}
+	if math.Abs(lon - -99) > 1e-12 {
+		t.Errorf("lon = %f; want -99", lon)
+	}
+}
+
+func TestMercator(t *testing.T) {
+	// IOGP Guidance Note 7-2 worked example, Makassar / NEIEZ
+	bessel := Ellipsoid{A: 6377397.155, F: 1 / 299.1528128}
+	p := NewMercator(bessel, 110, 0.997, 3900000, 900000)
+	x, y := p.Forward(-3, 120)
+	if math.Abs(x-5009726.58) > 0.01 || math.Abs(y-569150.82) > 0.01 {
+		t.Errorf("Forward = %.3f, %.3f; want 5009726.58, 569150.82", x, y)
+	}
+	lat, lon := p.Inverse(x, y)
+	if math.Abs(lat - -3) > 1e-9 || math.Abs(lon-120) > 1e-9 {
+		t.Errorf("Inverse = %.10f, %.10f; want -3, 120", lat, lon)
+	}
+}
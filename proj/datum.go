@@ -0,0 +1,149 @@
+package proj
+
+import "math"
+
+// DatumShift converts latitude and longitude in degrees between a datum and
+// WGS84
+type DatumShift interface {
+	ToWGS84(lat, lon float64) (float64, float64)
+	FromWGS84(lat, lon float64) (float64, float64)
+}
+
+// Clarke1866Ellipsoid is the ellipsoid of NAD27
+var Clarke1866Ellipsoid = Ellipsoid{A: 6378206.4, F: 1 - 6356583.8/6378206.4}
+
+// NAD27Helmert is the EPSG:1173 shift from NAD27 to WGS84 for the
+// conterminous US, a translation accurate to about 10 m. Grid shifts are
+// accurate to about 15 cm.
+var NAD27Helmert = &Helmert{Ellipsoid: Clarke1866Ellipsoid, Tx: -8, Ty: 160, Tz: 176}
+
+// nad27Grids are the NADCON and NTv2 grids for NAD27, as named by PROJ: the
+// conterminous US, Alaska, and Canada
+var nad27Grids = []string{"conus", "alaska", "ntv2_0.gsb"}
+
+// NAD27Shift returns the best available shift from NAD27 to WGS84: the NAD27
+// grids found in GridPath, falling back to NAD27Helmert outside them
+func NAD27Shift() DatumShift {
+	shift := &GridShift{Fallback: NAD27Helmert}
+	for _, name := range nad27Grids {
+		if g, err := FindGrid(name); err == nil {
+			shift.Grids = append(shift.Grids, g)
+		}
+	}
+	if len(shift.Grids) == 0 {
+		return NAD27Helmert
+	}
+	return shift
+}
+
+// Helmert is a seven parameter similarity transformation of Earth-centered
+// coordinates from a datum to WGS84. Rotations follow the position vector
+// convention of PROJ's +towgs84; negate them for parameters published in the
+// coordinate frame convention.
+type Helmert struct {
+	Ellipsoid  Ellipsoid // Ellipsoid of the datum
+	Tx, Ty, Tz float64   // Translations in meters
+	Rx, Ry, Rz float64   // Rotations in arc-seconds
+	Scale      float64   // Scale difference in parts per million
+}
+
+// ToWGS84 converts a position on the datum to WGS84
+func (h *Helmert) ToWGS84(lat, lon float64) (float64, float64) {
+	x, y, z := geodeticToGeocentric(h.Ellipsoid, lat, lon, 0)
+	x, y, z = h.forward(x, y, z)
+	lat, lon, _ = geocentricToGeodetic(WGS84Ellipsoid, x, y, z)
+	return lat, lon
+}
+
+// FromWGS84 converts a WGS84 position to the datum
+func (h *Helmert) FromWGS84(lat, lon float64) (float64, float64) {
+	x, y, z := geodeticToGeocentric(WGS84Ellipsoid, lat, lon, 0)
+	x, y, z = h.inverse(x, y, z)
+	lat, lon, _ = geocentricToGeodetic(h.Ellipsoid, x, y, z)
+	return lat, lon
+}
+
+// matrix returns the rotation and scale applied to Earth-centered
+// coordinates
+func (h *Helmert) matrix() [3][3]float64 {
+	const secToRad = degToRad / 3600
+	rx, ry, rz := h.Rx*secToRad, h.Ry*secToRad, h.Rz*secToRad
+	m := 1 + h.Scale*1e-6
+	return [3][3]float64{
+		{m, -m * rz, m * ry},
+		{m * rz, m, -m * rx},
+		{-m * ry, m * rx, m},
+	}
+}
+
+// forward transforms Earth-centered coordinates in meters to WGS84
+func (h *Helmert) forward(x, y, z float64) (float64, float64, float64) {
+	r := h.matrix()
+	return h.Tx + r[0][0]*x + r[0][1]*y + r[0][2]*z,
+		h.Ty + r[1][0]*x + r[1][1]*y + r[1][2]*z,
+		h.Tz + r[2][0]*x + r[2][1]*y + r[2][2]*z
+}
+
+// inverse transforms WGS84 Earth-centered coordinates to the datum, solving
+// the forward transformation exactly rather than negating the parameters
+func (h *Helmert) inverse(x, y, z float64) (float64, float64, float64) {
+	return solve3(h.matrix(), [3]float64{x - h.Tx, y - h.Ty, z - h.Tz})
+}
+
+// solve3 solves m·v = b for v by Cramer's rule
+func solve3(m [3][3]float64, b [3]float64) (float64, float64, float64) {
+	d := det3(m)
+	var v [3]float64
+	for i := range v {
+		mi := m
+		for row := range mi {
+			mi[row][i] = b[row]
+		}
+		v[i] = det3(mi) / d
+	}
+	return v[0], v[1], v[2]
+}
+
+func det3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// geodeticToGeocentric converts latitude and longitude in degrees and height
+// in meters on an ellipsoid to Earth-centered, Earth-fixed coordinates in
+// meters
+func geodeticToGeocentric(e Ellipsoid, lat, lon, h float64) (x, y, z float64) {
+	e2 := e.F * (2 - e.F)
+	sinLat, cosLat := sincosd(lat)
+	sinLon, cosLon := sincosd(lon)
+	n := e.A / math.Sqrt(1-e2*sinLat*sinLat) // Prime vertical radius of curvature
+	x = (n + h) * cosLat * cosLon
+	y = (n + h) * cosLat * sinLon
+	z = (n*(1-e2) + h) * sinLat
+	return x, y, z
+}
+
+// geocentricToGeodetic is the inverse of geodeticToGeocentric. Bowring's
+// method converges to well under a micrometer in three iterations near the
+// Earth's surface.
+func geocentricToGeodetic(e Ellipsoid, x, y, z float64) (lat, lon, h float64) {
+	e2 := e.F * (2 - e.F)
+	b := e.A * (1 - e.F)
+	ep2 := e2 / (1 - e2)
+	p := math.Hypot(x, y)
+	lon = math.Atan2(y, x) * radToDeg
+
+	// Iterate on the parametric latitude
+	beta := math.Atan2(z, p*(1-e.F))
+	var phi float64
+	for i := 0; i < 3; i++ {
+		sinB, cosB := math.Sincos(beta)
+		phi = math.Atan2(z+ep2*b*sinB*sinB*sinB, p-e2*e.A*cosB*cosB*cosB)
+		beta = math.Atan2((1-e.F)*math.Sin(phi), math.Cos(phi))
+	}
+
+	sinPhi, cosPhi := math.Sincos(phi)
+	h = p*cosPhi + z*sinPhi - e.A*math.Sqrt(1-e2*sinPhi*sinPhi)
+	return phi * radToDeg, lon, h
+}
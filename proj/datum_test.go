@@ -0,0 +1,129 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeocentric(t *testing.T) {
+	// IOGP Guidance Note 7-2 worked example
+	lat, lon := 53+48.0/60+33.82/3600, 2+7.0/60+46.38/3600
+	x, y, z := geodeticToGeocentric(WGS84Ellipsoid, lat, lon, 73)
+	if math.Abs(x-3771793.968) > 1e-3 || math.Abs(y-140253.342) > 1e-3 || math.Abs(z-5124304.349) > 1e-3 {
+		t.Errorf("geocentric = %.3f, %.3f, %.3f; want 3771793.968, 140253.342, 5124304.349", x, y, z)
+	}
+
+	gotLat, gotLon, h := geocentricToGeodetic(WGS84Ellipsoid, x, y, z)
+	if math.Abs(gotLat-lat) > 1e-11 || math.Abs(gotLon-lon) > 1e-11 || math.Abs(h-73) > 1e-6 {
+		t.Errorf("geodetic = %.12f, %.12f, %.6f; want %.12f, %.12f, 73", gotLat, gotLon, h, lat, lon)
+	}
+
+	// Near the poles and below the surface
+	for _, lat := range []float64{-90, -89.9999, 0, 45, 89.9999, 90} {
+		x, y, z := geodeticToGeocentric(Clarke1866Ellipsoid, lat, -100, -500)
+		gotLat, _, h := geocentricToGeodetic(Clarke1866Ellipsoid, x, y, z)
+		if math.Abs(gotLat-lat) > 1e-11 || math.Abs(h - -500) > 1e-6 {
+			t.Errorf("lat %g: round trip gives %.12f, %.6f", lat, gotLat, h)
+		}
+	}
+}
+
+func TestHelmert(t *testing.T) {
+	// IOGP Guidance Note 7-2 worked example, WGS 72 to WGS 84 (EPSG:1238) in
+	// the position vector convention
+	wgs72 := Ellipsoid{A: 6378135, F: 1 / 298.26}
+	h := &Helmert{Ellipsoid: wgs72, Tz: 4.5, Rz: 0.554, Scale: 0.219}
+	x, y, z := h.forward(3657660.66, 255768.55, 5201382.11)
+	if math.Abs(x-3657660.78) > 0.01 || math.Abs(y-255778.43) > 0.01 || math.Abs(z-5201387.75) > 0.01 {
+		t.Errorf("forward = %.3f, %.3f, %.3f; want 3657660.78, 255778.43, 5201387.75", x, y, z)
+	}
+	x, y, z = h.inverse(x, y, z)
+	if math.Abs(x-3657660.66) > 1e-6 || math.Abs(y-255768.55) > 1e-6 || math.Abs(z-5201382.11) > 1e-6 {
+		t.Errorf("inverse = %.6f, %.6f, %.6f; want the input", x, y, z)
+	}
+
+	// Geographic round trip
+	lat, lon := h.ToWGS84(55, 4)
+	if d := GeodesicDistance(55, 4, lat, lon); d < 1 || d > 20 {
+		t.Errorf("shift is %g m; want a few meters", d)
+	}
+	gotLat, gotLon := h.FromWGS84(lat, lon)
+	if math.Abs(gotLat-55) > 1e-10 || math.Abs(gotLon-4) > 1e-10 {
+		t.Errorf("round trip gives %.12f, %.12f", gotLat, gotLon)
+	}
+}
+
+// Meades Ranch, the NAD27 origin, on NAD27 and on NAD83(1986) as given by the
+// NGS datasheet for station KG0640. NAD83(1986) and WGS84 agree to about 2 m.
+var (
+	meadesRanchNAD27 = [2]float64{39 + 13.0/60 + 26.686/3600, -(98 + 32.0/60 + 30.506/3600)}
+	meadesRanchNAD83 = [2]float64{39 + 13.0/60 + 26.71220/3600, -(98 + 32.0/60 + 31.74540/3600)}
+)
+
+// nad27HelmertAccuracy is the accuracy EPSG gives for NAD27Helmert, in meters
+const nad27HelmertAccuracy = 10
+
+func TestNAD27Helmert(t *testing.T) {
+	// The published shift is 0.026"N and 1.239"W, about 30 m
+	lat0, lon0 := meadesRanchNAD27[0], meadesRanchNAD27[1]
+	lat, lon := NAD27Helmert.ToWGS84(lat0, lon0)
+	if d := GeodesicDistance(lat, lon, meadesRanchNAD83[0], meadesRanchNAD83[1]); d > nad27HelmertAccuracy {
+		t.Errorf("ToWGS84 = %.7f, %.7f, %.1f m from the published %.7f, %.7f",
+			lat, lon, d, meadesRanchNAD83[0], meadesRanchNAD83[1])
+	}
+
+	gotLat, gotLon := NAD27Helmert.FromWGS84(lat, lon)
+	// Heights are dropped on the way, so the round trip is good to a millimeter
+	if math.Abs(gotLat-lat0) > 1e-8 || math.Abs(gotLon-lon0) > 1e-8 {
+		t.Errorf("round trip gives %.12f, %.12f", gotLat, gotLon)
+	}
+}
+
+func TestTransformNAD27(t *testing.T) {
+	// The EPSG worked example for Lambert Conformal Conic is on NAD27
+	c := &crs{
+		name:  "NAD27 / Texas South Central",
+		datum: NAD27Helmert,
+		proj:  NewLambertConformalConic(Clarke1866Ellipsoid, dm(27, 50), -99, dm(28, 23), dm(30, 17), 2000000*USSurveyFoot, 0),
+		unit:  USSurveyFootUnit,
+	}
+	lat, lon := NAD27Helmert.ToWGS84(28.5, -96)
+	x, y := c.Forward(lat, lon)
+	if math.Abs(x-2963503.91) > 0.01 || math.Abs(y-254759.80) > 0.01 {
+		t.Errorf("Forward = %.3f, %.3f ftUS; want 2963503.91, 254759.80", x, y)
+	}
+
+	// Meades Ranch from NAD27 to NAD83, in degrees and in UTM zone 14
+	lon, lat, err := Transform(4267, 4269, meadesRanchNAD27[1], meadesRanchNAD27[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := GeodesicDistance(lat, lon, meadesRanchNAD83[0], meadesRanchNAD83[1]); d > nad27HelmertAccuracy {
+		t.Errorf("NAD27 to NAD83 = %.7f, %.7f, %.1f m from the published position", lat, lon, d)
+	}
+	x, y, err = Transform(4267, 26914, meadesRanchNAD27[1], meadesRanchNAD27[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantX, wantY, err := Transform(4269, 26914, meadesRanchNAD83[1], meadesRanchNAD83[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := math.Hypot(x-wantX, y-wantY); d > nad27HelmertAccuracy {
+		t.Errorf("NAD27 to NAD83 UTM = %.2f, %.2f, %.1f m from %.2f, %.2f", x, y, d, wantX, wantY)
+	}
+
+	// NAD27 UTM to NAD83 UTM moves points tens of meters. Heights are dropped
+	// on the way, so the round trip is good to a millimeter.
+	x, y, err = Transform(26714, 26914, 630000, 3300000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := math.Hypot(x-630000, y-3300000); d < 10 || d > 300 {
+		t.Errorf("NAD27 to NAD83 moved %g m", d)
+	}
+	x, y, _ = Transform(26914, 26714, x, y)
+	if math.Abs(x-630000) > 1e-3 || math.Abs(y-3300000) > 1e-3 {
+		t.Errorf("round trip gives (%.6f, %.6f)", x, y)
+	}
+}
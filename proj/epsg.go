@@ -0,0 +1,199 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// USSurveyFoot is the length in meters of the US survey foot used by most
+// State Plane coordinate systems
+const USSurveyFoot = 1200.0 / 3937
+
+// projection converts between geographic coordinates in degrees and projected
+// coordinates in meters
+type projection interface {
+	Forward(lat, lon float64) (x, y float64)
+	Inverse(x, y float64) (lat, lon float64)
+}
+
+// webMercator is the spherical Mercator projection of EPSG:3857
+type webMercator struct{}
+
+func (webMercator) Forward(lat, lon float64) (x, y float64) {
+	return LatLonToWebMercator(lat, lon)
+}
+
+func (webMercator) Inverse(x, y float64) (lat, lon float64) {
+	return WebMercatorToLatLon(x, y)
+}
+
+// statePlaneZone defines a NAD83 State Plane zone, which has one EPSG code in
+// meters and another in US survey feet
+type statePlaneZone struct {
+	name         string
+	meters, feet int  // EPSG codes
+	lambert      bool // Lambert Conformal Conic, otherwise Transverse Mercator
+	lat0, lon0   float64
+	lat1, lat2   float64 // Standard parallels of a Lambert zone
+	k0           float64 // Scale on the central meridian of a Transverse Mercator zone
+	fe, fn       float64 // False easting and northing in meters
+}
+
+// statePlaneZones are the most used NAD83 State Plane zones. Angles are in
+// degrees and minutes.
+var statePlaneZones = []statePlaneZone{
+	{name: "California zone 1", meters: 26941, feet: 2225, lambert: true, lat0: dm(39, 20), lon0: dm(-122, 0), lat1: dm(41, 40), lat2: dm(40, 0), fe: 2000000, fn: 500000},
+	{name: "California zone 2", meters: 26942, feet: 2226, lambert: true, lat0: dm(37, 40), lon0: dm(-122, 0), lat1: dm(39, 50), lat2: dm(38, 20), fe: 2000000, fn: 500000},
+	{name: "California zone 3", meters: 26943, feet: 2227, lambert: true, lat0: dm(36, 30), lon0: dm(-120, 30), lat1: dm(38, 26), lat2: dm(37, 4), fe: 2000000, fn: 500000},
+	{name: "California zone 4", meters: 26944, feet: 2228, lambert: true, lat0: dm(35, 20), lon0: dm(-119, 0), lat1: dm(37, 15), lat2: dm(36, 0), fe: 2000000, fn: 500000},
+	{name: "California zone 5", meters: 26945, feet: 2229, lambert: true, lat0: dm(33, 30), lon0: dm(-118, 0), lat1: dm(35, 28), lat2: dm(34, 2), fe: 2000000, fn: 500000},
+	{name: "California zone 6", meters: 26946, feet: 2230, lambert: true, lat0: dm(32, 10), lon0: dm(-116, 15), lat1: dm(33, 53), lat2: dm(32, 47), fe: 2000000, fn: 500000},
+	{name: "Colorado North", meters: 26953, feet: 2231, lambert: true, lat0: dm(39, 20), lon0: dm(-105, 30), lat1: dm(40, 47), lat2: dm(39, 43), fe: 914401.8289, fn: 304800.6096},
+	{name: "Colorado Central", meters: 26954, feet: 2232, lambert: true, lat0: dm(37, 50), lon0: dm(-105, 30), lat1: dm(39, 45), lat2: dm(38, 27), fe: 914401.8289, fn: 304800.6096},
+	{name: "Colorado South", meters: 26955, feet: 2233, lambert: true, lat0: dm(36, 40), lon0: dm(-105, 30), lat1: dm(38, 26), lat2: dm(37, 14), fe: 914401.8289, fn: 304800.6096},
+	{name: "Florida East", meters: 26958, feet: 2236, lat0: dm(24, 20), lon0: dm(-81, 0), k0: 0.999941177, fe: 200000},
+	{name: "Florida West", meters: 26959, feet: 2237, lat0: dm(24, 20), lon0: dm(-82, 0), k0: 0.999941177, fe: 200000},
+	{name: "Florida North", meters: 26960, feet: 2238, lambert: true, lat0: dm(29, 0), lon0: dm(-84, 30), lat1: dm(30, 45), lat2: dm(29, 35), fe: 600000},
+	{name: "Georgia East", meters: 26966, feet: 2239, lat0: dm(30, 0), lon0: dm(-82, 10), k0: 0.9999, fe: 200000},
+	{name: "Georgia West", meters: 26967, feet: 2240, lat0: dm(30, 0), lon0: dm(-84, 10), k0: 0.9999, fe: 700000},
+	{name: "Illinois East", meters: 26971, feet: 3435, lat0: dm(36, 40), lon0: dm(-88, 20), k0: 0.999975, fe: 300000},
+	{name: "Illinois West", meters: 26972, feet: 3436, lat0: dm(36, 40), lon0: dm(-90, 10), k0: 0.999941177, fe: 700000},
+	{name: "Maryland", meters: 26985, feet: 2248, lambert: true, lat0: dm(37, 40), lon0: dm(-77, 0), lat1: dm(39, 27), lat2: dm(38, 18), fe: 400000},
+	{name: "Massachusetts Mainland", meters: 26986, feet: 2249, lambert: true, lat0: dm(41, 0), lon0: dm(-71, 30), lat1: dm(42, 41), lat2: dm(41, 43), fe: 200000, fn: 750000},
+	{name: "New Jersey", meters: 32111, feet: 3424, lat0: dm(38, 50), lon0: dm(-74, 30), k0: 0.9999, fe: 150000},
+	{name: "New York East", meters: 32115, feet: 2260, lat0: dm(38, 50), lon0: dm(-74, 30), k0: 0.9999, fe: 150000},
+	{name: "New York Central", meters: 32116, feet: 2261, lat0: dm(40, 0), lon0: dm(-76, 35), k0: 0.9999375, fe: 250000},
+	{name: "New York West", meters: 32117, feet: 2262, lat0: dm(40, 0), lon0: dm(-78, 35), k0: 0.9999375, fe: 350000},
+	{name: "New York Long Island", meters: 32118, feet: 2263, lambert: true, lat0: dm(40, 10), lon0: dm(-74, 0), lat1: dm(41, 2), lat2: dm(40, 40), fe: 300000},
+	{name: "North Carolina", meters: 32119, feet: 2264, lambert: true, lat0: dm(33, 45), lon0: dm(-79, 0), lat1: dm(36, 10), lat2: dm(34, 20), fe: 609601.22},
+	{name: "Ohio North", meters: 32122, feet: 3734, lambert: true, lat0: dm(39, 40), lon0: dm(-82, 30), lat1: dm(41, 42), lat2: dm(40, 26), fe: 600000},
+	{name: "Ohio South", meters: 32123, feet: 3735, lambert: true, lat0: dm(38, 0), lon0: dm(-82, 30), lat1: dm(40, 2), lat2: dm(38, 44), fe: 600000},
+	{name: "Pennsylvania North", meters: 32128, feet: 2271, lambert: true, lat0: dm(40, 10), lon0: dm(-77, 45), lat1: dm(41, 57), lat2: dm(40, 53), fe: 600000},
+	{name: "Pennsylvania South", meters: 32129, feet: 2272, lambert: true, lat0: dm(39, 20), lon0: dm(-77, 45), lat1: dm(40, 58), lat2: dm(39, 56), fe: 600000},
+	{name: "Texas North", meters: 32137, feet: 2275, lambert: true, lat0: dm(34, 0), lon0: dm(-101, 30), lat1: dm(36, 11), lat2: dm(34, 39), fe: 200000, fn: 1000000},
+	{name: "Texas North Central", meters: 32138, feet: 2276, lambert: true, lat0: dm(31, 40), lon0: dm(-98, 30), lat1: dm(33, 58), lat2: dm(32, 8), fe: 600000, fn: 2000000},
+	{name: "Texas Central", meters: 32139, feet: 2277, lambert: true, lat0: dm(29, 40), lon0: dm(-100, 20), lat1: dm(31, 53), lat2: dm(30, 7), fe: 700000, fn: 3000000},
+	{name: "Texas South Central", meters: 32140, feet: 2278, lambert: true, lat0: dm(27, 50), lon0: dm(-99, 0), lat1: dm(30, 17), lat2: dm(28, 23), fe: 600000, fn: 4000000},
+	{name: "Texas South", meters: 32141, feet: 2279, lambert: true, lat0: dm(25, 40), lon0: dm(-98, 30), lat1: dm(27, 50), lat2: dm(26, 10), fe: 300000, fn: 5000000},
+	{name: "Virginia North", meters: 32146, feet: 2283, lambert: true, lat0: dm(37, 40), lon0: dm(-78, 30), lat1: dm(39, 12), lat2: dm(38, 2), fe: 3500000, fn: 2000000},
+	{name: "Virginia South", meters: 32147, feet: 2284, lambert: true, lat0: dm(36, 20), lon0: dm(-78, 30), lat1: dm(37, 58), lat2: dm(36, 46), fe: 3500000, fn: 1000000},
+	{name: "Washington North", meters: 32148, feet: 2285, lambert: true, lat0: dm(47, 0), lon0: dm(-120, 50), lat1: dm(48, 44), lat2: dm(47, 30), fe: 500000},
+	{name: "Washington South", meters: 32149, feet: 2286, lambert: true, lat0: dm(45, 20), lon0: dm(-120, 30), lat1: dm(47, 20), lat2: dm(45, 50), fe: 500000},
+}
+
+var (
+	epsgMu       sync.Mutex
+	epsgRegistry = map[int]CRS{}
+)
+
+// Transform converts coordinates between two coordinate reference systems
+// given by EPSG code. Geographic coordinates are x = longitude, y = latitude
+// in degrees. Projected coordinates are in the system's unit: meters, or US
+// survey feet for the State Plane codes in feet.
+//
+// Built in codes are 4326 (WGS84), 4269 (NAD83), 4267 (NAD27), 3857 (Web
+// Mercator), the WGS84 UTM zones 32601-32660 and 32701-32760, the NAD83 UTM
+// zones 26901-26923, the NAD27 UTM zones 26701-26722, and the common NAD83
+// State Plane zones. NAD83 and WGS84 are treated as the same datum; they
+// differ by 1-2 m in North America. NAD27 is shifted with NAD27Shift.
+func Transform(fromEPSG, toEPSG int, x, y float64) (float64, float64, error) {
+	from, err := LookupEPSG(fromEPSG)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := LookupEPSG(toEPSG)
+	if err != nil {
+		return 0, 0, err
+	}
+	x, y = NewTransformer(from, to).Transform(x, y)
+	return x, y, nil
+}
+
+// LookupEPSG returns the coordinate system with an EPSG code
+func LookupEPSG(code int) (CRS, error) {
+	epsgMu.Lock()
+	defer epsgMu.Unlock()
+	if c, ok := epsgRegistry[code]; ok {
+		return c, nil
+	}
+	c := newEPSG(code)
+	if c == nil {
+		return nil, fmt.Errorf("unsupported EPSG code %d", code)
+	}
+	epsgRegistry[code] = c
+	return c, nil
+}
+
+// RegisterEPSG adds a coordinate system to the registry, or replaces a built
+// in one. Definitions can come from ParsePROJ.
+func RegisterEPSG(code int, c CRS) {
+	epsgMu.Lock()
+	defer epsgMu.Unlock()
+	epsgRegistry[code] = c
+}
+
+// newEPSG creates a built in coordinate system, or returns nil if there is no
+// system with the code
+func newEPSG(code int) CRS {
+	switch {
+	case code == 4326:
+		return WGS84
+	case code == 4269:
+		return &crs{name: "NAD83", unit: Degree, axes: NorthEast, bounds: WorldBounds}
+	case code == 4267:
+		return &crs{name: "NAD27", datum: NAD27Shift(), unit: Degree, axes: NorthEast, bounds: WorldBounds}
+	case code == 3857, code == 3785, code == 900913:
+		return WebMercator
+	case code >= 32601 && code <= 32660:
+		return utm("WGS 84", WGS84Ellipsoid, code-32600, false)
+	case code >= 32701 && code <= 32760:
+		return utm("WGS 84", WGS84Ellipsoid, code-32700, true)
+	case code >= 26901 && code <= 26923:
+		return utm("NAD83", GRS80Ellipsoid, code-26900, false)
+	case code >= 26701 && code <= 26722:
+		c := utm("NAD27", Clarke1866Ellipsoid, code-26700, false)
+		c.datum = NAD27Shift()
+		return c
+	}
+
+	for _, z := range statePlaneZones {
+		if code != z.meters && code != z.feet {
+			continue
+		}
+		c := &crs{name: "NAD83 / " + z.name, unit: Meter, bounds: WorldBounds}
+		if z.lambert {
+			c.proj = NewLambertConformalConic(GRS80Ellipsoid, z.lat0, z.lon0, z.lat1, z.lat2, z.fe, z.fn)
+		} else {
+			c.proj = NewTransverseMercator(GRS80Ellipsoid, z.lat0, z.lon0, z.k0, z.fe, z.fn)
+		}
+		if code == z.feet {
+			c.name += " (ftUS)"
+			c.unit = USSurveyFootUnit
+		}
+		return c
+	}
+	return nil
+}
+
+// utm returns a Universal Transverse Mercator zone
+func utm(datum string, e Ellipsoid, zone int, south bool) *crs {
+	lon0 := float64(6*zone - 183)
+	c := &crs{
+		name:   fmt.Sprintf("%s / UTM zone %dN", datum, zone),
+		proj:   NewTransverseMercator(e, 0, lon0, 0.9996, 500000, 0),
+		unit:   Meter,
+		bounds: Bounds{MinLat: 0, MinLon: lon0 - 3, MaxLat: 84, MaxLon: lon0 + 3},
+	}
+	if south {
+		c.name = fmt.Sprintf("%s / UTM zone %dS", datum, zone)
+		c.proj = NewTransverseMercator(e, 0, lon0, 0.9996, 500000, 10000000)
+		c.bounds.MinLat, c.bounds.MaxLat = -80, 0
+	}
+	return c
+}
+
+// dm returns an angle given in degrees and minutes in degrees
+func dm(degrees, minutes float64) float64 {
+	return math.Copysign(math.Abs(degrees)+minutes/60, degrees)
+}
@@ -0,0 +1,103 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		x, y     float64
+		wantX    float64
+		wantY    float64
+		tol      float64
+	}{
+		{
+			name: "UTM zone 31N central meridian at 45N",
+			from: 4326, to: 32631, x: 3, y: 45,
+			wantX: 500000, wantY: 4982950.4002, tol: 1e-3,
+		},
+		{
+			name: "UTM zone 31N edge on the equator",
+			from: 4326, to: 32631, x: 0, y: 0,
+			wantX: 166021.4431, wantY: 0, tol: 1e-3,
+		},
+		{
+			name: "UTM zone 31S false northing",
+			from: 4326, to: 32731, x: 3, y: 0,
+			wantX: 500000, wantY: 10000000, tol: 1e-3,
+		},
+		{
+			name: "UTM back to geographic",
+			from: 32631, to: 4326, x: 500000, y: 4982950.4002,
+			wantX: 3, wantY: 45, tol: 1e-9,
+		},
+		{
+			name: "State Plane origin in meters",
+			from: 4269, to: 32140, x: -99, y: dm(27, 50),
+			wantX: 600000, wantY: 4000000, tol: 1e-6,
+		},
+		{
+			name: "State Plane origin in US survey feet",
+			from: 4269, to: 2278, x: -99, y: dm(27, 50),
+			wantX: 1968500, wantY: 13123333.333, tol: 1e-3,
+		},
+		{
+			name: "Transverse Mercator State Plane origin",
+			from: 4269, to: 32116, x: dm(-76, 35), y: 40,
+			wantX: 250000, wantY: 0, tol: 1e-6,
+		},
+		{
+			name: "Web Mercator",
+			from: 4326, to: 3857, x: 180, y: 0,
+			wantX: 20037508.3428, wantY: 0, tol: 1e-3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, err := Transform(tt.from, tt.to, tt.x, tt.y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(x-tt.wantX) > tt.tol || math.Abs(y-tt.wantY) > tt.tol {
+				t.Errorf("got (%.4f, %.4f); want (%.4f, %.4f)", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestTransformBetweenProjections(t *testing.T) {
+	// Texas South Central in feet to meters is a change of unit
+	x, y, err := Transform(2278, 32140, 3000000, 13500000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(x-3000000*USSurveyFoot) > 1e-6 || math.Abs(y-13500000*USSurveyFoot) > 1e-6 {
+		t.Errorf("got (%.6f, %.6f)", x, y)
+	}
+
+	// UTM to State Plane and back
+	x, y, err = Transform(26914, 2278, 630000, 3300000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y, err = Transform(2278, 26914, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(x-630000) > 1e-6 || math.Abs(y-3300000) > 1e-6 {
+		t.Errorf("round trip gives (%.6f, %.6f)", x, y)
+	}
+}
+
+func TestTransformUnknownCode(t *testing.T) {
+	if _, _, err := Transform(4326, 99999, 0, 0); err == nil {
+		t.Error("expected an error for an unknown EPSG code")
+	}
+	if _, _, err := Transform(32661, 4326, 0, 0); err == nil {
+		t.Error("expected an error for an unknown EPSG code")
+	}
+}
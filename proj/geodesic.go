@@ -0,0 +1,1095 @@
+package proj
+
+import "math"
+
+// Geodesics on the ellipsoid, following C. F. F. Karney, "Algorithms for
+// geodesics", J. Geodesy 87, 43-55 (2013), as implemented in GeographicLib.
+// Unlike Vincenty's formulae, the inverse solution converges for all pairs of
+// points, including nearly antipodal ones, and is accurate to about 15 nm.
+
+// Ellipsoid is a reference ellipsoid
+type Ellipsoid struct {
+	A float64 // Semi-major axis in meters
+	F float64 // Flattening
+}
+
+// WGS84Ellipsoid is the ellipsoid of WGS84 and of GPS coordinates
+var WGS84Ellipsoid = Ellipsoid{A: 6378137, F: 1 / 298.257223563}
+
+// Orders of the series expansions in the third flattening
+const (
+	nA1  = 6
+	nC1  = 6
+	nC1p = 6
+	nA2  = 6
+	nC2  = 6
+	nA3  = 6
+	nA3x = nA3
+	nC3  = 6
+	nC3x = (nC3 * (nC3 - 1)) / 2
+	nC4  = 6
+	nC4x = (nC4 * (nC4 + 1)) / 2
+)
+
+// Iteration limits and tolerances of the inverse solution
+const (
+	maxit1 = 20
+	maxit2 = maxit1 + 53 + 10
+)
+
+var (
+	tiny    = math.Sqrt(0x1p-1022)
+	tol0    = 0x1p-52
+	tol1    = 200 * tol0
+	tol2    = math.Sqrt(tol0)
+	tolb    = tol0 * tol2
+	xthresh = 1000 * tol2
+)
+
+// Geodesic solves geodesic problems on an oblate ellipsoid
+type Geodesic struct {
+	a, f              float64
+	f1, e2, ep2, n, b float64
+	c2, etol2         float64
+	a3x               [nA3x]float64
+	c3x               [nC3x]float64
+	c4x               [nC4x]float64
+	area0             float64 // Area of the whole ellipsoid
+}
+
+// WGS84Geodesic solves geodesic problems on the WGS84 ellipsoid
+var WGS84Geodesic = NewGeodesic(WGS84Ellipsoid)
+
+// NewGeodesic returns a solver for an ellipsoid. The flattening must be
+// between 0 and 1; prolate ellipsoids aren't supported.
+func NewGeodesic(e Ellipsoid) *Geodesic {
+	g := &Geodesic{a: e.A, f: e.F}
+	g.f1 = 1 - g.f
+	g.e2 = g.f * (2 - g.f)
+	g.ep2 = g.e2 / sq(g.f1)
+	g.n = g.f / (2 - g.f)
+	g.b = g.a * g.f1
+	if g.e2 == 0 {
+		g.c2 = sq(g.a)
+	} else {
+		g.c2 = (sq(g.a) + sq(g.b)*math.Atanh(math.Sqrt(g.e2))/math.Sqrt(g.e2)) / 2
+	}
+	g.etol2 = 0.1 * tol2 / math.Sqrt(math.Max(0.001, math.Abs(g.f))*math.Min(1, 1-g.f/2)/2)
+	g.area0 = 4 * math.Pi * g.c2
+	g.a3coeff()
+	g.c3coeff()
+	g.c4coeff()
+	return g
+}
+
+// Inverse solves the inverse geodesic problem: the distance in meters
+// between two points, and the azimuths of the geodesic at each point in
+// degrees clockwise from north. azi2 is the direction of travel at the
+// second point, so the bearing back to the first is azi2+180.
+func (g *Geodesic) Inverse(lat1, lon1, lat2, lon2 float64) (s12, azi1, azi2 float64) {
+	r := g.inverse(lat1, lon1, lat2, lon2, false)
+	return r.s12, atan2d(r.salp1, r.calp1), atan2d(r.salp2, r.calp2)
+}
+
+// Direct solves the direct geodesic problem: the point reached by
+// travelling s12 meters from a point at azimuth azi1, and the azimuth of
+// travel there. Negative distances travel backwards.
+func (g *Geodesic) Direct(lat1, lon1, azi1, s12 float64) (lat2, lon2, azi2 float64) {
+	return g.line(lat1, lon1, azi1).position(s12)
+}
+
+// PathLength returns the length in meters of the geodesic polyline through points
+func (g *Geodesic) PathLength(points []LatLon) float64 {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		r := g.inverse(points[i-1].Lat, points[i-1].Lon, points[i].Lat, points[i].Lon, false)
+		length += r.s12
+	}
+	return length
+}
+
+// PolygonArea returns the area in square meters of the polygon with
+// geodesic edges joining points, and its perimeter in meters. The polygon is
+// closed automatically. The area is positive when the points go
+// counterclockwise and negative when they go clockwise; polygons may
+// encircle a pole.
+func (g *Geodesic) PolygonArea(points []LatLon) (area, perimeter float64) {
+	if len(points) < 2 {
+		return 0, 0
+	}
+	var areaSum float64
+	crossings := 0
+	for i, p1 := range points {
+		p2 := points[(i+1)%len(points)]
+		r := g.inverse(p1.Lat, p1.Lon, p2.Lat, p2.Lon, true)
+		perimeter += r.s12
+		areaSum += r.area
+		crossings += transit(p1.Lon, p2.Lon)
+	}
+
+	// The sum is clockwise; reduce it to (-area0/2, area0/2] counterclockwise
+	area = math.Remainder(areaSum, g.area0)
+	if crossings&1 != 0 {
+		if area < 0 {
+			area += g.area0 / 2
+		} else {
+			area -= g.area0 / 2
+		}
+	}
+	area = -area
+	if area > g.area0/2 {
+		area -= g.area0
+	} else if area <= -g.area0/2 {
+		area += g.area0
+	}
+	return area + 0, perimeter
+}
+
+// LatLon is a WGS84 position in degrees
+type LatLon struct {
+	Lat, Lon float64
+}
+
+// GeodesicDistance returns the distance in meters between two points on the
+// WGS84 ellipsoid
+func GeodesicDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	s12, _, _ := WGS84Geodesic.Inverse(lat1, lon1, lat2, lon2)
+	return s12
+}
+
+// InitialBearing returns the azimuth in degrees, from 0 to 360 clockwise from
+// north, to set out on from the first point to reach the second along the
+// shortest path on the WGS84 ellipsoid
+func InitialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	_, azi1, _ := WGS84Geodesic.Inverse(lat1, lon1, lat2, lon2)
+	return bearing360(azi1)
+}
+
+// FinalBearing returns the azimuth in degrees, from 0 to 360 clockwise from
+// north, of travel on arrival at the second point
+func FinalBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	_, _, azi2 := WGS84Geodesic.Inverse(lat1, lon1, lat2, lon2)
+	return bearing360(azi2)
+}
+
+// Destination returns the point reached by travelling distance meters from a
+// point on a bearing in degrees on the WGS84 ellipsoid
+func Destination(lat, lon, bearing, distance float64) (lat2, lon2 float64) {
+	lat2, lon2, _ = WGS84Geodesic.Direct(lat, lon, bearing, distance)
+	return lat2, lon2
+}
+
+// PathLength returns the length in meters of the geodesic polyline through
+// points on the WGS84 ellipsoid
+func PathLength(points []LatLon) float64 {
+	return WGS84Geodesic.PathLength(points)
+}
+
+// PolygonArea returns the area in square meters and perimeter in meters of a
+// polygon on the WGS84 ellipsoid. See Geodesic.PolygonArea for the sign of
+// the area.
+func PolygonArea(points []LatLon) (area, perimeter float64) {
+	return WGS84Geodesic.PolygonArea(points)
+}
+
+// bearing360 maps an azimuth in [-180, 180] to [0, 360)
+func bearing360(azi float64) float64 {
+	if azi < 0 {
+		azi += 360
+	}
+	if azi >= 360 {
+		azi -= 360
+	}
+	return azi
+}
+
+// transit counts crossings of the prime meridian by the edge between two
+// longitudes: 1 going east, -1 going west
+func transit(lon1, lon2 float64) int {
+	lon12, _ := angDiff(lon1, lon2)
+	lon1 = angNormalize(lon1)
+	lon2 = angNormalize(lon2)
+	switch {
+	case lon12 > 0 && ((lon1 < 0 && lon2 >= 0) || (lon1 > 0 && lon2 == 0)):
+		return 1
+	case lon12 < 0 && lon1 >= 0 && lon2 < 0:
+		return -1
+	}
+	return 0
+}
+
+// inverseResult holds the parts of the inverse solution used by callers
+type inverseResult struct {
+	s12                        float64
+	salp1, calp1, salp2, calp2 float64
+	area                       float64 // Area between the geodesic and the equator
+}
+
+// inverse solves the inverse problem, computing the area term if asked
+func (g *Geodesic) inverse(lat1, lon1, lat2, lon2 float64, wantArea bool) inverseResult {
+	// Compute longitude difference exactly, then reduce to [0, 180]
+	lon12, lon12s := angDiff(lon1, lon2)
+	lonsign := math.Copysign(1, lon12)
+	lon12 = lonsign * angRound(lon12)
+	lon12s = angRound((180 - lon12) - lonsign*lon12s)
+	lam12 := lon12 * degToRad
+	var slam12, clam12 float64
+	if lon12 > 90 {
+		slam12, clam12 = sincosd(lon12s)
+		clam12 = -clam12
+	} else {
+		slam12, clam12 = sincosd(lon12)
+	}
+
+	// Swap points so that the first is furthest from the equator, and make
+	// its latitude negative
+	lat1 = angRound(latFix(lat1))
+	lat2 = angRound(latFix(lat2))
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) || math.IsNaN(lat2) {
+		swapp = -1
+		lonsign = -lonsign
+		lat1, lat2 = lat2, lat1
+	}
+	latsign := math.Copysign(1, -lat1)
+	lat1 *= latsign
+	lat2 *= latsign
+
+	// Reduced latitudes
+	sbet1, cbet1 := sincosd(lat1)
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	sbet2, cbet2 := sincosd(lat2)
+	sbet2 *= g.f1
+	sbet2, cbet2 = norm(sbet2, cbet2)
+	cbet2 = math.Max(tiny, cbet2)
+
+	// Make the reduced latitudes match exactly when the latitudes are equal
+	// in magnitude, which the series below rely on
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+
+	dn1 := math.Sqrt(1 + g.ep2*sq(sbet1))
+	dn2 := math.Sqrt(1 + g.ep2*sq(sbet2))
+
+	var c1a [nC1 + 1]float64
+	var c2a [nC2 + 1]float64
+	var c3a [nC3]float64
+
+	var s12x, m12x, sig12 float64
+	var salp1, calp1, salp2, calp2 float64
+	var omg12, somg12, comg12 float64
+	haveOmg12, haveSomg12 := false, false
+
+	meridian := lat1 == -90 || slam12 == 0
+	if meridian {
+		// The geodesic runs along a meridian, or through a pole
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1, 0
+
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		s12x, m12x, _ = g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, true, true, c1a[:], c2a[:])
+
+		// A negative reduced length past the half way point means the meridian
+		// isn't the shortest path, which only happens on prolate ellipsoids
+		if sig12 < 1 || m12x >= 0 {
+			if sig12 < 3*tiny || (sig12 < tol0 && (s12x < 0 || m12x < 0)) {
+				sig12, m12x, s12x = 0, 0, 0
+			}
+			m12x *= g.b
+			s12x *= g.b
+		} else {
+			meridian = false
+		}
+	}
+
+	switch {
+	case meridian:
+	case sbet1 == 0 && (g.f <= 0 || lon12s >= g.f*180):
+		// Both points on the equator, and the geodesic follows it
+		calp1, calp2 = 0, 0
+		salp1, salp2 = 1, 1
+		s12x = g.a * lam12
+		sig12 = lam12 / g.f1
+		omg12, haveOmg12 = sig12, true
+	default:
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12, c1a[:], c2a[:])
+		if sig12 >= 0 {
+			// Short line, solved directly
+			s12x = sig12 * g.b * dnm
+			omg12, haveOmg12 = lam12/(g.f1*dnm), true
+			break
+		}
+
+		// Newton's method on alp1, falling back to bisection
+		var ssig1, csig1, ssig2, csig2, eps, domg12 float64
+		tripn, tripb := false, false
+		salp1a, calp1a := tiny, 1.0
+		salp1b, calp1b := tiny, -1.0
+		for numit := 0; numit < maxit2; {
+			var v, dv float64
+			v, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12, dv = g.lambda12(
+				sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12, numit < maxit1, c1a[:], c2a[:], c3a[:])
+			tol := tol0
+			if tripn {
+				tol = 8 * tol0
+			}
+			if tripb || !(math.Abs(v) >= tol) {
+				break
+			}
+			// Update the bracket on the root
+			if v > 0 && (numit > maxit1 || calp1/salp1 > calp1b/salp1b) {
+				salp1b, calp1b = salp1, calp1
+			} else if v < 0 && (numit > maxit1 || calp1/salp1 < calp1a/salp1a) {
+				salp1a, calp1a = salp1, calp1
+			}
+			numit++
+			if numit < maxit1 && dv > 0 {
+				dalp1 := -v / dv
+				if math.Abs(dalp1) < math.Pi {
+					sdalp1, cdalp1 := math.Sincos(dalp1)
+					nsalp1 := salp1*cdalp1 + calp1*sdalp1
+					if nsalp1 > 0 {
+						calp1 = calp1*cdalp1 - salp1*sdalp1
+						salp1 = nsalp1
+						salp1, calp1 = norm(salp1, calp1)
+						tripn = math.Abs(v) <= 16*tol0
+						continue
+					}
+				}
+			}
+			// Newton's method left the bracket or stalled; bisect instead
+			salp1 = (salp1a + salp1b) / 2
+			calp1 = (calp1a + calp1b) / 2
+			salp1, calp1 = norm(salp1, calp1)
+			tripn = false
+			tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < tolb || math.Abs(salp1-salp1b)+(calp1-calp1b) < tolb
+		}
+		s12x, m12x, _ = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, true, false, c1a[:], c2a[:])
+		s12x *= g.b
+
+		if wantArea {
+			sdomg12, cdomg12 := math.Sincos(domg12)
+			somg12 = slam12*cdomg12 - clam12*sdomg12
+			comg12 = clam12*cdomg12 + slam12*sdomg12
+			haveSomg12 = true
+		}
+	}
+
+	r := inverseResult{s12: s12x + 0}
+
+	if wantArea {
+		// Area between the geodesic and the equator, from the ellipsoidal
+		// correction plus the spherical excess
+		salp0 := salp1 * cbet1
+		calp0 := math.Hypot(calp1, salp1*sbet1)
+		if calp0 != 0 && salp0 != 0 {
+			ssig1, csig1 := norm(sbet1, calp1*cbet1)
+			ssig2, csig2 := norm(sbet2, calp2*cbet2)
+			k2 := sq(calp0) * g.ep2
+			eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+			a4 := sq(g.a) * calp0 * salp0 * g.e2
+			var c4a [nC4]float64
+			g.c4f(eps, c4a[:])
+			b41 := sinCosSeries(false, ssig1, csig1, c4a[:])
+			b42 := sinCosSeries(false, ssig2, csig2, c4a[:])
+			r.area = a4 * (b42 - b41)
+		}
+
+		if !meridian && !haveSomg12 && haveOmg12 {
+			somg12, comg12 = math.Sincos(omg12)
+			haveSomg12 = true
+		}
+		var alp12 float64
+		if !meridian && haveSomg12 && comg12 > -0.7071 && sbet2-sbet1 < 1.75 {
+			// Use tan(Gamma/2) = tan(omg12/2) * (tan(bet1/2)+tan(bet2/2))/(1+tan(bet1/2)*tan(bet2/2))
+			domg12 := 1 + comg12
+			dbet1 := 1 + cbet1
+			dbet2 := 1 + cbet2
+			alp12 = 2 * math.Atan2(somg12*(sbet1*dbet2+sbet2*dbet1), domg12*(sbet1*sbet2+dbet1*dbet2))
+		} else {
+			salp12 := salp2*calp1 - calp2*salp1
+			calp12 := calp2*calp1 + salp2*salp1
+			if salp12 == 0 && calp12 < 0 {
+				salp12 = tiny * calp1
+				calp12 = -1
+			}
+			alp12 = math.Atan2(salp12, calp12)
+		}
+		r.area += g.c2 * alp12
+		r.area *= swapp * lonsign * latsign
+		r.area += 0
+	}
+
+	// Undo the swap and sign changes
+	if swapp < 0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	r.salp1, r.calp1 = salp1*swapp*lonsign, calp1*swapp*latsign
+	r.salp2, r.calp2 = salp2*swapp*lonsign, calp2*swapp*latsign
+	return r
+}
+
+// lengths returns the distance s12b and reduced length m12b, both divided
+// by b, and the m0 coefficient, for an arc of sig12 on the auxiliary sphere
+func (g *Geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64, distance, reduced bool, c1a, c2a []float64) (s12b, m12b, m0 float64) {
+	a1 := a1m1f(eps)
+	c1f(eps, c1a)
+	var a2, m0x, j12 float64
+	if reduced {
+		a2 = a2m1f(eps)
+		c2f(eps, c2a)
+		m0x = a1 - a2
+		a2 = 1 + a2
+	}
+	a1 = 1 + a1
+
+	if distance {
+		b1 := sinCosSeries(true, ssig2, csig2, c1a) - sinCosSeries(true, ssig1, csig1, c1a)
+		s12b = a1 * (sig12 + b1)
+		if reduced {
+			b2 := sinCosSeries(true, ssig2, csig2, c2a) - sinCosSeries(true, ssig1, csig1, c2a)
+			j12 = m0x*sig12 + (a1*b1 - a2*b2)
+		}
+	} else if reduced {
+		// Combine the series so only one needs summing
+		for l := 1; l <= nC2; l++ {
+			c2a[l] = a1*c1a[l] - a2*c2a[l]
+		}
+		j12 = m0x*sig12 + (sinCosSeries(true, ssig2, csig2, c2a) - sinCosSeries(true, ssig1, csig1, c2a))
+	}
+	if reduced {
+		m0 = m0x
+		m12b = dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	}
+	return s12b, m12b, m0
+}
+
+// inverseStart returns a starting point for Newton's method, or solves the
+// problem outright for short lines, returning sig12 >= 0
+func (g *Geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12 float64, c1a, c2a []float64) (sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	var somg12, comg12 float64
+	if shortline {
+		sbetm2 := sq(sbet1 + sbet2)
+		sbetm2 /= sbetm2 + sq(cbet1+cbet2)
+		dnm = math.Sqrt(1 + g.ep2*sbetm2)
+		omg12 := lam12 / (g.f1 * dnm)
+		somg12, comg12 = math.Sincos(omg12)
+	} else {
+		somg12, comg12 = slam12, clam12
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*sq(somg12)/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*sq(somg12)/(1-comg12)
+	}
+
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	switch {
+	case shortline && ssig12 < g.etol2:
+		// Really short lines
+		salp2 = cbet1 * somg12
+		if comg12 >= 0 {
+			calp2 = sbet12 - cbet1*sbet2*sq(somg12)/(1+comg12)
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1-comg12)
+		}
+		salp2, calp2 = norm(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+	case math.Abs(g.n) > 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(g.n)*math.Pi*sq(cbet1):
+		// Nothing to do; the zeroth order spherical approximation is fine
+	default:
+		// Nearly antipodal points; scale to the astroid problem
+		lam12x := math.Atan2(-slam12, -clam12)
+		k2 := sq(sbet1) * g.ep2
+		eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+		lamscale := g.f * cbet1 * g.a3f(eps) * math.Pi
+		betscale := lamscale * cbet1
+		x := lam12x / lamscale
+		y := sbet12a / betscale
+
+		if y > -tol1 && x > -1-xthresh {
+			salp1 = math.Min(1, -x)
+			calp1 = -math.Sqrt(1 - sq(salp1))
+		} else {
+			k := astroid(x, y)
+			omg12a := lamscale * (-x * k / (1 + k))
+			somg12, comg12 = math.Sincos(omg12a)
+			comg12 = -comg12
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*sq(somg12)/(1-comg12)
+		}
+	}
+
+	if !(salp1 <= 0) {
+		salp1, calp1 = norm(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+	return sig12, salp1, calp1, salp2, calp2, dnm
+}
+
+// lambda12 returns the longitude difference reached by setting out at alp1,
+// and its derivative with respect to alp1 if diffp is set
+func (g *Geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64, diffp bool, c1a, c2a, c3a []float64) (
+	lam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12, dlam12 float64) {
+
+	if sbet1 == 0 && calp1 == 0 {
+		// Break the degeneracy of equatorial lines
+		calp1 = -tiny
+	}
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	ssig1 = sbet1
+	somg1 := salp0 * sbet1
+	csig1 = calp1 * cbet1
+	comg1 := csig1
+	ssig1, csig1 = norm(ssig1, csig1)
+
+	if cbet2 != cbet1 {
+		salp2 = salp0 / cbet2
+	} else {
+		salp2 = salp1
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		var t float64
+		if cbet1 < -sbet1 {
+			t = (cbet2 - cbet1) * (cbet1 + cbet2)
+		} else {
+			t = (sbet1 - sbet2) * (sbet1 + sbet2)
+		}
+		calp2 = math.Sqrt(sq(calp1*cbet1)+t) / cbet2
+	} else {
+		calp2 = math.Abs(calp1)
+	}
+
+	ssig2 = sbet2
+	somg2 := salp0 * sbet2
+	csig2 = calp2 * cbet2
+	comg2 := csig2
+	ssig2, csig2 = norm(ssig2, csig2)
+
+	sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := sq(calp0) * g.ep2
+	eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	g.c3f(eps, c3a)
+	b312 := sinCosSeries(true, ssig2, csig2, c3a) - sinCosSeries(true, ssig1, csig1, c3a)
+	domg12 = -g.f * g.a3f(eps) * salp0 * (sig12 + b312)
+	lam12 = eta + domg12
+
+	if diffp {
+		if calp2 == 0 {
+			dlam12 = -2 * g.f1 * dn1 / sbet1
+		} else {
+			_, dlam12, _ = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, false, true, c1a, c2a)
+			dlam12 *= g.f1 / (calp2 * cbet2)
+		}
+	} else {
+		dlam12 = math.NaN()
+	}
+	return
+}
+
+// geodesicLine is a geodesic from a point at a given azimuth, for solving
+// the direct problem
+type geodesicLine struct {
+	g                          *Geodesic
+	lat1, lon1                 float64
+	salp0, calp0, k2           float64
+	ssig1, csig1, somg1, comg1 float64
+	stau1, ctau1               float64
+	a1m1, a3c, b11, b31        float64
+	c1a                        [nC1 + 1]float64
+	c1pa                       [nC1p + 1]float64
+	c3a                        [nC3]float64
+}
+
+// line sets up the geodesic leaving a point at an azimuth
+func (g *Geodesic) line(lat1, lon1, azi1 float64) *geodesicLine {
+	l := &geodesicLine{g: g, lat1: latFix(lat1), lon1: lon1}
+	salp1, calp1 := sincosd(angRound(angNormalize(azi1)))
+
+	sbet1, cbet1 := sincosd(angRound(l.lat1))
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	l.salp0 = salp1 * cbet1
+	l.calp0 = math.Hypot(calp1, salp1*sbet1)
+
+	l.ssig1 = sbet1
+	l.somg1 = l.salp0 * sbet1
+	if sbet1 != 0 || calp1 != 0 {
+		l.csig1 = cbet1 * calp1
+	} else {
+		l.csig1 = 1
+	}
+	l.comg1 = l.csig1
+	l.ssig1, l.csig1 = norm(l.ssig1, l.csig1)
+
+	l.k2 = sq(l.calp0) * g.ep2
+	eps := l.k2 / (2*(1+math.Sqrt(1+l.k2)) + l.k2)
+
+	l.a1m1 = a1m1f(eps)
+	c1f(eps, l.c1a[:])
+	l.b11 = sinCosSeries(true, l.ssig1, l.csig1, l.c1a[:])
+	s, c := math.Sincos(l.b11)
+	l.stau1 = l.ssig1*c + l.csig1*s
+	l.ctau1 = l.csig1*c - l.ssig1*s
+	c1pf(eps, l.c1pa[:])
+
+	l.a3c = -g.f * l.salp0 * g.a3f(eps)
+	g.c3f(eps, l.c3a[:])
+	l.b31 = sinCosSeries(true, l.ssig1, l.csig1, l.c3a[:])
+	return l
+}
+
+// position returns the point s12 meters along the line and the azimuth there
+func (l *geodesicLine) position(s12 float64) (lat2, lon2, azi2 float64) {
+	g := l.g
+	tau12 := s12 / (g.b * (1 + l.a1m1))
+	s, c := math.Sincos(tau12)
+	b12 := -sinCosSeries(true, l.stau1*c+l.ctau1*s, l.ctau1*c-l.stau1*s, l.c1pa[:])
+	sig12 := tau12 - (b12 - l.b11)
+	ssig12, csig12 := math.Sincos(sig12)
+	if math.Abs(g.f) > 0.01 {
+		// Reverting the series is only accurate for small flattening; take a
+		// step of Newton's method for the rest
+		ssig2 := l.ssig1*csig12 + l.csig1*ssig12
+		csig2 := l.csig1*csig12 - l.ssig1*ssig12
+		b12 = sinCosSeries(true, ssig2, csig2, l.c1a[:])
+		serr := (1+l.a1m1)*(sig12+(b12-l.b11)) - s12/g.b
+		sig12 -= serr / math.Sqrt(1+l.k2*sq(ssig2))
+		ssig12, csig12 = math.Sincos(sig12)
+	}
+
+	ssig2 := l.ssig1*csig12 + l.csig1*ssig12
+	csig2 := l.csig1*csig12 - l.ssig1*ssig12
+	sbet2 := l.calp0 * ssig2
+	cbet2 := math.Hypot(l.salp0, l.calp0*csig2)
+	if cbet2 == 0 {
+		// At a pole
+		cbet2, csig2 = tiny, tiny
+	}
+	salp2 := l.salp0
+	calp2 := l.calp0 * csig2
+
+	somg2 := l.salp0 * ssig2
+	comg2 := csig2
+	omg12 := math.Atan2(somg2*l.comg1-comg2*l.somg1, comg2*l.comg1+somg2*l.somg1)
+	lam12 := omg12 + l.a3c*(sig12+(sinCosSeries(true, ssig2, csig2, l.c3a[:])-l.b31))
+	lon12 := lam12 * radToDeg
+
+	lat2 = atan2d(sbet2, g.f1*cbet2)
+	lon2 = angNormalize(angNormalize(l.lon1) + angNormalize(lon12))
+	azi2 = atan2d(salp2, calp2)
+	return lat2, lon2, azi2
+}
+
+// a3f evaluates A3 for the ellipsoid
+func (g *Geodesic) a3f(eps float64) float64 {
+	return polyval(nA3x-1, g.a3x[:], eps)
+}
+
+// c3f evaluates the C3 coefficients into c[1:]
+func (g *Geodesic) c3f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 1; l < nC3; l++ {
+		m := nC3 - l - 1
+		mult *= eps
+		c[l] = mult * polyval(m, g.c3x[o:], eps)
+		o += m + 1
+	}
+}
+
+// c4f evaluates the C4 coefficients into c
+func (g *Geodesic) c4f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 0; l < nC4; l++ {
+		m := nC4 - l - 1
+		c[l] = mult * polyval(m, g.c4x[o:], eps)
+		o += m + 1
+		mult *= eps
+	}
+}
+
+// a3coeff precomputes the A3 coefficients as polynomials in eps
+func (g *Geodesic) a3coeff() {
+	coeff := [...]float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	o, k := 0, 0
+	for j := nA3 - 1; j >= 0; j-- {
+		m := min(nA3-j-1, j)
+		g.a3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+		k++
+		o += m + 2
+	}
+}
+
+// c3coeff precomputes the C3 coefficients as polynomials in eps
+func (g *Geodesic) c3coeff() {
+	coeff := [...]float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	o, k := 0, 0
+	for l := 1; l < nC3; l++ {
+		for j := nC3 - 1; j >= l; j-- {
+			m := min(nC3-j-1, j)
+			g.c3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+// c4coeff precomputes the C4 coefficients as polynomials in eps
+func (g *Geodesic) c4coeff() {
+	coeff := [...]float64{
+		97, 15015,
+		1088, 156, 45045,
+		-224, -4784, 1573, 45045,
+		-10656, 14144, -4576, -858, 45045,
+		64, 624, -4576, 6864, -3003, 15015,
+		100, 208, 572, 3432, -12012, 30030, 45045,
+		1, 9009,
+		-2944, 468, 135135,
+		5792, 1040, -1287, 135135,
+		5952, -11648, 9152, -2574, 135135,
+		-64, -624, 4576, -6864, 3003, 135135,
+		8, 10725,
+		1856, -936, 225225,
+		-8448, 4992, -1144, 225225,
+		-1440, 4160, -4576, 1716, 225225,
+		-136, 63063,
+		1024, -208, 105105,
+		3584, -3328, 1144, 315315,
+		-128, 135135,
+		-2560, 832, 405405,
+		128, 99099,
+	}
+	o, k := 0, 0
+	for l := 0; l < nC4; l++ {
+		for j := nC4 - 1; j >= l; j-- {
+			m := nC4 - j - 1
+			g.c4x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+// a1m1f returns A1-1, the scale of the distance integral
+func a1m1f(eps float64) float64 {
+	coeff := [...]float64{1, 4, 64, 0, 256}
+	m := nA1 / 2
+	t := polyval(m, coeff[:], sq(eps)) / coeff[m+1]
+	return (t + eps) / (1 - eps)
+}
+
+// c1f evaluates the C1 coefficients of the distance integral into c[1:]
+func c1f(eps float64, c []float64) {
+	coeff := [...]float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	evenSeries(nC1, coeff[:], eps, c)
+}
+
+// c1pf evaluates the C1' coefficients of the reverted distance series into c[1:]
+func c1pf(eps float64, c []float64) {
+	coeff := [...]float64{
+		205, -432, 768, 1536,
+		4005, -4736, 3840, 12288,
+		-225, 116, 384,
+		-7173, 2695, 7680,
+		3467, 7680,
+		38081, 61440,
+	}
+	evenSeries(nC1p, coeff[:], eps, c)
+}
+
+// a2m1f returns A2-1, the scale of the reduced length integral
+func a2m1f(eps float64) float64 {
+	coeff := [...]float64{-11, -28, -192, 0, 256}
+	m := nA2 / 2
+	t := polyval(m, coeff[:], sq(eps)) / coeff[m+1]
+	return (t - eps) / (1 + eps)
+}
+
+// c2f evaluates the C2 coefficients of the reduced length integral into c[1:]
+func c2f(eps float64, c []float64) {
+	coeff := [...]float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+	evenSeries(nC2, coeff[:], eps, c)
+}
+
+// evenSeries evaluates coefficients c[1..n] that are eps^l times a
+// polynomial in eps^2, from a table of numerators and denominators
+func evenSeries(n int, coeff []float64, eps float64, c []float64) {
+	eps2 := sq(eps)
+	d := eps
+	o := 0
+	for l := 1; l <= n; l++ {
+		m := (n - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+// sinCosSeries sums a Fourier series with Clenshaw's method: sum of
+// c[l]*sin(2*l*x) for l >= 1 if sinp, else sum of c[l]*cos((2*l+1)*x)
+func sinCosSeries(sinp bool, sinx, cosx float64, c []float64) float64 {
+	k := len(c)
+	n := k
+	if sinp {
+		n--
+	}
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	var y0, y1 float64
+	if n&1 != 0 {
+		k--
+		y0 = c[k]
+	}
+	for n /= 2; n > 0; n-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+	if sinp {
+		return 2 * sinx * cosx * y0
+	}
+	return cosx * (y0 - y1)
+}
+
+// astroid solves k^4+2*k^3-(x^2+y^2-1)*k^2-2*y^2*k-y^2 = 0 for its positive root
+func astroid(x, y float64) float64 {
+	p := sq(x)
+	q := sq(y)
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+	s := p * q / 4
+	r2 := sq(r)
+	r3 := r * r2
+	disc := s * (s + 2*r3)
+	u := r
+	if disc >= 0 {
+		t3 := s + r3
+		if t3 < 0 {
+			t3 -= math.Sqrt(disc)
+		} else {
+			t3 += math.Sqrt(disc)
+		}
+		t := math.Cbrt(t3)
+		if t != 0 {
+			u += t + r2/t
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(s + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+	v := math.Sqrt(sq(u) + q)
+	var uv float64
+	if u < 0 {
+		uv = q / (v - u)
+	} else {
+		uv = u + v
+	}
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+sq(w)) + w)
+}
+
+// polyval evaluates the polynomial of degree n with coefficients p, highest first
+func polyval(n int, p []float64, x float64) float64 {
+	if n < 0 {
+		return 0
+	}
+	y := p[0]
+	for i := 1; i <= n; i++ {
+		y = y*x + p[i]
+	}
+	return y
+}
+
+func sq(x float64) float64 {
+	return x * x
+}
+
+// norm scales x, y to a unit vector
+func norm(x, y float64) (float64, float64) {
+	r := math.Hypot(x, y)
+	return x / r, y / r
+}
+
+// sumErr returns the sum of u and v and the rounding error in it
+func sumErr(u, v float64) (s, t float64) {
+	s = u + v
+	up := s - v
+	vpp := s - up
+	up -= u
+	vpp -= v
+	if s == 0 {
+		return s, s
+	}
+	return s, -(up + vpp)
+}
+
+// angRound rounds tiny angles to zero so that the series see exact zeros
+// rather than values below their precision
+func angRound(x float64) float64 {
+	const z = 1.0 / 16
+	y := math.Abs(x)
+	if y < z {
+		y = z - (z - y)
+	}
+	if x == 0 {
+		return x
+	}
+	return math.Copysign(y, x)
+}
+
+// angNormalize reduces an angle in degrees to [-180, 180]
+func angNormalize(x float64) float64 {
+	y := math.Remainder(x, 360)
+	if math.Abs(y) == 180 {
+		return math.Copysign(180, x)
+	}
+	return y
+}
+
+// latFix returns NaN for latitudes beyond the poles
+func latFix(x float64) float64 {
+	if math.Abs(x) > 90 {
+		return math.NaN()
+	}
+	return x
+}
+
+// angDiff returns y-x reduced to [-180, 180], exactly, as a sum of a
+// value and its rounding error
+func angDiff(x, y float64) (d, t float64) {
+	d, t = sumErr(math.Remainder(-x, 360), math.Remainder(y, 360))
+	d, t = sumErr(math.Remainder(d, 360), t)
+	if d == 0 || math.Abs(d) == 180 {
+		sign := y - x
+		if t != 0 {
+			sign = -t
+		}
+		d = math.Copysign(d, sign)
+	}
+	return d, t
+}
+
+// sincosd returns the sine and cosine of an angle in degrees, exact for
+// multiples of 90
+func sincosd(x float64) (s, c float64) {
+	r := math.Mod(x, 360)
+	q := 0
+	if !math.IsNaN(r) {
+		q = int(math.Round(r / 90))
+	}
+	r -= 90 * float64(q)
+	s, c = math.Sincos(r * degToRad)
+	switch uint(q) & 3 {
+	case 1:
+		s, c = c, -s
+	case 2:
+		s, c = -s, -c
+	case 3:
+		s, c = -c, s
+	}
+	c += 0
+	if s == 0 {
+		s = math.Copysign(0, x)
+	}
+	return s, c
+}
+
+// atan2d returns atan2(y, x) in degrees, exact for the axes
+func atan2d(y, x float64) float64 {
+	q := 0
+	if math.Abs(y) > math.Abs(x) {
+		q = 2
+		x, y = y, x
+	}
+	if x < 0 {
+		q++
+		x = -x
+	}
+	ang := math.Atan2(y, x) * radToDeg
+	switch q {
+	case 1:
+		if y >= 0 {
+			ang = 180 - ang
+		} else {
+			ang = -180 - ang
+		}
+	case 2:
+		ang = 90 - ang
+	case 3:
+		ang = -90 + ang
+	}
+	return ang
+}
@@ -0,0 +1,222 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+// Reference values are from the GeographicLib test suite and the GeodTest
+// data set, computed with high precision arithmetic.
+
+func TestGeodesicInverse(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		s12, azi1, azi2        float64
+		tolS, tolAzi           float64
+	}{
+		{
+			name: "GeodTest line",
+			lat1: 35.60777, lon1: -139.44815, lat2: -11.17491, lon2: -69.95921,
+			s12: 8935244.5604818305, azi1: 111.098748429560326, azi2: 129.289270889708762,
+			tolS: 1e-6, tolAzi: 1e-9,
+		},
+		{
+			name: "JFK to CDG",
+			lat1: 40.6, lon1: -73.8, lat2: 49.01666667, lon2: 2.55,
+			s12: 5853226, azi1: 53.47022, azi2: 111.59367,
+			tolS: 0.5, tolAzi: 0.5e-5,
+		},
+		{
+			name: "Short line",
+			lat1: 36.493349428792, lon1: 0, lat2: 36.49334942879201, lon2: 0.0000008,
+			s12: 0.072, azi1: 90, azi2: 90,
+			tolS: 0.5e-3, tolAzi: 0.5e-3,
+		},
+		{
+			name: "Equator, nearly antipodal",
+			lat1: 0, lon1: 0, lat2: 0, lon2: 179,
+			s12: 19926189, azi1: 90, azi2: 90,
+			tolS: 0.5, tolAzi: 0.5e-5,
+		},
+		{
+			name: "Equator, nearer antipodal",
+			lat1: 0, lon1: 0, lat2: 0, lon2: 179.5,
+			s12: 19980862, azi1: 55.96650, azi2: 124.03350,
+			tolS: 0.5, tolAzi: 0.5e-5,
+		},
+		{
+			name: "Antipodal on the equator",
+			lat1: 0, lon1: 0, lat2: 0, lon2: 180,
+			s12: 20003931, azi1: 0, azi2: 180,
+			tolS: 0.5, tolAzi: 0.5e-5,
+		},
+		{
+			name: "Nearly antipodal off the equator",
+			lat1: 0, lon1: 0, lat2: 1, lon2: 180,
+			s12: 19893357, azi1: 0, azi2: 180,
+			tolS: 0.5, tolAzi: 0.5e-5,
+		},
+		{
+			name: "Nearly antipodal near the poles",
+			lat1: 88.202499451857, lon1: 0, lat2: -88.202499451857, lon2: 179.981022032992859592,
+			s12: 20003898.214, azi1: math.NaN(), azi2: math.NaN(),
+			tolS: 0.5e-3,
+		},
+		{
+			name: "Nearly antipodal nearer the poles",
+			lat1: 89.262080389218, lon1: 0, lat2: -89.262080389218, lon2: 179.992207982775375662,
+			s12: 20003925.854, azi1: math.NaN(), azi2: math.NaN(),
+			tolS: 0.5e-3,
+		},
+		{
+			name: "Pole to pole",
+			lat1: 90, lon1: 0, lat2: -90, lon2: 0,
+			s12: 20003931.4586, azi1: 180, azi2: 180,
+			tolS: 1e-3, tolAzi: 1e-9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s12, azi1, azi2 := WGS84Geodesic.Inverse(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(s12-tt.s12) > tt.tolS {
+				t.Errorf("s12 = %.6f; want %.6f", s12, tt.s12)
+			}
+			if !math.IsNaN(tt.azi1) && angleError(azi1, tt.azi1) > tt.tolAzi {
+				t.Errorf("azi1 = %.9f; want %.9f", azi1, tt.azi1)
+			}
+			if !math.IsNaN(tt.azi2) && angleError(azi2, tt.azi2) > tt.tolAzi {
+				t.Errorf("azi2 = %.9f; want %.9f", azi2, tt.azi2)
+			}
+		})
+	}
+}
+
+func TestGeodesicDirect(t *testing.T) {
+	lat2, lon2, azi2 := WGS84Geodesic.Direct(35.60777, -139.44815, 111.098748429560326, 8935244.5604818305)
+	if math.Abs(lat2 - -11.17491) > 1e-9 || math.Abs(lon2 - -69.95921) > 1e-9 || angleError(azi2, 129.289270889708762) > 1e-9 {
+		t.Errorf("Direct = %.10f, %.10f, %.10f", lat2, lon2, azi2)
+	}
+
+	lat2, lon2, azi2 = WGS84Geodesic.Direct(40.63972222, -73.77888889, 53.5, 5850e3)
+	if math.Abs(lat2-49.01467) > 0.5e-5 || math.Abs(lon2-2.56106) > 0.5e-5 || math.Abs(azi2-111.62947) > 0.5e-5 {
+		t.Errorf("Direct = %.6f, %.6f, %.6f; want 49.01467, 2.56106, 111.62947", lat2, lon2, azi2)
+	}
+
+	// Reaching the pole
+	lat2, lon2, azi2 = WGS84Geodesic.Direct(0.01777745589997, 30, 0, 10e6)
+	if math.Abs(lat2-90) > 0.5e-5 {
+		t.Errorf("lat2 = %.6f; want 90", lat2)
+	}
+	if lon2 < 0 {
+		if math.Abs(lon2 - -150) > 0.5e-5 || math.Abs(math.Abs(azi2)-180) > 0.5e-5 {
+			t.Errorf("lon2, azi2 = %.6f, %.6f; want -150, 180", lon2, azi2)
+		}
+	} else if math.Abs(lon2-30) > 0.5e-5 || math.Abs(azi2) > 0.5e-5 {
+		t.Errorf("lon2, azi2 = %.6f, %.6f; want 30, 0", lon2, azi2)
+	}
+}
+
+func TestGeodesicRoundTrip(t *testing.T) {
+	points := []LatLon{
+		{39.8333, -98.5833}, {-33.9, 151.2}, {89.9, 45}, {-89.99, -120}, {0, 179.9}, {51.5, -0.1},
+	}
+	for _, p1 := range points {
+		for _, p2 := range points {
+			s12, azi1, _ := WGS84Geodesic.Inverse(p1.Lat, p1.Lon, p2.Lat, p2.Lon)
+			lat2, lon2 := Destination(p1.Lat, p1.Lon, azi1, s12)
+			if d := GeodesicDistance(lat2, lon2, p2.Lat, p2.Lon); d > 1e-6 {
+				t.Errorf("%v to %v: destination is %g m from the target", p1, p2, d)
+			}
+		}
+	}
+}
+
+func TestBearings(t *testing.T) {
+	// Due west along the equator
+	if b := InitialBearing(0, 10, 0, 5); math.Abs(b-270) > 1e-9 {
+		t.Errorf("InitialBearing = %f; want 270", b)
+	}
+	// Due north along a meridian
+	if b := FinalBearing(0, 10, 60, 10); math.Abs(b) > 1e-9 {
+		t.Errorf("FinalBearing = %f; want 0", b)
+	}
+	// Great circles turn, so the final bearing differs from the initial one
+	if b1, b2 := InitialBearing(40.6, -73.8, 49.01666667, 2.55), FinalBearing(40.6, -73.8, 49.01666667, 2.55); b2-b1 < 50 {
+		t.Errorf("InitialBearing, FinalBearing = %f, %f", b1, b2)
+	}
+}
+
+func TestPathLength(t *testing.T) {
+	// Equator to pole, in two legs and in one
+	path := []LatLon{{0, 0}, {45, 0}, {90, 0}}
+	if got, want := PathLength(path), 20003931.4586/2; math.Abs(got-want) > 1e-3 {
+		t.Errorf("PathLength = %.4f; want %.4f", got, want)
+	}
+	if got := PathLength(path[:1]); got != 0 {
+		t.Errorf("PathLength of one point = %f; want 0", got)
+	}
+}
+
+func TestPolygonArea(t *testing.T) {
+	tests := []struct {
+		name            string
+		points          []LatLon
+		area, perimeter float64
+		tolArea, tolPer float64
+	}{
+		{
+			name:   "Encircling the north pole",
+			points: []LatLon{{89, 0}, {89, 90}, {89, 180}, {89, 270}},
+			area:   24952305678, perimeter: 631819.8745,
+			tolArea: 1, tolPer: 1e-4,
+		},
+		{
+			name:   "Encircling the south pole",
+			points: []LatLon{{-89, 0}, {-89, 90}, {-89, 180}, {-89, 270}},
+			area:   -24952305678, perimeter: 631819.8745,
+			tolArea: 1, tolPer: 1e-4,
+		},
+		{
+			name:   "Diamond at the origin",
+			points: []LatLon{{0, -1}, {-1, 0}, {0, 1}, {1, 0}},
+			area:   24619419146, perimeter: 627598.2731,
+			tolArea: 1, tolPer: 1e-4,
+		},
+		{
+			name:   "Octant",
+			points: []LatLon{{90, 0}, {0, 0}, {0, 90}},
+			area:   63758202715511, perimeter: 30022685,
+			tolArea: 1, tolPer: 1,
+		},
+		{
+			name:   "Clockwise diamond",
+			points: []LatLon{{1, 0}, {0, 1}, {-1, 0}, {0, -1}},
+			area:   -24619419146, perimeter: 627598.2731,
+			tolArea: 1, tolPer: 1e-4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area, perimeter := PolygonArea(tt.points)
+			if math.Abs(area-tt.area) > tt.tolArea {
+				t.Errorf("area = %.1f; want %.1f", area, tt.area)
+			}
+			if math.Abs(perimeter-tt.perimeter) > tt.tolPer {
+				t.Errorf("perimeter = %.4f; want %.4f", perimeter, tt.perimeter)
+			}
+		})
+	}
+
+	if area, _ := PolygonArea([]LatLon{{1, 1}}); area != 0 {
+		t.Errorf("area of a point = %f; want 0", area)
+	}
+}
+
+// angleError returns the difference between two angles in degrees
+func angleError(a, b float64) float64 {
+	d, _ := angDiff(a, b)
+	return math.Abs(d)
+}
@@ -0,0 +1,396 @@
+package proj
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Grid is a datum shift grid loaded from an NTv2 (.gsb) file or a NADCON
+// (.las and .los) file pair. It gives the shift to NAD83 at each node, which
+// is interpolated between them. NTv2 grids can hold finer subgrids for some
+// areas.
+type Grid struct {
+	Name  string
+	grids []*subgrid // Top level grids
+}
+
+// subgrid is one rectangular lattice of shifts. Rows go from south to north
+// and columns from west to east.
+type subgrid struct {
+	name                           string
+	minLat, minLon, maxLat, maxLon float64 // Degrees
+	latInc, lonInc                 float64 // Degrees
+	rows, cols                     int
+	dlat, dlon                     []float32 // Seconds, north and east
+	children                       []*subgrid
+}
+
+// contains reports whether a point is inside the subgrid
+func (g *subgrid) contains(lat, lon float64) bool {
+	return lat >= g.minLat && lat <= g.maxLat && lon >= g.minLon && lon <= g.maxLon
+}
+
+// shift interpolates the shift at a point inside the subgrid, in degrees
+func (g *subgrid) shift(lat, lon float64) (dlat, dlon float64) {
+	fy := (lat - g.minLat) / g.latInc
+	fx := (lon - g.minLon) / g.lonInc
+	row := min(int(fy), g.rows-2)
+	col := min(int(fx), g.cols-2)
+	fy -= float64(row)
+	fx -= float64(col)
+
+	i := row*g.cols + col
+	bilinear := func(v []float32) float64 {
+		return (1-fy)*((1-fx)*float64(v[i])+fx*float64(v[i+1])) +
+			fy*((1-fx)*float64(v[i+g.cols])+fx*float64(v[i+g.cols+1]))
+	}
+	return bilinear(g.dlat) / 3600, bilinear(g.dlon) / 3600
+}
+
+// find returns the finest subgrid containing a point
+func (g *Grid) find(lat, lon float64) *subgrid {
+	var found *subgrid
+	for grids := g.grids; ; {
+		var next *subgrid
+		for _, sub := range grids {
+			if sub.contains(lat, lon) {
+				next = sub
+				break
+			}
+		}
+		if next == nil {
+			return found
+		}
+		found, grids = next, next.children
+	}
+}
+
+// Covers reports whether the grid has shifts for a point
+func (g *Grid) Covers(lat, lon float64) bool {
+	return g.find(lat, lon) != nil
+}
+
+// Shift returns a point shifted to NAD83, and false if the grid doesn't
+// cover it
+func (g *Grid) Shift(lat, lon float64) (float64, float64, bool) {
+	sub := g.find(lat, lon)
+	if sub == nil {
+		return lat, lon, false
+	}
+	dlat, dlon := sub.shift(lat, lon)
+	return lat + dlat, lon + dlon, true
+}
+
+// Unshift is the inverse of Shift. The shift varies slowly, so a few fixed
+// point iterations converge.
+func (g *Grid) Unshift(lat, lon float64) (float64, float64, bool) {
+	guessLat, guessLon := lat, lon
+	for i := 0; i < 10; i++ {
+		sub := g.find(guessLat, guessLon)
+		if sub == nil {
+			return lat, lon, false
+		}
+		dlat, dlon := sub.shift(guessLat, guessLon)
+		nextLat, nextLon := lat-dlat, lon-dlon
+		done := math.Abs(nextLat-guessLat) < 1e-12 && math.Abs(nextLon-guessLon) < 1e-12
+		guessLat, guessLon = nextLat, nextLon
+		if done {
+			break
+		}
+	}
+	return guessLat, guessLon, true
+}
+
+// LoadGrid reads a datum shift grid. An NTv2 file ends in .gsb; a NADCON
+// grid is a pair of .las and .los files, either of which can be named.
+func LoadGrid(path string) (*Grid, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gsb":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading grid failed: %w", err)
+		}
+		g, err := parseNTv2(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s failed: %w", path, err)
+		}
+		g.Name = filepath.Base(path)
+		return g, nil
+	case ".las", ".los":
+		ext := filepath.Ext(path)
+		base := strings.TrimSuffix(path, ext)
+		lasExt, losExt := ".las", ".los"
+		if ext == strings.ToUpper(ext) {
+			lasExt, losExt = ".LAS", ".LOS"
+		}
+		las, err := os.ReadFile(base + lasExt)
+		if err != nil {
+			return nil, fmt.Errorf("loading grid failed: %w", err)
+		}
+		los, err := os.ReadFile(base + losExt)
+		if err != nil {
+			return nil, fmt.Errorf("loading grid failed: %w", err)
+		}
+		g, err := parseNADCON(las, los)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s failed: %w", base, err)
+		}
+		g.Name = filepath.Base(base)
+		return g, nil
+	}
+	return nil, fmt.Errorf("loading %s failed: not a .gsb, .las or .los file", path)
+}
+
+// NTv2 record layout: records of an 8 byte key and an 8 byte value
+const ntv2RecordSize = 16
+
+// parseNTv2 reads an NTv2 grid file. Its subgrids are listed parents first;
+// longitudes are positive west and rows run east to west.
+func parseNTv2(data []byte) (*Grid, error) {
+	if len(data) < 11*ntv2RecordSize || string(data[:8]) != "NUM_OREC" {
+		return nil, errors.New("not an NTv2 file")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if binary.LittleEndian.Uint32(data[8:]) != 11 {
+		order = binary.BigEndian
+	}
+	numOverview := int(order.Uint32(data[8:]))
+	numSubgrids := int(order.Uint32(data[2*ntv2RecordSize+8:])) // NUM_FILE
+	if numOverview != 11 || numSubgrids < 1 {
+		return nil, errors.New("invalid NTv2 header")
+	}
+
+	g := &Grid{}
+	byName := map[string]*subgrid{}
+	pos := numOverview * ntv2RecordSize
+	for i := 0; i < numSubgrids; i++ {
+		if pos+11*ntv2RecordSize > len(data) {
+			return nil, errors.New("truncated NTv2 file")
+		}
+		rec := func(n int) []byte { return data[pos+n*ntv2RecordSize : pos+(n+1)*ntv2RecordSize] }
+		num := func(n int) float64 { return math.Float64frombits(order.Uint64(rec(n)[8:])) }
+		text := func(n int) string { return strings.TrimSpace(string(rec(n)[8:])) }
+
+		sLat, nLat := num(4)/3600, num(5)/3600
+		eLon, wLon := -num(6)/3600, -num(7)/3600
+		latInc, lonInc := num(8)/3600, num(9)/3600
+		count := int(order.Uint32(rec(10)[8:]))
+		if latInc <= 0 || lonInc <= 0 || nLat <= sLat || eLon <= wLon {
+			return nil, fmt.Errorf("invalid extent of NTv2 subgrid %q", text(0))
+		}
+		parent := text(1)
+		sub := &subgrid{
+			name:   text(0),
+			minLat: sLat, maxLat: nLat, minLon: wLon, maxLon: eLon,
+			latInc: latInc, lonInc: lonInc,
+			rows: int(math.Round((nLat-sLat)/latInc)) + 1,
+			cols: int(math.Round((eLon-wLon)/lonInc)) + 1,
+		}
+		if sub.rows < 2 || sub.cols < 2 || sub.rows*sub.cols != count {
+			return nil, fmt.Errorf("NTv2 subgrid %q has %d nodes, not %d by %d", sub.name, count, sub.rows, sub.cols)
+		}
+		pos += 11 * ntv2RecordSize
+		if pos+count*ntv2RecordSize > len(data) {
+			return nil, errors.New("truncated NTv2 file")
+		}
+
+		// Each node is latitude and longitude shifts and their accuracies
+		sub.dlat = make([]float32, count)
+		sub.dlon = make([]float32, count)
+		for k := 0; k < count; k++ {
+			node := data[pos+k*ntv2RecordSize:]
+			row, fileCol := k/sub.cols, k%sub.cols
+			j := row*sub.cols + sub.cols - 1 - fileCol
+			sub.dlat[j] = math.Float32frombits(order.Uint32(node))
+			sub.dlon[j] = -math.Float32frombits(order.Uint32(node[4:]))
+		}
+		pos += count * ntv2RecordSize
+
+		if p, ok := byName[parent]; ok && parent != "NONE" {
+			p.children = append(p.children, sub)
+		} else {
+			g.grids = append(g.grids, sub)
+		}
+		byName[sub.name] = sub
+	}
+	return g, nil
+}
+
+// parseNADCON reads a NADCON grid from its latitude (.las) and longitude
+// (.los) files. Each holds a header record then one record per row, south to
+// north; a record is a 4 byte field then a 4 byte float for each column.
+// Longitude shifts are positive west.
+func parseNADCON(las, los []byte) (*Grid, error) {
+	lat, err := parseNADCONFile(las)
+	if err != nil {
+		return nil, err
+	}
+	lon, err := parseNADCONFile(los)
+	if err != nil {
+		return nil, err
+	}
+	if lat.rows != lon.rows || lat.cols != lon.cols || lat.minLat != lon.minLat || lat.minLon != lon.minLon {
+		return nil, errors.New("NADCON latitude and longitude grids differ")
+	}
+	lat.dlon = lon.dlat
+	for i := range lat.dlon {
+		lat.dlon[i] = -lat.dlon[i]
+	}
+	return &Grid{grids: []*subgrid{lat}}, nil
+}
+
+// parseNADCONFile reads one NADCON file into the latitude shifts of a
+// subgrid
+func parseNADCONFile(data []byte) (*subgrid, error) {
+	const headerSize = 64 + 3*4 + 5*4 // Identification, dimensions, extent
+	if len(data) < headerSize {
+		return nil, errors.New("not a NADCON file")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if n := binary.LittleEndian.Uint32(data[64+8:]); n != 1 {
+		order = binary.BigEndian
+	}
+	var header struct {
+		Cols, Rows, Z            int32
+		MinLon, LonInc           float32
+		MinLat, LatInc, Rotation float32
+	}
+	if err := binary.Read(bytes.NewReader(data[64:headerSize]), order, &header); err != nil {
+		return nil, err
+	}
+	cols, rows := int(header.Cols), int(header.Rows)
+	if header.Z != 1 || cols < 2 || rows < 2 || header.LonInc <= 0 || header.LatInc <= 0 {
+		return nil, errors.New("not a NADCON file")
+	}
+	recordSize := (cols + 1) * 4
+	if len(data) < (rows+1)*recordSize {
+		return nil, errors.New("truncated NADCON file")
+	}
+
+	sub := &subgrid{
+		name:   strings.TrimSpace(string(data[:56])),
+		minLat: float64(header.MinLat), minLon: float64(header.MinLon),
+		latInc: float64(header.LatInc), lonInc: float64(header.LonInc),
+		rows: rows, cols: cols,
+		dlat: make([]float32, rows*cols),
+	}
+	sub.maxLat = sub.minLat + float64(rows-1)*sub.latInc
+	sub.maxLon = sub.minLon + float64(cols-1)*sub.lonInc
+	for row := 0; row < rows; row++ {
+		record := data[(row+1)*recordSize+4:]
+		for col := 0; col < cols; col++ {
+			sub.dlat[row*cols+col] = math.Float32frombits(order.Uint32(record[col*4:]))
+		}
+	}
+	return sub, nil
+}
+
+// GridShift shifts points between a datum and WGS84 with the first of its
+// grids that covers them. The grids shift to NAD83, which is taken to be
+// WGS84. Points outside every grid use Fallback, or become NaN without one.
+type GridShift struct {
+	Grids    []*Grid
+	Fallback DatumShift
+}
+
+// ToWGS84 converts a position on the datum to WGS84
+func (s *GridShift) ToWGS84(lat, lon float64) (float64, float64) {
+	for _, g := range s.Grids {
+		if lat, lon, ok := g.Shift(lat, lon); ok {
+			return lat, lon
+		}
+	}
+	if s.Fallback != nil {
+		return s.Fallback.ToWGS84(lat, lon)
+	}
+	return math.NaN(), math.NaN()
+}
+
+// FromWGS84 converts a WGS84 position to the datum
+func (s *GridShift) FromWGS84(lat, lon float64) (float64, float64) {
+	for _, g := range s.Grids {
+		if lat, lon, ok := g.Unshift(lat, lon); ok {
+			return lat, lon
+		}
+	}
+	if s.Fallback != nil {
+		return s.Fallback.FromWGS84(lat, lon)
+	}
+	return math.NaN(), math.NaN()
+}
+
+// GridPath lists the directories searched for grid files named without a
+// directory, such as those in PROJ strings. It starts with the PROJ_DATA or
+// PROJ_LIB directory if either is set. Change it before looking up systems
+// that use grids.
+var GridPath = defaultGridPath()
+
+func defaultGridPath() []string {
+	for _, env := range []string{"PROJ_DATA", "PROJ_LIB"} {
+		if dir := os.Getenv(env); dir != "" {
+			return filepath.SplitList(dir)
+		}
+	}
+	return nil
+}
+
+// gridLoad is the result of looking for a grid file, found or not
+type gridLoad struct {
+	once sync.Once
+	grid *Grid
+	err  error
+}
+
+var (
+	gridMu    sync.Mutex
+	gridCache = map[string]*gridLoad{} // Keyed by name and GridPath
+)
+
+// FindGrid loads a grid file by name from GridPath, or by path if the name
+// has a directory. NADCON grids can be named without an extension. Each grid
+// is looked for once and shared; a grid that is missing or fails to load is
+// not looked for again unless GridPath changes.
+func FindGrid(name string) (*Grid, error) {
+	key := name + string(filepath.ListSeparator) + strings.Join(GridPath, string(filepath.ListSeparator))
+	gridMu.Lock()
+	load, ok := gridCache[key]
+	if !ok {
+		load = &gridLoad{}
+		gridCache[key] = load
+	}
+	gridMu.Unlock()
+
+	load.once.Do(func() {
+		load.grid, load.err = findGrid(name)
+	})
+	return load.grid, load.err
+}
+
+// findGrid searches for and loads a grid file
+func findGrid(name string) (*Grid, error) {
+	var candidates []string
+	if filepath.IsAbs(name) || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		candidates = []string{name}
+	} else {
+		for _, dir := range GridPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	for _, path := range candidates {
+		if filepath.Ext(path) == "" {
+			path += ".las"
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		return LoadGrid(path)
+	}
+	return nil, fmt.Errorf("grid %s not found", name)
+}
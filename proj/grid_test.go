@@ -0,0 +1,410 @@
+package proj
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Shifts in seconds, north and east, that grids in these tests hold. The
+// parent shift is linear so that interpolation reproduces it exactly.
+func parentShift(lat, lon float64) (dlat, dlon float64) {
+	return 0.1 + 0.02*(lat-30) + 0.01*(lon+100), -1 - 0.03*(lat-30) + 0.02*(lon+100)
+}
+
+func childShift(lat, lon float64) (dlat, dlon float64) {
+	return 2, 3
+}
+
+type testGrid struct {
+	name, parent                   string
+	minLat, minLon, maxLat, maxLon float64
+	inc                            float64
+	shift                          func(lat, lon float64) (float64, float64)
+}
+
+// writeNTv2 writes grids to an NTv2 file, parents first
+func writeNTv2(t *testing.T, path string, order binary.AppendByteOrder, grids ...testGrid) {
+	t.Helper()
+	var data []byte
+	record := func(key string, value any) {
+		data = append(data, []byte((key + "        ")[:8])...)
+		switch v := value.(type) {
+		case int:
+			data = order.AppendUint32(data, uint32(v))
+			data = append(data, 0, 0, 0, 0)
+		case float64:
+			data = order.AppendUint64(data, math.Float64bits(v))
+		case string:
+			data = append(data, []byte((v + "        ")[:8])...)
+		}
+	}
+	record("NUM_OREC", 11)
+	record("NUM_SREC", 11)
+	record("NUM_FILE", len(grids))
+	record("GS_TYPE", "SECONDS")
+	record("VERSION", "NTv2.0")
+	record("SYSTEM_F", "NAD27")
+	record("SYSTEM_T", "NAD83")
+	record("MAJOR_F", Clarke1866Ellipsoid.A)
+	record("MINOR_F", Clarke1866Ellipsoid.A*(1-Clarke1866Ellipsoid.F))
+	record("MAJOR_T", GRS80Ellipsoid.A)
+	record("MINOR_T", GRS80Ellipsoid.A*(1-GRS80Ellipsoid.F))
+	for _, g := range grids {
+		rows := int(math.Round((g.maxLat-g.minLat)/g.inc)) + 1
+		cols := int(math.Round((g.maxLon-g.minLon)/g.inc)) + 1
+		record("SUB_NAME", g.name)
+		record("PARENT", g.parent)
+		record("CREATED", "20250101")
+		record("UPDATED", "20250101")
+		record("S_LAT", g.minLat*3600)
+		record("N_LAT", g.maxLat*3600)
+		record("E_LONG", -g.maxLon*3600)
+		record("W_LONG", -g.minLon*3600)
+		record("LAT_INC", g.inc*3600)
+		record("LONG_INC", g.inc*3600)
+		record("GS_COUNT", rows*cols)
+		// South to north, then east to west, with longitude positive west
+		for row := 0; row < rows; row++ {
+			for col := cols - 1; col >= 0; col-- {
+				dlat, dlon := g.shift(g.minLat+float64(row)*g.inc, g.minLon+float64(col)*g.inc)
+				for _, v := range []float64{dlat, -dlon, 0, 0} {
+					data = order.AppendUint32(data, math.Float32bits(float32(v)))
+				}
+			}
+		}
+	}
+	record("END", "")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeNADCON writes a grid to a .las and .los file pair
+func writeNADCON(t *testing.T, base string, g testGrid, lonInc float64) {
+	t.Helper()
+	rows := int(math.Round((g.maxLat-g.minLat)/g.inc)) + 1
+	cols := int(math.Round((g.maxLon-g.minLon)/lonInc)) + 1
+	for i, ext := range []string{".las", ".los"} {
+		header := []byte("NADCON TEST GRID                                        NADGRD  ")
+		order := binary.LittleEndian
+		for _, v := range []int32{int32(cols), int32(rows), 1} {
+			header = order.AppendUint32(header, uint32(v))
+		}
+		for _, v := range []float32{float32(g.minLon), float32(lonInc), float32(g.minLat), float32(g.inc), 0} {
+			header = order.AppendUint32(header, math.Float32bits(v))
+		}
+		recordSize := (cols + 1) * 4
+		if len(header) > recordSize {
+			t.Fatalf("NADCON header needs %d columns", len(header)/4)
+		}
+		data := append(header, make([]byte, recordSize-len(header))...)
+		for row := 0; row < rows; row++ {
+			data = append(data, 0, 0, 0, 0)
+			for col := 0; col < cols; col++ {
+				dlat, dlon := g.shift(g.minLat+float64(row)*g.inc, g.minLon+float64(col)*lonInc)
+				v := dlat
+				if i == 1 {
+					v = -dlon // Positive west
+				}
+				data = order.AppendUint32(data, math.Float32bits(float32(v)))
+			}
+		}
+		if err := os.WriteFile(base+ext, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var (
+	testParent = testGrid{name: "PARENT", parent: "NONE", minLat: 30, minLon: -100, maxLat: 40, maxLon: -90, inc: 1, shift: parentShift}
+	testChild  = testGrid{name: "CHILD", parent: "PARENT", minLat: 34, minLon: -96, maxLat: 36, maxLon: -94, inc: 0.5, shift: childShift}
+)
+
+// checkShift checks a grid's shift at a point against an expected shift
+func checkShift(t *testing.T, g *Grid, lat, lon float64, want func(lat, lon float64) (float64, float64)) {
+	t.Helper()
+	gotLat, gotLon, ok := g.Shift(lat, lon)
+	if !ok {
+		t.Fatalf("Shift(%g, %g) is outside the grid", lat, lon)
+	}
+	dlat, dlon := want(lat, lon)
+	if math.Abs((gotLat-lat)*3600-dlat) > 1e-6 || math.Abs((gotLon-lon)*3600-dlon) > 1e-6 {
+		t.Errorf("Shift(%g, %g) moves %.7f\", %.7f\"; want %.7f\", %.7f\"",
+			lat, lon, (gotLat-lat)*3600, (gotLon-lon)*3600, dlat, dlon)
+	}
+}
+
+// checkUnshift checks that Unshift undoes Shift at a point whose shifted
+// position is in the same subgrid
+func checkUnshift(t *testing.T, g *Grid, lat, lon float64) {
+	t.Helper()
+	shiftedLat, shiftedLon, _ := g.Shift(lat, lon)
+	backLat, backLon, ok := g.Unshift(shiftedLat, shiftedLon)
+	if !ok || math.Abs(backLat-lat) > 1e-10 || math.Abs(backLon-lon) > 1e-10 {
+		t.Errorf("Unshift(Shift(%g, %g)) = %.12f, %.12f, %v", lat, lon, backLat, backLon, ok)
+	}
+}
+
+func TestNTv2(t *testing.T) {
+	for _, order := range []binary.AppendByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.gsb")
+			writeNTv2(t, path, order, testParent, testChild)
+			g, err := LoadGrid(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Name != "test.gsb" {
+				t.Errorf("Name = %q", g.Name)
+			}
+
+			checkShift(t, g, 32, -98, parentShift)       // Node
+			checkShift(t, g, 32.5, -97.25, parentShift)  // Inside a cell
+			checkShift(t, g, 40, -90, parentShift)       // Northeast corner
+			checkShift(t, g, 30.01, -99.99, parentShift) // Near the southwest corner
+			checkShift(t, g, 35.25, -95.75, childShift)  // Subgrid
+			checkShift(t, g, 36, -94, childShift)        // Subgrid edge
+			checkUnshift(t, g, 32.5, -97.25)
+			checkUnshift(t, g, 35.25, -95.75)
+
+			if g.Covers(41, -95) || g.Covers(35, -89) {
+				t.Error("Covers points outside the grid")
+			}
+			if lat, lon, ok := g.Shift(41, -95); ok || lat != 41 || lon != -95 {
+				t.Errorf("Shift outside the grid = %g, %g, %v", lat, lon, ok)
+			}
+		})
+	}
+}
+
+func TestNADCON(t *testing.T) {
+	dir := t.TempDir()
+	writeNADCON(t, filepath.Join(dir, "test"), testParent, 0.4)
+	for _, name := range []string{"test.las", "test.los"} {
+		g, err := LoadGrid(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Name != "test" {
+			t.Errorf("Name = %q", g.Name)
+		}
+		checkShift(t, g, 32, -98, parentShift)
+		checkShift(t, g, 35.25, -95.75, parentShift)
+		checkShift(t, g, 40, -90, parentShift)
+		checkUnshift(t, g, 35.25, -95.75)
+		if g.Covers(29.9, -95) {
+			t.Error("Covers a point outside the grid")
+		}
+	}
+}
+
+func TestLoadGridErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.gsb")
+	if err := os.WriteFile(bad, []byte("not a grid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{bad, filepath.Join(dir, "missing.gsb"), filepath.Join(dir, "missing.los"), filepath.Join(dir, "grid.tif")} {
+		if _, err := LoadGrid(path); err == nil {
+			t.Errorf("LoadGrid(%s) succeeded", filepath.Base(path))
+		}
+	}
+}
+
+func TestGridShift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.gsb")
+	writeNTv2(t, path, binary.LittleEndian, testParent)
+	g, err := LoadGrid(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shift := &GridShift{Grids: []*Grid{g}, Fallback: NAD27Helmert}
+	lat, lon := shift.ToWGS84(32, -98)
+	wantLat, wantLon, _ := g.Shift(32, -98)
+	if lat != wantLat || lon != wantLon {
+		t.Errorf("ToWGS84 inside the grid = %g, %g; want %g, %g", lat, lon, wantLat, wantLon)
+	}
+	lat, lon = shift.ToWGS84(45, -98)
+	wantLat, wantLon = NAD27Helmert.ToWGS84(45, -98)
+	if lat != wantLat || lon != wantLon {
+		t.Errorf("ToWGS84 outside the grid = %g, %g; want the fallback %g, %g", lat, lon, wantLat, wantLon)
+	}
+	lat, lon = shift.FromWGS84(shift.ToWGS84(45, -98))
+	if math.Abs(lat-45) > 1e-8 || math.Abs(lon+98) > 1e-8 {
+		t.Errorf("fallback round trip gives %g, %g", lat, lon)
+	}
+
+	shift.Fallback = nil
+	if lat, lon := shift.ToWGS84(45, -98); !math.IsNaN(lat) || !math.IsNaN(lon) {
+		t.Errorf("ToWGS84 without a fallback = %g, %g; want NaN", lat, lon)
+	}
+	if lat, lon := shift.FromWGS84(45, -98); !math.IsNaN(lat) || !math.IsNaN(lon) {
+		t.Errorf("FromWGS84 without a fallback = %g, %g; want NaN", lat, lon)
+	}
+}
+
+func TestParsePROJGrids(t *testing.T) {
+	dir := t.TempDir()
+	writeNTv2(t, filepath.Join(dir, "parsetest.gsb"), binary.LittleEndian, testParent, testChild)
+	writeNADCON(t, filepath.Join(dir, "parsetest"), testParent, 0.4)
+	defer func(path []string) { GridPath = path }(GridPath)
+	GridPath = []string{filepath.Join(dir, "missing"), dir}
+
+	for _, grids := range []string{"parsetest.gsb", "@missing.gsb,parsetest.gsb", "parsetest", filepath.Join(dir, "parsetest.gsb")} {
+		c, err := ParsePROJ("+proj=longlat +ellps=clrk66 +nadgrids=" + grids)
+		if err != nil {
+			t.Errorf("+nadgrids=%s: %v", grids, err)
+			continue
+		}
+		lat, lon := c.Inverse(-97.25, 32.5)
+		dlat, dlon := parentShift(32.5, -97.25)
+		if math.Abs((lat-32.5)*3600-dlat) > 1e-6 || math.Abs((lon+97.25)*3600-dlon) > 1e-6 {
+			t.Errorf("+nadgrids=%s: Inverse = %g, %g", grids, lat, lon)
+		}
+		x, y := c.Forward(lat, lon)
+		if math.Abs(x+97.25) > 1e-10 || math.Abs(y-32.5) > 1e-10 {
+			t.Errorf("+nadgrids=%s: Forward(Inverse) = %g, %g", grids, x, y)
+		}
+	}
+
+	c, err := ParsePROJ("+proj=longlat +datum=NAD27 +nadgrids=@null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat, lon := c.Inverse(-97.25, 32.5); lat != 32.5 || lon != -97.25 {
+		t.Errorf("+nadgrids=@null: Inverse = %g, %g; want no shift", lat, lon)
+	}
+
+	for _, grids := range []string{"missing.gsb", "@missing.gsb", "parsetest.gsb,missing.gsb"} {
+		if _, err := ParsePROJ("+proj=longlat +ellps=clrk66 +nadgrids=" + grids); err == nil {
+			t.Errorf("+nadgrids=%s succeeded", grids)
+		}
+	}
+}
+
+func TestFindGridOnce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "oncetest.gsb")
+	writeNTv2(t, path, binary.LittleEndian, testParent)
+	defer func(path []string) { GridPath = path }(GridPath)
+	GridPath = []string{dir}
+
+	grids := make([]*Grid, 8)
+	var wg sync.WaitGroup
+	for i := range grids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g, err := FindGrid("oncetest.gsb")
+			if err != nil {
+				t.Error(err)
+			}
+			grids[i] = g
+		}()
+	}
+	wg.Wait()
+	for _, g := range grids[1:] {
+		if g != grids[0] {
+			t.Fatal("concurrent lookups loaded the grid more than once")
+		}
+	}
+
+	// Later lookups don't read the file again
+	if err := os.WriteFile(path, []byte("not a grid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := FindGrid("oncetest.gsb"); err != nil || g != grids[0] {
+		t.Errorf("second lookup = %p, %v; want the loaded grid", g, err)
+	}
+
+	// Nor search for a missing one again, until GridPath changes
+	if _, err := FindGrid("oncemissing.gsb"); err == nil {
+		t.Fatal("found a missing grid")
+	}
+	writeNTv2(t, filepath.Join(dir, "oncemissing.gsb"), binary.LittleEndian, testParent)
+	if _, err := FindGrid("oncemissing.gsb"); err == nil {
+		t.Error("missing grid searched for again")
+	}
+	GridPath = []string{filepath.Join(dir, "missing"), dir}
+	if _, err := FindGrid("oncemissing.gsb"); err != nil {
+		t.Errorf("grid not found after GridPath changed: %v", err)
+	}
+}
+
+func TestParsePROJTowgs84(t *testing.T) {
+	c, err := ParsePROJ("+proj=longlat +ellps=clrk66 +towgs84=-8,160,176")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat, lon := c.Inverse(-98, 39)
+	wantLat, wantLon := NAD27Helmert.ToWGS84(39, -98)
+	if lat != wantLat || lon != wantLon {
+		t.Errorf("Inverse = %g, %g; want %g, %g", lat, lon, wantLat, wantLon)
+	}
+
+	c, err = ParsePROJ("+proj=utm +zone=31 +ellps=intl +towgs84=-87,-98,-121,0,0,0,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := c.Forward(c.Inverse(500000, 5000000))
+	if math.Abs(x-500000) > 1e-3 || math.Abs(y-5000000) > 1e-3 {
+		t.Errorf("round trip gives %g, %g", x, y)
+	}
+
+	c, err = ParsePROJ("+proj=longlat +towgs84=0,0,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat, lon := c.Inverse(-98, 39); lat != 39 || lon != -98 {
+		t.Errorf("zero +towgs84: Inverse = %g, %g; want no shift", lat, lon)
+	}
+
+	for _, s := range []string{"+towgs84=1,2", "+towgs84=1,2,x"} {
+		if _, err := ParsePROJ("+proj=longlat " + s); err == nil {
+			t.Errorf("%s succeeded", s)
+		}
+	}
+}
+
+func TestNAD27GridShift(t *testing.T) {
+	// NADCON itself, if PROJ's grids are installed, reproduces the published
+	// NAD83 position of Meades Ranch within its accuracy of about 15 cm
+	if g, err := FindGrid("conus"); err == nil {
+		lat, lon, ok := g.Shift(meadesRanchNAD27[0], meadesRanchNAD27[1])
+		if !ok || math.Abs(lat-meadesRanchNAD83[0])*3600 > 0.005 || math.Abs(lon-meadesRanchNAD83[1])*3600 > 0.005 {
+			t.Errorf("conus shifts Meades Ranch to %.9f, %.9f, %v; want the published %.9f, %.9f",
+				lat, lon, ok, meadesRanchNAD83[0], meadesRanchNAD83[1])
+		}
+	} else {
+		t.Logf("Skipping the NADCON check: %v", err)
+	}
+
+	// A conus grid around Meades Ranch holding the published shift of the
+	// station, 0.02620"N and 1.23940"W, must move it onto its NAD83 position
+	published := func(lat, lon float64) (float64, float64) {
+		return (meadesRanchNAD83[0] - meadesRanchNAD27[0]) * 3600, (meadesRanchNAD83[1] - meadesRanchNAD27[1]) * 3600
+	}
+	dir := t.TempDir()
+	writeNADCON(t, filepath.Join(dir, "conus"), testGrid{minLat: 38, minLon: -100, maxLat: 40, maxLon: -97, inc: 0.5, shift: published}, 0.125)
+
+	defer func(path []string) { GridPath = path }(GridPath)
+	GridPath = []string{dir}
+	shift := NAD27Shift()
+	if _, ok := shift.(*GridShift); !ok {
+		t.Fatalf("NAD27Shift = %T; want a grid shift", shift)
+	}
+	lat, lon := shift.ToWGS84(meadesRanchNAD27[0], meadesRanchNAD27[1])
+	if math.Abs(lat-meadesRanchNAD83[0])*3600 > 1e-5 || math.Abs(lon-meadesRanchNAD83[1])*3600 > 1e-5 {
+		t.Errorf("ToWGS84 = %.9f, %.9f; want the published %.9f, %.9f",
+			lat, lon, meadesRanchNAD83[0], meadesRanchNAD83[1])
+	}
+	lat, lon = shift.FromWGS84(meadesRanchNAD83[0], meadesRanchNAD83[1])
+	if math.Abs(lat-meadesRanchNAD27[0])*3600 > 1e-5 || math.Abs(lon-meadesRanchNAD27[1])*3600 > 1e-5 {
+		t.Errorf("FromWGS84 = %.9f, %.9f; want the NAD27 origin", lat, lon)
+	}
+}
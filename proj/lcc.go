@@ -0,0 +1,110 @@
+package proj
+
+import "math"
+
+// LambertConformalConic projects geographic coordinates onto a cone secant to
+// the ellipsoid along two standard parallels, following the formulae of IOGP
+// Guidance Note 7-2. Projected coordinates are in meters.
+type LambertConformalConic struct {
+	es     float64 // Eccentricity
+	lon0   float64 // Central meridian in degrees
+	fe, fn float64 // False easting and northing in meters
+	n      float64 // Cone constant
+	af     float64 // Semi-major axis times the scale and mapping radius factor F
+	r0     float64 // Radius of the parallel through the origin
+}
+
+// NewLambertConformalConic returns a Lambert Conformal Conic projection with
+// standard parallels lat1 and lat2, its origin at lat0, lon0, and false
+// easting and northing in meters. Angles are in degrees. When the standard
+// parallels are equal the cone touches the ellipsoid along that parallel.
+func NewLambertConformalConic(e Ellipsoid, lat0, lon0, lat1, lat2, falseEasting, falseNorthing float64) *LambertConformalConic {
+	return newLambertConformalConic(e, lat0, lon0, lat1, lat2, 1, falseEasting, falseNorthing)
+}
+
+// NewLambertConformalConic1SP returns a Lambert Conformal Conic projection
+// touching the ellipsoid along the parallel through its origin, with scale k0
+// there
+func NewLambertConformalConic1SP(e Ellipsoid, lat0, lon0, k0, falseEasting, falseNorthing float64) *LambertConformalConic {
+	return newLambertConformalConic(e, lat0, lon0, lat0, lat0, k0, falseEasting, falseNorthing)
+}
+
+func newLambertConformalConic(e Ellipsoid, lat0, lon0, lat1, lat2, k0, falseEasting, falseNorthing float64) *LambertConformalConic {
+	p := &LambertConformalConic{
+		es:   math.Sqrt(e.F * (2 - e.F)),
+		lon0: lon0,
+		fe:   falseEasting,
+		fn:   falseNorthing,
+	}
+	phi1, phi2 := lat1*degToRad, lat2*degToRad
+	m1, m2 := p.m(phi1), p.m(phi2)
+	t1, t2 := p.t(phi1), p.t(phi2)
+	if lat1 == lat2 {
+		p.n = math.Sin(phi1)
+	} else {
+		p.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	p.af = k0 * e.A * m1 / (p.n * math.Pow(t1, p.n))
+	p.r0 = p.radius(lat0 * degToRad)
+	return p
+}
+
+// Forward projects a point in degrees to easting and northing in meters
+func (p *LambertConformalConic) Forward(lat, lon float64) (x, y float64) {
+	r := p.radius(lat * degToRad)
+	theta := p.n * angNormalize(lon-p.lon0) * degToRad
+	return p.fe + r*math.Sin(theta), p.fn + p.r0 - r*math.Cos(theta)
+}
+
+// Inverse returns the position in degrees of an easting and northing in meters
+func (p *LambertConformalConic) Inverse(x, y float64) (lat, lon float64) {
+	dx, dy := x-p.fe, p.r0-(y-p.fn)
+	if p.n < 0 {
+		dx, dy = -dx, -dy
+	}
+	r := math.Copysign(math.Hypot(dx, dy), p.n)
+	theta := math.Atan2(dx, dy)
+
+	if r == 0 {
+		lat = math.Copysign(90, p.n)
+	} else {
+		t := math.Pow(r/p.af, 1/p.n)
+		// The conformal latitude, then iterate for the geographic one
+		phi := math.Pi/2 - 2*math.Atan(t)
+		for i := 0; i < 15; i++ {
+			prev := phi
+			esin := p.es * math.Sin(phi)
+			phi = math.Pi/2 - 2*math.Atan(t*math.Pow((1-esin)/(1+esin), p.es/2))
+			if math.Abs(phi-prev) < 1e-14 {
+				break
+			}
+		}
+		lat = phi * radToDeg
+	}
+	lon = angNormalize(p.lon0 + theta/p.n*radToDeg)
+	return lat, lon
+}
+
+// radius returns the radius on the cone of the parallel at latitude phi in
+// radians
+func (p *LambertConformalConic) radius(phi float64) float64 {
+	if math.Abs(phi) >= math.Pi/2 {
+		if phi*p.n > 0 {
+			return 0 // The apex of the cone
+		}
+		return math.Inf(1)
+	}
+	return p.af * math.Pow(p.t(phi), p.n)
+}
+
+// m returns the ratio of the radius of a parallel to the semi-major axis
+func (p *LambertConformalConic) m(phi float64) float64 {
+	esin := p.es * math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-esin*esin)
+}
+
+// t returns the tangent of half the conformal colatitude
+func (p *LambertConformalConic) t(phi float64) float64 {
+	esin := p.es * math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-esin)/(1+esin), p.es/2)
+}
@@ -0,0 +1,49 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLambertConformalConic(t *testing.T) {
+	// NAD27 Texas South Central, IOGP Guidance Note 7-2 worked example
+	clarke1866 := Ellipsoid{A: 6378206.4, F: 1 / 294.9786982}
+	p := NewLambertConformalConic(clarke1866, dm(27, 50), -99, dm(28, 23), dm(30, 17), 2000000*USSurveyFoot, 0)
+	x, y := p.Forward(28.5, -96)
+	if x, y := x/USSurveyFoot, y/USSurveyFoot; math.Abs(x-2963503.91) > 0.01 || math.Abs(y-254759.80) > 0.01 {
+		t.Errorf("Forward = %.3f, %.3f ftUS; want 2963503.91, 254759.80", x, y)
+	}
+	lat, lon := p.Inverse(2963503.91*USSurveyFoot, 254759.80*USSurveyFoot)
+	if math.Abs(lat-28.5) > 1e-7 || math.Abs(lon - -96) > 1e-7 {
+		t.Errorf("Inverse = %.9f, %.9f; want 28.5, -96", lat, lon)
+	}
+}
+
+func TestLambertConformalConicRoundTrip(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat0, lon0, lat1, lat2 float64
+	}{
+		{"Northern", 39, -96, 33, 45},
+		{"Southern", -30, 20, -20, -40},
+		{"One standard parallel", 45, 10, 45, 45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewLambertConformalConic(WGS84Ellipsoid, tt.lat0, tt.lon0, tt.lat1, tt.lat2, 1000000, 500000)
+			if x, y := p.Forward(tt.lat0, tt.lon0); math.Abs(x-1000000) > 1e-6 || math.Abs(y-500000) > 1e-6 {
+				t.Errorf("origin = %f, %f; want the false easting and northing", x, y)
+			}
+			for _, dlat := range []float64{-15, 0, 15} {
+				for _, dlon := range []float64{-20, 0, 20} {
+					lat, lon := tt.lat0+dlat, tt.lon0+dlon
+					x, y := p.Forward(lat, lon)
+					gotLat, gotLon := p.Inverse(x, y)
+					if math.Abs(gotLat-lat) > 1e-9 || math.Abs(gotLon-lon) > 1e-9 {
+						t.Errorf("%g, %g: round trip gives %.10f, %.10f", lat, lon, gotLat, gotLon)
+					}
+				}
+			}
+		})
+	}
+}
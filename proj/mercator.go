@@ -8,6 +8,8 @@ const (
 	minLat   = -85.0511
 	degToRad = math.Pi / 180.0
 	radToDeg = 180.0 / math.Pi
+
+	earthRadius = 6378137.0 // Sphere radius used by Web Mercator (WGS84 semi-major axis)
 )
 
 // pow2 contains pre-calculated powers of 2 for zoom levels 0-21
@@ -115,3 +117,142 @@ func WebMercatorToScreenCoords(x, y float64, zoom int, mapTopLeftPixelX, mapTopL
 
 	return screenX, screenY
 }
+
+// TileCoordsToLatLon converts Web Mercator tile coordinates at the specified zoom
+// level back to WGS84 coordinates. It is the inverse of LatLonToTileCoords.
+//
+// Parameters:
+//   - x: Tile X coordinate (fractional). Values outside 0 to 2^zoom give
+//     longitudes outside -180 to 180.
+//   - y: Tile Y coordinate (fractional)
+//   - zoom: Zoom level (0-21)
+//
+// Returns:
+//   - lat: Latitude in degrees
+//   - lon: Longitude in degrees
+func TileCoordsToLatLon(x, y float64, zoom int) (lat, lon float64) {
+	n := pow2[zoom]
+
+	lon = x/n*360.0 - 180.0
+	lat = math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * radToDeg
+
+	return lat, lon
+}
+
+// LatLonToWebMercator converts WGS84 coordinates to Web Mercator (EPSG:3857)
+// coordinates in meters.
+//
+// Parameters:
+//   - lat: Latitude in degrees (clamped to -85.0511 to 85.0511)
+//   - lon: Longitude in degrees (-180 to 180)
+//
+// Returns:
+//   - x: X coordinate in meters
+//   - y: Y coordinate in meters
+func LatLonToWebMercator(lat, lon float64) (x, y float64) {
+	if lat > maxLat {
+		lat = maxLat
+	} else if lat < minLat {
+		lat = minLat
+	}
+
+	x = lon * degToRad * earthRadius
+	y = math.Log(math.Tan(math.Pi/4+lat*degToRad/2)) * earthRadius
+
+	return x, y
+}
+
+// WebMercatorToLatLon converts Web Mercator (EPSG:3857) coordinates in meters
+// to WGS84 coordinates. It is the inverse of LatLonToWebMercator.
+//
+// Parameters:
+//   - x: X coordinate in meters (-20037508.34 to 20037508.34)
+//   - y: Y coordinate in meters (-20037508.34 to 20037508.34)
+//
+// Returns:
+//   - lat: Latitude in degrees
+//   - lon: Longitude in degrees
+func WebMercatorToLatLon(x, y float64) (lat, lon float64) {
+	lon = x / earthRadius * radToDeg
+	lat = (2*math.Atan(math.Exp(y/earthRadius)) - math.Pi/2) * radToDeg
+
+	return lat, lon
+}
+
+// LatLonToScreen converts WGS84 coordinates to screen pixel coordinates relative
+// to the top-left of the visible map area.
+//
+// Parameters:
+//   - lat: Latitude in degrees
+//   - lon: Longitude in degrees
+//   - zoom: The zoom level of the tiles being drawn.
+//   - mapTopLeftPixelX: The X coordinate (in world pixels) of the map's
+//     top-left corner currently visible on the screen.
+//   - mapTopLeftPixelY: The Y coordinate (in world pixels) of the map's
+//     top-left corner currently visible on the screen.
+//   - tileSize: The display size of a single map tile in pixels (e.g., 256).
+//
+// Returns:
+//   - screenX: The X coordinate on the screen in pixels.
+//   - screenY: The Y coordinate on the screen in pixels.
+func LatLonToScreen(lat, lon float64, zoom int, mapTopLeftPixelX, mapTopLeftPixelY, tileSize float64) (screenX, screenY float64) {
+	tileX, tileY := LatLonToTileCoords(lat, lon, zoom)
+
+	screenX = tileX*tileSize - mapTopLeftPixelX
+	screenY = tileY*tileSize - mapTopLeftPixelY
+
+	return screenX, screenY
+}
+
+// ScreenToLatLon converts screen pixel coordinates relative to the top-left of
+// the visible map area to WGS84 coordinates. It is the inverse of LatLonToScreen.
+//
+// Parameters:
+//   - screenX: The X coordinate on the screen in pixels.
+//   - screenY: The Y coordinate on the screen in pixels.
+//   - zoom: The zoom level of the tiles being drawn.
+//   - mapTopLeftPixelX: The X coordinate (in world pixels) of the map's
+//     top-left corner currently visible on the screen.
+//   - mapTopLeftPixelY: The Y coordinate (in world pixels) of the map's
+//     top-left corner currently visible on the screen.
+//   - tileSize: The display size of a single map tile in pixels (e.g., 256).
+//
+// Returns:
+//   - lat: Latitude in degrees
+//   - lon: Longitude in degrees
+func ScreenToLatLon(screenX, screenY float64, zoom int, mapTopLeftPixelX, mapTopLeftPixelY, tileSize float64) (lat, lon float64) {
+	tileX := (screenX + mapTopLeftPixelX) / tileSize
+	tileY := (screenY + mapTopLeftPixelY) / tileSize
+
+	return TileCoordsToLatLon(tileX, tileY, zoom)
+}
+
+// Mercator is the Mercator projection on an ellipsoid. On a sphere with the
+// WGS84 semi-major axis it is Web Mercator.
+type Mercator struct {
+	e2, es float64 // Eccentricity squared and eccentricity
+	ak0    float64 // Semi-major axis times the scale on the equator
+	lon0   float64 // Central meridian in degrees
+	fe, fn float64 // False easting and northing in meters
+}
+
+// NewMercator returns a Mercator projection with central meridian lon0 in
+// degrees, scale k0 on the equator, and false easting and northing in meters
+func NewMercator(e Ellipsoid, lon0, k0, falseEasting, falseNorthing float64) *Mercator {
+	e2 := e.F * (2 - e.F)
+	return &Mercator{e2: e2, es: math.Sqrt(e2), ak0: e.A * k0, lon0: lon0, fe: falseEasting, fn: falseNorthing}
+}
+
+// Forward projects a point in degrees to easting and northing in meters
+func (p *Mercator) Forward(lat, lon float64) (x, y float64) {
+	x = p.fe + p.ak0*angNormalize(lon-p.lon0)*degToRad
+	y = p.fn + p.ak0*math.Asinh(conformalTan(math.Tan(lat*degToRad), p.es))
+	return x, y
+}
+
+// Inverse returns the position in degrees of an easting and northing in meters
+func (p *Mercator) Inverse(x, y float64) (lat, lon float64) {
+	lat = math.Atan(geographicTan(math.Sinh((y-p.fn)/p.ak0), p.e2, p.es)) * radToDeg
+	lon = angNormalize(p.lon0 + (x-p.fe)/p.ak0*radToDeg)
+	return lat, lon
+}
@@ -33,3 +33,67 @@ func BenchmarkWebMercatorToTileCoords(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkTileCoordsToLatLon(b *testing.B) {
+	coords := [][3]float64{
+		{1, 1, 1},
+		{0, 0, 10},
+		{32768, 32768, 15},
+		{652.215, 1465.090, 12}, // Portland, OR
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range coords {
+			TileCoordsToLatLon(c[0], c[1], int(c[2]))
+		}
+	}
+}
+
+func BenchmarkLatLonToWebMercator(b *testing.B) {
+	coords := [][2]float64{
+		{0, 0},
+		{maxLat, 180},
+		{minLat, -180},
+		{45.51621, -122.67640}, // Portland, OR
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range coords {
+			LatLonToWebMercator(c[0], c[1])
+		}
+	}
+}
+
+func BenchmarkWebMercatorToLatLon(b *testing.B) {
+	coords := [][2]float64{
+		{0, 0},
+		{20037508.34, 20037508.34},
+		{-20037508.34, -20037508.34},
+		{-13656274.0, 5703158.0}, // Portland, OR
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range coords {
+			WebMercatorToLatLon(c[0], c[1])
+		}
+	}
+}
+
+func BenchmarkScreenToLatLon(b *testing.B) {
+	coords := [][2]float64{
+		{0, 0},
+		{400, 300},
+		{800, 600},
+		{123.5, 456.25},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range coords {
+			ScreenToLatLon(c[0], c[1], 4, 755, 1282, 256)
+		}
+	}
+}
@@ -117,3 +117,174 @@ func TestWebMercatorToTileCoords(t *testing.T) {
 		})
 	}
 }
+
+func TestTileCoordsToLatLon(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y    float64
+		zoom    int
+		wantLat float64
+		wantLon float64
+	}{
+		{
+			name:    "Center of map at zoom 1",
+			x:       1.0,
+			y:       1.0,
+			zoom:    1,
+			wantLat: 0,
+			wantLon: 0,
+		},
+		{
+			name:    "Top-left corner at zoom 1",
+			x:       0.0,
+			y:       0.0,
+			zoom:    1,
+			wantLat: 85.0511287798,
+			wantLon: -180,
+		},
+		{
+			name:    "Bottom-right corner at zoom 1",
+			x:       2.0,
+			y:       2.0,
+			zoom:    1,
+			wantLat: -85.0511287798,
+			wantLon: 180,
+		},
+		{
+			name:    "Middle of tile (1,1) at zoom 1",
+			x:       1.5,
+			y:       1.0,
+			zoom:    1,
+			wantLat: 0,
+			wantLon: 90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLat, gotLon := TileCoordsToLatLon(tt.x, tt.y, tt.zoom)
+			if math.Abs(gotLat-tt.wantLat) > 1e-6 || math.Abs(gotLon-tt.wantLon) > 1e-6 {
+				t.Errorf("got (%f, %f); want (%f, %f)",
+					gotLat, gotLon, tt.wantLat, tt.wantLon)
+			}
+		})
+	}
+}
+
+func TestTileCoordsRoundTrip(t *testing.T) {
+	points := [][2]float64{
+		{0, 0},
+		{45.51621, -122.67640}, // Portland, OR
+		{39.8333, -98.5833},    // Kansas
+		{-33.8688, 151.2093},   // Sydney
+		{84.9, 179.9},
+		{-84.9, -179.9},
+	}
+
+	for _, zoom := range []int{0, 5, 12, 19} {
+		for _, p := range points {
+			x, y := LatLonToTileCoords(p[0], p[1], zoom)
+			lat, lon := TileCoordsToLatLon(x, y, zoom)
+			if math.Abs(lat-p[0]) > 1e-9 || math.Abs(lon-p[1]) > 1e-9 {
+				t.Errorf("zoom %d: (%f, %f) round-tripped to (%f, %f)",
+					zoom, p[0], p[1], lat, lon)
+			}
+		}
+	}
+}
+
+func TestLatLonToWebMercator(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lon  float64
+		wantX     float64
+		wantY     float64
+		tolerance float64
+	}{
+		{
+			name:      "Origin",
+			lat:       0,
+			lon:       0,
+			wantX:     0,
+			wantY:     0,
+			tolerance: 1e-6,
+		},
+		{
+			name:      "Antimeridian on the equator",
+			lat:       0,
+			lon:       180,
+			wantX:     20037508.34,
+			wantY:     0,
+			tolerance: 0.01,
+		},
+		{
+			name: "Portland, OR",
+			// Coordinates verified with GDAL:
+			// EPSG:4326 (-122.67640, 45.51621) = EPSG:3857 (-13656274, 5703158)
+			lat:       45.51621,
+			lon:       -122.67640,
+			wantX:     -13656274.0,
+			wantY:     5703158.0,
+			tolerance: 1.0, // Meter-level precision
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotX, gotY := LatLonToWebMercator(tt.lat, tt.lon)
+			if math.Abs(gotX-tt.wantX) > tt.tolerance || math.Abs(gotY-tt.wantY) > tt.tolerance {
+				t.Errorf("LatLonToWebMercator(%f, %f) = (%f, %f); want (%f, %f)",
+					tt.lat, tt.lon, gotX, gotY, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestWebMercatorRoundTrip(t *testing.T) {
+	points := [][2]float64{
+		{0, 0},
+		{45.51621, -122.67640},
+		{-33.8688, 151.2093},
+		{85.0, 180},
+		{-85.0, -180},
+	}
+
+	for _, p := range points {
+		x, y := LatLonToWebMercator(p[0], p[1])
+		lat, lon := WebMercatorToLatLon(x, y)
+		if math.Abs(lat-p[0]) > 1e-9 || math.Abs(lon-p[1]) > 1e-9 {
+			t.Errorf("(%f, %f) round-tripped to (%f, %f)", p[0], p[1], lat, lon)
+		}
+
+		// Meters and tile coordinates must agree
+		tileX, tileY := WebMercatorToTileCoords(x, y, 10)
+		wantX, wantY := LatLonToTileCoords(p[0], p[1], 10)
+		if math.Abs(tileX-wantX) > 1e-6 || math.Abs(tileY-wantY) > 1e-6 {
+			t.Errorf("(%f, %f): tile coords via meters (%f, %f); want (%f, %f)",
+				p[0], p[1], tileX, tileY, wantX, wantY)
+		}
+	}
+}
+
+func TestScreenLatLonRoundTrip(t *testing.T) {
+	// A 800x600 view of Kansas at zoom 4
+	const zoom = 4
+	const tileSize = 256.0
+	centerX, centerY := LatLonToTileCoords(39.8333, -98.5833, zoom)
+	topLeftX := centerX*tileSize - 400
+	topLeftY := centerY*tileSize - 300
+
+	// The view center maps to the middle of the screen
+	screenX, screenY := LatLonToScreen(39.8333, -98.5833, zoom, topLeftX, topLeftY, tileSize)
+	if math.Abs(screenX-400) > 1e-6 || math.Abs(screenY-300) > 1e-6 {
+		t.Errorf("center projected to (%f, %f); want (400, 300)", screenX, screenY)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {400, 300}, {800, 600}, {123.5, 456.25}} {
+		lat, lon := ScreenToLatLon(p[0], p[1], zoom, topLeftX, topLeftY, tileSize)
+		x, y := LatLonToScreen(lat, lon, zoom, topLeftX, topLeftY, tileSize)
+		if math.Abs(x-p[0]) > 1e-6 || math.Abs(y-p[1]) > 1e-6 {
+			t.Errorf("screen (%f, %f) round-tripped to (%f, %f)", p[0], p[1], x, y)
+		}
+	}
+}
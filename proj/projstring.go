@@ -0,0 +1,251 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Ellipsoids known by name in PROJ strings
+var projEllipsoids = map[string]Ellipsoid{
+	"WGS84":  WGS84Ellipsoid,
+	"GRS80":  GRS80Ellipsoid,
+	"clrk66": Clarke1866Ellipsoid,
+	"airy":   {A: 6377563.396, F: 1 - 6356256.910/6377563.396},
+	"intl":   {A: 6378388, F: 1 / 297.0},
+	"bessel": {A: 6377397.155, F: 1 / 299.1528128},
+	"krass":  {A: 6378245, F: 1 / 298.3},
+}
+
+// Datums known by name in PROJ strings, by their ellipsoids. NAD83
+// coincides with WGS84 to within a couple of meters; NAD27 is shifted with
+// NAD27Shift.
+var projDatums = map[string]string{
+	"WGS84": "WGS84",
+	"NAD83": "GRS80",
+	"NAD27": "clrk66",
+}
+
+// Units known by name in PROJ strings
+var projUnits = map[string]Unit{
+	"m":     Meter,
+	"km":    Kilometer,
+	"ft":    Foot,
+	"us-ft": USSurveyFootUnit,
+}
+
+// ParsePROJ returns the CRS defined by a PROJ string such as
+// "+proj=lcc +lat_1=33 +lat_2=45 +lat_0=39 +lon_0=-96 +x_0=0 +y_0=0
+// +datum=NAD83 +units=m". It supports the longlat, utm, tmerc, lcc, merc and
+// webmerc projections.
+func ParsePROJ(s string) (CRS, error) {
+	params := map[string]string{}
+	for _, field := range strings.Fields(s) {
+		if !strings.HasPrefix(field, "+") {
+			return nil, fmt.Errorf("parsing PROJ string failed: %q is not a +parameter", field)
+		}
+		key, value, _ := strings.Cut(field[1:], "=")
+		params[key] = value
+	}
+	p := projParams{params: params}
+
+	e, err := p.ellipsoid()
+	if err != nil {
+		return nil, err
+	}
+	shift, err := p.datumShift(e)
+	if err != nil {
+		return nil, err
+	}
+	c := &crs{name: strings.TrimSpace(s), datum: shift, unit: Meter, bounds: WorldBounds}
+
+	lat0, lon0 := p.float("lat_0", 0), p.float("lon_0", 0)
+	fe, fn := p.float("x_0", 0), p.float("y_0", 0)
+	k0 := p.float("k_0", p.float("k", 1))
+	switch name := params["proj"]; name {
+	case "longlat", "latlong", "lonlat", "latlon":
+		c.unit = Degree
+	case "utm":
+		zone := int(p.float("zone", 0))
+		if zone < 1 || zone > 60 {
+			return nil, fmt.Errorf("parsing PROJ string failed: UTM zone %q is not from 1 to 60", params["zone"])
+		}
+		u := utm("", e, zone, p.has("south"))
+		c.proj, c.bounds = u.proj, u.bounds
+	case "tmerc", "etmerc":
+		c.proj = NewTransverseMercator(e, lat0, lon0, k0, fe, fn)
+	case "lcc":
+		lat1 := p.float("lat_1", lat0)
+		lat2 := p.float("lat_2", lat1)
+		if lat1 == -lat2 {
+			return nil, fmt.Errorf("parsing PROJ string failed: standard parallels on opposite sides of the equator")
+		}
+		c.proj = newLambertConformalConic(e, lat0, lon0, lat1, lat2, k0, fe, fn)
+	case "merc":
+		if p.has("lat_ts") {
+			// True scale along a parallel rather than the equator
+			sin, cos := math.Sincos(p.float("lat_ts", 0) * degToRad)
+			k0 = cos / math.Sqrt(1-e.F*(2-e.F)*sin*sin)
+		}
+		c.proj = NewMercator(e, lon0, k0, fe, fn)
+	case "webmerc":
+		c.proj = webMercator{}
+	case "":
+		return nil, fmt.Errorf("parsing PROJ string failed: no +proj")
+	default:
+		return nil, fmt.Errorf("parsing PROJ string failed: unsupported projection %q", name)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if c.proj != nil {
+		if c.unit, err = p.unit(); err != nil {
+			return nil, err
+		}
+	}
+	switch axis := params["axis"]; axis {
+	case "", "enu":
+	case "neu":
+		c.axes = NorthEast
+	default:
+		return nil, fmt.Errorf("parsing PROJ string failed: unsupported axis order %q", axis)
+	}
+	return c, nil
+}
+
+// projParams reads the parameters of a PROJ string, keeping the first error
+type projParams struct {
+	params map[string]string
+	err    error
+}
+
+func (p *projParams) has(key string) bool {
+	_, ok := p.params[key]
+	return ok
+}
+
+// float returns a number, or def if the parameter isn't given
+func (p *projParams) float(key string, def float64) float64 {
+	value, ok := p.params[key]
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("parsing PROJ string failed: +%s: %w", key, err)
+	}
+	return f
+}
+
+// ellipsoid returns the ellipsoid given by +datum, +ellps, +R, or +a with +b,
+// +rf or +f
+func (p *projParams) ellipsoid() (Ellipsoid, error) {
+	e := WGS84Ellipsoid
+	if datum, ok := p.params["datum"]; ok {
+		name, ok := projDatums[datum]
+		if !ok {
+			return Ellipsoid{}, fmt.Errorf("parsing PROJ string failed: unsupported datum %q", datum)
+		}
+		e = projEllipsoids[name]
+	}
+	if name, ok := p.params["ellps"]; ok {
+		if e, ok = projEllipsoids[name]; !ok {
+			return Ellipsoid{}, fmt.Errorf("parsing PROJ string failed: unknown ellipsoid %q", name)
+		}
+	}
+	if p.has("R") {
+		e = Ellipsoid{A: p.float("R", 0)}
+	}
+	if p.has("a") {
+		e.A = p.float("a", 0)
+		switch {
+		case p.has("b"):
+			e.F = 1 - p.float("b", 0)/e.A
+		case p.has("rf"):
+			e.F = 1 / p.float("rf", 0)
+		case p.has("f"):
+			e.F = p.float("f", 0)
+		}
+	}
+	if p.err != nil {
+		return Ellipsoid{}, p.err
+	}
+	if e.A <= 0 || e.F < 0 || e.F >= 1 {
+		return Ellipsoid{}, fmt.Errorf("parsing PROJ string failed: invalid ellipsoid")
+	}
+	return e, nil
+}
+
+// datumShift returns the shift to WGS84 given by +nadgrids, +towgs84 or
+// +datum, or nil for none. Grid names starting with @ are optional.
+func (p *projParams) datumShift(e Ellipsoid) (DatumShift, error) {
+	if grids, ok := p.params["nadgrids"]; ok {
+		if grids == "@null" {
+			return nil, nil
+		}
+		shift := &GridShift{}
+		for _, name := range strings.Split(grids, ",") {
+			optional := strings.HasPrefix(name, "@")
+			g, err := FindGrid(strings.TrimPrefix(name, "@"))
+			if err != nil {
+				if optional {
+					continue
+				}
+				return nil, fmt.Errorf("parsing PROJ string failed: %w", err)
+			}
+			shift.Grids = append(shift.Grids, g)
+		}
+		if len(shift.Grids) == 0 {
+			return nil, fmt.Errorf("parsing PROJ string failed: none of the grids %s were found", grids)
+		}
+		return shift, nil
+	}
+
+	if towgs84, ok := p.params["towgs84"]; ok {
+		var v [7]float64
+		fields := strings.Split(towgs84, ",")
+		if len(fields) != 3 && len(fields) != 7 {
+			return nil, fmt.Errorf("parsing PROJ string failed: +towgs84 needs 3 or 7 values")
+		}
+		for i, field := range fields {
+			var err error
+			if v[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("parsing PROJ string failed: +towgs84: %w", err)
+			}
+		}
+		if v == [7]float64{} {
+			return nil, nil
+		}
+		return &Helmert{Ellipsoid: e, Tx: v[0], Ty: v[1], Tz: v[2], Rx: v[3], Ry: v[4], Rz: v[5], Scale: v[6]}, nil
+	}
+
+	if p.params["datum"] == "NAD27" {
+		return NAD27Shift(), nil
+	}
+	return nil, nil
+}
+
+// unit returns the unit of projected coordinates given by +units or +to_meter
+func (p *projParams) unit() (Unit, error) {
+	if p.has("to_meter") {
+		meters := p.float("to_meter", 0)
+		if p.err != nil {
+			return Unit{}, p.err
+		}
+		if meters <= 0 {
+			return Unit{}, fmt.Errorf("parsing PROJ string failed: invalid +to_meter")
+		}
+		return Unit{Name: "unit of " + p.params["to_meter"] + " m", Meters: meters}, nil
+	}
+	name, ok := p.params["units"]
+	if !ok {
+		return Meter, nil
+	}
+	unit, ok := projUnits[name]
+	if !ok {
+		return Unit{}, fmt.Errorf("parsing PROJ string failed: unsupported unit %q", name)
+	}
+	return unit, nil
+}
@@ -0,0 +1,119 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePROJ(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      string
+		epsg     int // Equivalent EPSG code
+		lat, lon float64
+		tol      float64
+	}{
+		{
+			name: "UTM",
+			def:  "+proj=utm +zone=14 +datum=NAD83 +units=m +no_defs",
+			epsg: 26914, lat: 30, lon: -98, tol: 1e-9,
+		},
+		{
+			name: "UTM south",
+			def:  "+proj=utm +zone=56 +south +datum=WGS84",
+			epsg: 32756, lat: -33.9, lon: 151.2, tol: 1e-9,
+		},
+		{
+			name: "Transverse Mercator in US survey feet",
+			def:  "+proj=tmerc +lat_0=40 +lon_0=-76.58333333333333 +k=0.9999375 +x_0=250000 +y_0=0 +ellps=GRS80 +units=us-ft +no_defs",
+			epsg: 2261, lat: 42.5, lon: -76, tol: 1e-6,
+		},
+		{
+			name: "Lambert Conformal Conic in US survey feet",
+			def: "+proj=lcc +lat_1=30.28333333333333 +lat_2=28.38333333333333 +lat_0=27.83333333333333 " +
+				"+lon_0=-99 +x_0=600000 +y_0=4000000 +datum=NAD83 +units=us-ft +no_defs",
+			epsg: 2278, lat: 29.76, lon: -95.37, tol: 1e-6,
+		},
+		{
+			name: "Lambert Conformal Conic with +to_meter",
+			def:  "+proj=lcc +lat_1=41.03333333333333 +lat_2=40.66666666666666 +lat_0=40.16666666666666 +lon_0=-74 +x_0=300000 +y_0=0 +ellps=GRS80 +to_meter=0.3048006096012192",
+			epsg: 2263, lat: 40.748, lon: -73.985, tol: 1e-6,
+		},
+		{
+			name: "Web Mercator as spherical Mercator",
+			def:  "+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext +no_defs",
+			epsg: 3857, lat: 51.5, lon: -0.1, tol: 1e-6,
+		},
+		{
+			name: "Geographic",
+			def:  "+proj=longlat +datum=WGS84 +no_defs",
+			epsg: 4326, lat: 51.5, lon: -0.1, tol: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParsePROJ(tt.def)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ref, err := LookupEPSG(tt.epsg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			x, y := c.Forward(tt.lat, tt.lon)
+			wantX, wantY := ref.Forward(tt.lat, tt.lon)
+			if math.Abs(x-wantX) > tt.tol || math.Abs(y-wantY) > tt.tol {
+				t.Errorf("Forward = (%.6f, %.6f); want (%.6f, %.6f)", x, y, wantX, wantY)
+			}
+			if c.Unit().Meters != ref.Unit().Meters {
+				t.Errorf("Unit = %v; want %v", c.Unit(), ref.Unit())
+			}
+			lat, lon := c.Inverse(x, y)
+			if math.Abs(lat-tt.lat) > 1e-9 || math.Abs(lon-tt.lon) > 1e-9 {
+				t.Errorf("Inverse = (%.10f, %.10f); want (%g, %g)", lat, lon, tt.lat, tt.lon)
+			}
+		})
+	}
+}
+
+func TestParsePROJMercatorTrueScale(t *testing.T) {
+	// IOGP Guidance Note 7-2 worked example, Pulkovo 1942 / Caspian Sea Mercator
+	c, err := ParsePROJ("+proj=merc +lon_0=51 +lat_ts=42 +x_0=0 +y_0=0 +ellps=krass +units=m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := c.Forward(53, 53)
+	if math.Abs(x-165704.29) > 0.01 || math.Abs(y-5171848.07) > 0.01 {
+		t.Errorf("Forward = %.3f, %.3f; want 165704.29, 5171848.07", x, y)
+	}
+}
+
+func TestParsePROJAxis(t *testing.T) {
+	c, err := ParsePROJ("+proj=longlat +ellps=GRS80 +axis=neu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AxisOrder() != NorthEast || c.Unit() != Degree {
+		t.Errorf("got %v, %v; want north-east in degrees", c.AxisOrder(), c.Unit())
+	}
+}
+
+func TestParsePROJErrors(t *testing.T) {
+	for _, def := range []string{
+		"",
+		"proj=utm +zone=14",
+		"+proj=utm",
+		"+proj=utm +zone=61",
+		"+proj=tmerc +lat_0=abc",
+		"+proj=stere +lat_0=90",
+		"+proj=utm +zone=14 +ellps=foo",
+		"+proj=utm +zone=14 +units=furlong",
+		"+proj=lcc +lat_1=30 +lat_2=-30",
+		"+proj=utm +zone=14 +axis=wsu",
+	} {
+		if _, err := ParsePROJ(def); err == nil {
+			t.Errorf("ParsePROJ(%q) succeeded; want an error", def)
+		}
+	}
+}
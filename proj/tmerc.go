@@ -0,0 +1,143 @@
+package proj
+
+import "math"
+
+// Transverse Mercator on the ellipsoid, using the series in the third
+// flattening of L. Krüger (1912) to sixth order as given by C. F. F. Karney,
+// "Transverse Mercator with an accuracy of a few nanometers", J. Geodesy 85,
+// 475-485 (2011). This is accurate to 5 nm within 3900 km of the central
+// meridian, far better than the classic Redfearn formulae used by older
+// software, which degrade quickly outside a UTM zone.
+
+// Order of the Krüger series
+const tmOrder = 6
+
+// GRS80Ellipsoid is the ellipsoid of NAD83. It differs from WGS84's only in
+// the flattening, by 0.1 mm at the poles.
+var GRS80Ellipsoid = Ellipsoid{A: 6378137, F: 1 / 298.257222101}
+
+// TransverseMercator projects geographic coordinates onto a cylinder
+// touching the ellipsoid along a central meridian. Projected coordinates are
+// in meters.
+type TransverseMercator struct {
+	e2, es     float64 // Eccentricity squared and eccentricity
+	k0a        float64 // Scale on the central meridian times the rectifying radius
+	lat0, lon0 float64 // Origin in degrees
+	fe, fn     float64 // False easting and northing in meters
+	xi0        float64 // Rectifying latitude of the origin, in radians
+
+	// Coefficients of the forward series and of the inverse series, negated
+	// so that both are summed the same way
+	alp, bet [tmOrder + 1]float64
+}
+
+// NewTransverseMercator returns a Transverse Mercator projection with its
+// origin at lat0, lon0 in degrees, scale k0 on the central meridian, and false
+// easting and northing in meters
+func NewTransverseMercator(e Ellipsoid, lat0, lon0, k0, falseEasting, falseNorthing float64) *TransverseMercator {
+	n := e.F / (2 - e.F)
+	n2 := n * n
+	p := &TransverseMercator{
+		e2:   e.F * (2 - e.F),
+		lat0: lat0,
+		lon0: lon0,
+		fe:   falseEasting,
+		fn:   falseNorthing,
+	}
+	p.es = math.Sqrt(p.e2)
+	// Rectifying radius: the meridian has length 2πA
+	p.k0a = k0 * e.A / (1 + n) * (1 + n2*(1.0/4+n2*(1.0/64+n2/256)))
+
+	p.alp[1] = n * (1.0/2 + n*(-2.0/3+n*(5.0/16+n*(41.0/180+n*(-127.0/288+n*7891.0/37800)))))
+	p.alp[2] = n2 * (13.0/48 + n*(-3.0/5+n*(557.0/1440+n*(281.0/630+n*-1983433.0/1935360))))
+	p.alp[3] = n2 * n * (61.0/240 + n*(-103.0/140+n*(15061.0/26880+n*167603.0/181440)))
+	p.alp[4] = n2 * n2 * (49561.0/161280 + n*(-179.0/168+n*6601661.0/7257600))
+	p.alp[5] = n2 * n2 * n * (34729.0/80640 + n*-3418889.0/1995840)
+	p.alp[6] = n2 * n2 * n2 * 212378941.0 / 319334400
+
+	p.bet[1] = -n * (1.0/2 + n*(-2.0/3+n*(37.0/96+n*(-1.0/360+n*(-81.0/512+n*96199.0/604800)))))
+	p.bet[2] = -n2 * (1.0/48 + n*(1.0/15+n*(-437.0/1440+n*(46.0/105+n*-1118711.0/3870720))))
+	p.bet[3] = -n2 * n * (17.0/480 + n*(-37.0/840+n*(-209.0/4480+n*5569.0/90720)))
+	p.bet[4] = -n2 * n2 * (4397.0/161280 + n*(-11.0/504+n*-830251.0/7257600))
+	p.bet[5] = -n2 * n2 * n * (4583.0/161280 + n*-108847.0/3991680)
+	p.bet[6] = -n2 * n2 * n2 * 20648693.0 / 638668800
+
+	// On the central meridian the conformal and rectifying latitudes are
+	// related by the same series
+	xip := math.Atan(conformalTan(math.Tan(lat0*degToRad), p.es))
+	p.xi0, _ = p.series(&p.alp, xip, 0)
+	return p
+}
+
+// Forward projects a point in degrees to easting and northing in meters
+func (p *TransverseMercator) Forward(lat, lon float64) (x, y float64) {
+	lam := angNormalize(lon-p.lon0) * degToRad
+
+	// Conformal sphere, then the Gauss-Krüger transverse projection of it
+	taup := conformalTan(math.Tan(lat*degToRad), p.es)
+	xip := math.Atan2(taup, math.Cos(lam))
+	etap := math.Asinh(math.Sin(lam) / math.Hypot(taup, math.Cos(lam)))
+
+	xi, eta := p.series(&p.alp, xip, etap)
+	return p.fe + p.k0a*eta, p.fn + p.k0a*(xi-p.xi0)
+}
+
+// Inverse returns the position in degrees of an easting and northing in meters
+func (p *TransverseMercator) Inverse(x, y float64) (lat, lon float64) {
+	xi := (y-p.fn)/p.k0a + p.xi0
+	eta := (x - p.fe) / p.k0a
+
+	xip, etap := p.series(&p.bet, xi, eta)
+
+	sinhEta, cosXi := math.Sinh(etap), math.Cos(xip)
+	taup := math.Sin(xip) / math.Hypot(sinhEta, cosXi)
+	lat = math.Atan(geographicTan(taup, p.e2, p.es)) * radToDeg
+	lon = angNormalize(p.lon0 + math.Atan2(sinhEta, cosXi)*radToDeg)
+	return lat, lon
+}
+
+// series returns xi + Σ c[j] sin(2j xi) cosh(2j eta) and
+// eta + Σ c[j] cos(2j xi) sinh(2j eta), which map between the conformal
+// sphere and the ellipsoid
+func (p *TransverseMercator) series(c *[tmOrder + 1]float64, xi, eta float64) (float64, float64) {
+	sumXi, sumEta := xi, eta
+	for j := 1; j <= tmOrder; j++ {
+		k := 2 * float64(j)
+		sumXi += c[j] * math.Sin(k*xi) * math.Cosh(k*eta)
+		sumEta += c[j] * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	return sumXi, sumEta
+}
+
+// conformalTan returns the tangent of the conformal latitude for the tangent
+// of the geographic latitude on an ellipsoid with eccentricity es
+func conformalTan(tau, es float64) float64 {
+	if math.IsInf(tau, 0) {
+		return tau
+	}
+	tau1 := math.Hypot(1, tau)
+	sig := math.Sinh(es * math.Atanh(es*tau/tau1))
+	return math.Hypot(1, sig)*tau - sig*tau1
+}
+
+// geographicTan inverts conformalTan by Newton's method, which converges in
+// two or three iterations
+func geographicTan(taup, e2, es float64) float64 {
+	const numit = 5
+	tol := math.Sqrt(tol0) / 10
+	e2m := 1 - e2
+	if math.IsInf(taup, 0) {
+		return taup
+	}
+	tau := taup / e2m
+	stol := tol * math.Max(1, math.Abs(taup))
+	for i := 0; i < numit; i++ {
+		taupa := conformalTan(tau, es)
+		dtau := (taup - taupa) * (1 + e2m*tau*tau) / (e2m * math.Hypot(1, tau) * math.Hypot(1, taupa))
+		tau += dtau
+		if math.Abs(dtau) < stol {
+			break
+		}
+	}
+	return tau
+}
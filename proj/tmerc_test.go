@@ -0,0 +1,34 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransverseMercator(t *testing.T) {
+	// British National Grid, IOGP Guidance Note 7-2 worked example
+	airy := Ellipsoid{A: 6377563.396, F: 1 / 299.3249646}
+	p := NewTransverseMercator(airy, 49, -2, 0.9996012717, 400000, -100000)
+	x, y := p.Forward(50.5, 0.5)
+	if math.Abs(x-577274.99) > 0.01 || math.Abs(y-69740.50) > 0.01 {
+		t.Errorf("Forward = %.3f, %.3f; want 577274.99, 69740.50", x, y)
+	}
+	lat, lon := p.Inverse(577274.99, 69740.50)
+	if math.Abs(lat-50.5) > 1e-7 || math.Abs(lon-0.5) > 1e-7 {
+		t.Errorf("Inverse = %.9f, %.9f; want 50.5, 0.5", lat, lon)
+	}
+}
+
+func TestTransverseMercatorRoundTrip(t *testing.T) {
+	// The Krüger series stays accurate far outside a UTM zone
+	p := NewTransverseMercator(WGS84Ellipsoid, 0, 0, 0.9996, 500000, 0)
+	for _, lat := range []float64{-89.9, -60, -30, -1, 0, 1, 30, 60, 89.9} {
+		for _, lon := range []float64{-30, -3, 0, 3, 30} {
+			x, y := p.Forward(lat, lon)
+			gotLat, gotLon := p.Inverse(x, y)
+			if d := GeodesicDistance(lat, lon, gotLat, gotLon); d > 1e-6 {
+				t.Errorf("%g, %g: round trip is off by %g m", lat, lon, d)
+			}
+		}
+	}
+}
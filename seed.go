@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+	"github.com/OpticalFlyer/goliath/ui"
+)
+
+const (
+	// Zoom levels downloaded by the map's area download, starting at the current zoom
+	seedZoomLevels = 4
+
+	// How long the result of an area download stays on screen
+	seedResultDuration = 5 * time.Second
+)
+
+// runSeed implements the "goliath seed" subcommand, which downloads an area
+// into the tile cache or an MBTiles file for offline use
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	bboxFlag := fs.String("bbox", "", "area to download as minLon,minLat,maxLon,maxLat")
+	minZoom := fs.Int("minzoom", 0, "lowest zoom level to download")
+	maxZoom := fs.Int("maxzoom", 12, "highest zoom level to download")
+	urlTemplate := fs.String("url", "", "XYZ URL template of the tile server (default OpenStreetMap)")
+	sourceName := fs.String("name", "custom", "source name for tiles from -url")
+	format := fs.String("format", "png", "tile format recorded in MBTiles metadata")
+	mbtilesPath := fs.String("mbtiles", "", "write tiles to this MBTiles file instead of the tile cache")
+	rate := fs.Float64("rate", tilemap.DefaultSeedRate, "maximum tiles downloaded per second, 0 for no limit")
+	workers := fs.Int("workers", tilemap.DefaultSeedWorkers, "number of concurrent downloads")
+	dryRun := fs.Bool("dry-run", false, "print the tile count and size estimate without downloading")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goliath seed -bbox minLon,minLat,maxLon,maxLat [options]\n\n"+
+			"Downloads tiles for offline use. Interrupted downloads resume when run again.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *bboxFlag == "" {
+		fs.Usage()
+		return errors.New("-bbox is required")
+	}
+	bbox, err := tilemap.ParseBBox(*bboxFlag)
+	if err != nil {
+		return err
+	}
+
+	var source tilemap.TileSource = tilemap.NewOpenStreetMapSource()
+	if *urlTemplate != "" {
+		source = tilemap.NewXYZSource(*sourceName, *urlTemplate)
+	}
+
+	area := tilemap.SeedArea{Bounds: bbox, MinZoom: *minZoom, MaxZoom: *maxZoom}
+	if err := area.Validate(); err != nil {
+		return err
+	}
+	estimate := area.EstimateBytes()
+	fmt.Printf("%d tiles from %s, zoom %d-%d, about %s\n",
+		area.TileCount(), source.Name(), area.MinZoom, area.MaxZoom, formatBytes(estimate))
+	if *dryRun {
+		return nil
+	}
+
+	var sink tilemap.SeedSink
+	if *mbtilesPath != "" {
+		writer, err := tilemap.CreateMBTiles(*mbtilesPath, source, area, *format)
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		sink = writer
+	} else {
+		cacheDir, err := tilemap.DefaultDiskCacheDir()
+		if err != nil {
+			return err
+		}
+		if estimate > tilemap.DefaultDiskCacheSize {
+			fmt.Printf("Warning: this is larger than the %s tile cache, so older tiles will be evicted; "+
+				"consider -mbtiles\n", formatBytes(tilemap.DefaultDiskCacheSize))
+		}
+		diskCache, err := tilemap.NewDiskCache(cacheDir, tilemap.DefaultDiskCacheSize)
+		if err != nil {
+			return err
+		}
+		sink = tilemap.NewDiskCacheSink(diskCache, source)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	seeder := tilemap.NewSeeder(source, sink)
+	seeder.Rate = *rate
+	seeder.Workers = *workers
+	seeder.OnProgress = func(p tilemap.SeedProgress) {
+		fmt.Fprintf(os.Stderr, "\r%s   ", formatSeedProgress(p))
+	}
+
+	progress, err := seeder.Run(ctx, area)
+	fmt.Fprintln(os.Stderr)
+	if errors.Is(err, context.Canceled) {
+		return errors.New("interrupted; run the same command again to resume")
+	}
+	if err != nil {
+		return err
+	}
+	if progress.Failed > 0 {
+		return fmt.Errorf("%d tiles failed; run the same command again to retry them", progress.Failed)
+	}
+	return nil
+}
+
+// formatSeedProgress describes download progress on one line
+func formatSeedProgress(p tilemap.SeedProgress) string {
+	percent := 100.0
+	if p.Total > 0 {
+		percent = 100 * float64(p.Done) / float64(p.Total)
+	}
+	return fmt.Sprintf("Zoom %d: %d/%d tiles (%.1f%%), %d skipped, %d failed, %s, %s left",
+		p.Zoom, p.Done, p.Total, percent, p.Skipped, p.Failed,
+		formatBytes(p.Bytes), p.Remaining().Round(time.Second))
+}
+
+// formatBytes formats a byte count for display
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+}
+
+// seedJob is an area download started from the map
+type seedJob struct {
+	area   tilemap.SeedArea
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	progress   tilemap.SeedProgress
+	finished   bool
+	finishedAt time.Time
+	err        error
+}
+
+// status returns a line describing the job, and whether it should still be shown
+func (j *seedJob) status() (string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case !j.finished:
+		return "Downloading area: " + formatSeedProgress(j.progress) + " (Esc to cancel)", true
+	case time.Since(j.finishedAt) > seedResultDuration:
+		return "", false
+	case errors.Is(j.err, context.Canceled):
+		return "Area download canceled", true
+	case j.err != nil:
+		return "Area download failed: " + j.err.Error(), true
+	case j.progress.Total == 0:
+		return "Nothing to download at this zoom level", true
+	}
+	return fmt.Sprintf("Area download finished: %d tiles, %d failed", j.progress.Done, j.progress.Failed), true
+}
+
+// startAreaSelection lets the next mouse drag mark an area to download
+func (g *Goliath) startAreaSelection() {
+	if g.diskCache == nil {
+		log.Printf("Area download needs the tile disk cache, which is disabled")
+		return
+	}
+	if _, ok := g.tileMap.Source().(tilemap.ConditionalSource); !ok {
+		log.Printf("%s is already available offline", g.tileMap.Source().Name())
+		return
+	}
+	g.selectingArea = true
+	g.areaDragging = false
+}
+
+// updateAreaSelection tracks the rectangle being dragged out and starts the
+// download when the mouse is released
+func (g *Goliath) updateAreaSelection() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if g.selectingArea {
+			g.selectingArea = false
+			g.areaDragging = false
+		} else if g.measure.active() {
+			g.measure.clear()
+		} else if g.seed != nil {
+			g.seed.cancel()
+		}
+		return
+	}
+	if !g.selectingArea {
+		return
+	}
+
+	x, y := ui.CursorPosition()
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft):
+		g.areaDragging = true
+		g.areaStartX, g.areaStartY = x, y
+		g.areaEndX, g.areaEndY = x, y
+	case inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) && g.areaDragging:
+		g.areaEndX, g.areaEndY = x, y
+		g.selectingArea = false
+		g.areaDragging = false
+		if g.areaStartX != g.areaEndX && g.areaStartY != g.areaEndY {
+			g.startSeed()
+		}
+	case g.areaDragging:
+		g.areaEndX, g.areaEndY = x, y
+	}
+}
+
+// startSeed downloads the selected area into the disk cache in the background,
+// from the current zoom level down seedZoomLevels levels
+func (g *Goliath) startSeed() {
+	if g.seed != nil {
+		g.seed.cancel()
+	}
+
+	source := g.tileMap.Source()
+	minZoom := max(source.MinZoom(), g.tileMap.TileZoom())
+	area := tilemap.SeedArea{
+		Bounds: g.tileMap.RectBounds(float64(g.areaStartX), float64(g.areaStartY),
+			float64(g.areaEndX), float64(g.areaEndY)),
+		MinZoom: minZoom,
+		MaxZoom: min(source.MaxZoom(), minZoom+seedZoomLevels-1),
+	}
+	if area.Validate() != nil {
+		// Zoomed in past the source's tiles
+		log.Printf("Nothing to download: %s has no tiles beyond zoom %d", source.Name(), source.MaxZoom())
+		g.seed = &seedJob{area: area, cancel: func() {}, finished: true, finishedAt: time.Now()}
+		return
+	}
+	log.Printf("Downloading %d tiles, about %s", area.TileCount(), formatBytes(area.EstimateBytes()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &seedJob{area: area, cancel: cancel}
+	job.progress.Total = area.TileCount()
+	g.seed = job
+
+	seeder := tilemap.NewSeeder(source, tilemap.NewDiskCacheSink(g.diskCache, source))
+	seeder.OnProgress = func(p tilemap.SeedProgress) {
+		job.mu.Lock()
+		job.progress = p
+		job.mu.Unlock()
+	}
+
+	go func() {
+		progress, err := seeder.Run(ctx, area)
+		job.mu.Lock()
+		job.progress = progress
+		job.finished = true
+		job.finishedAt = time.Now()
+		job.err = err
+		job.mu.Unlock()
+	}()
+}
+
+// drawAreaSelection draws the rectangle being selected and the download status
+func (g *Goliath) drawAreaSelection(screen *ebiten.Image) {
+	if g.areaDragging {
+		x := float32(math.Min(float64(g.areaStartX), float64(g.areaEndX)))
+		y := float32(math.Min(float64(g.areaStartY), float64(g.areaEndY)))
+		w := float32(math.Abs(float64(g.areaEndX - g.areaStartX)))
+		h := float32(math.Abs(float64(g.areaEndY - g.areaStartY)))
+		vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{0, 60, 120, 60}, false)
+		vector.StrokeRect(screen, x, y, w, h, 2, color.RGBA{0, 120, 255, 255}, false)
+	}
+
+	var text string
+	if g.selectingArea {
+		text = "Drag a rectangle to download (Esc to cancel)"
+	} else if g.seed != nil {
+		var ok bool
+		if text, ok = g.seed.status(); !ok {
+			g.seed = nil
+		}
+	}
+	if text != "" {
+		ebitenutil.DebugPrintAt(screen, text, 10, g.tileMap.ScreenHeight-20)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Size of the debug font, in logical pixels
+const (
+	charWidth  = 6
+	lineHeight = 16
+)
+
+// labelBackground darkens the area behind text drawn over the map
+var labelBackground = color.RGBA{0, 0, 0, 150}
+
+// textWidth returns the width of text in the debug font
+func textWidth(text string) int {
+	return len([]rune(text)) * charWidth
+}
+
+// latin1 replaces characters the debug font can't draw
+func latin1(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFF {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// drawLabel draws a line of text on a dark box centered on x, y
+func drawLabel(screen *ebiten.Image, text string, x, y float64) {
+	text = latin1(text)
+	width := textWidth(text)
+	left := int(x) - width/2
+	top := int(y) - lineHeight/2
+	vector.DrawFilledRect(screen, float32(left-3), float32(top), float32(width+6), lineHeight, labelBackground, false)
+	ebitenutil.DebugPrintAt(screen, text, left, top)
+}
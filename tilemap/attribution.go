@@ -0,0 +1,87 @@
+package tilemap
+
+import (
+	"html"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Attribution credits the provider of a layer's data
+type Attribution struct {
+	Text string
+	URL  string // Page with details, such as the license terms; may be empty
+}
+
+// AttributedSource is a TileSource whose data must be credited on the map
+type AttributedSource interface {
+	TileSource
+	Attribution() Attribution
+}
+
+// OpenStreetMapAttribution is the credit the OpenStreetMap license requires
+var OpenStreetMapAttribution = Attribution{
+	Text: "© OpenStreetMap contributors",
+	URL:  "https://www.openstreetmap.org/copyright",
+}
+
+var (
+	attributionHref = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+	attributionTag  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseAttribution converts the HTML attribution found in MBTiles and
+// PMTiles metadata to plain text and the first link in it. Links other than
+// http and https ones are dropped.
+func ParseAttribution(s string) Attribution {
+	var a Attribution
+	if m := attributionHref.FindStringSubmatch(s); m != nil {
+		link := html.UnescapeString(m[1])
+		if IsWebURL(link) {
+			a.URL = link
+		} else {
+			log.Printf("Ignoring attribution link %q: not an http or https URL", link)
+		}
+	}
+	text := html.UnescapeString(attributionTag.ReplaceAllString(s, ""))
+	a.Text = strings.Join(strings.Fields(text), " ")
+	return a
+}
+
+// IsWebURL reports whether s is an absolute http or https URL, the only kind
+// of link attributions may open
+func IsWebURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Attributions returns the credits of the layers drawn at the current zoom,
+// from the bottom of the stack up, without duplicates
+func (tm *TileMap) Attributions() []Attribution {
+	zoom := tm.TileZoom()
+	var attrs []Attribution
+	seen := make(map[string]int)
+	for _, l := range tm.layers {
+		src, ok := l.source.(AttributedSource)
+		if !ok || !l.drawnAt(zoom) {
+			continue
+		}
+		a := src.Attribution()
+		if a.Text == "" {
+			continue
+		}
+		if i, ok := seen[a.Text]; ok {
+			if attrs[i].URL == "" {
+				attrs[i].URL = a.URL
+			}
+			continue
+		}
+		seen[a.Text] = len(attrs)
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
@@ -0,0 +1,77 @@
+package tilemap
+
+import "testing"
+
+func TestParseAttribution(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want Attribution
+	}{
+		{
+			name: "Plain text",
+			html: "© County GIS",
+			want: Attribution{Text: "© County GIS"},
+		},
+		{
+			name: "Link",
+			html: `<a href="https://www.openstreetmap.org/copyright" target="_blank">&copy; OpenStreetMap</a>  contributors`,
+			want: Attribution{Text: "© OpenStreetMap contributors", URL: "https://www.openstreetmap.org/copyright"},
+		},
+		{
+			name: "First link used",
+			html: `<a href='http://a.example.com/?x=1&amp;y=2'>A</a> <a href="https://b.example.com/">B</a>`,
+			want: Attribution{Text: "A B", URL: "http://a.example.com/?x=1&y=2"},
+		},
+		{
+			name: "Script link dropped",
+			html: `<a href="javascript:alert(1)">Data</a>`,
+			want: Attribution{Text: "Data"},
+		},
+		{
+			name: "File link dropped",
+			html: `<a href="file:///etc/passwd">Data</a>`,
+			want: Attribution{Text: "Data"},
+		},
+		{
+			name: "Relative link dropped",
+			html: `<a href="/copyright">Data</a>`,
+			want: Attribution{Text: "Data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAttribution(tt.html); got != tt.want {
+				t.Errorf("ParseAttribution(%q) = %+v; want %+v", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsWebURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com/terms", want: true},
+		{url: "http://example.com", want: true},
+		{url: "HTTPS://EXAMPLE.COM/", want: true},
+		{url: "", want: false},
+		{url: "example.com/terms", want: false},
+		{url: "//example.com/terms", want: false},
+		{url: "https:///terms", want: false},
+		{url: "javascript:alert(1)", want: false},
+		{url: "file:///etc/passwd", want: false},
+		{url: "mailto:gis@example.com", want: false},
+		{url: "calc.exe", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsWebURL(tt.url); got != tt.want {
+				t.Errorf("IsWebURL(%q) = %v; want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
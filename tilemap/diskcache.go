@@ -0,0 +1,300 @@
+package tilemap
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDiskCacheSize is the default size cap of the on-disk tile cache in bytes
+	DefaultDiskCacheSize = 512 << 20
+	// DefaultTileMaxAge is how long a tile stays fresh when the server sends no expiry.
+	// The OpenStreetMap tile usage policy asks for at least 7 days.
+	DefaultTileMaxAge = 7 * 24 * time.Hour
+
+	tileDataExt = ".tile"
+	tileMetaExt = ".meta"
+)
+
+// CachedTile holds raw tile bytes along with the HTTP validators needed to revalidate them
+type CachedTile struct {
+	Data         []byte    `json:"-"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Expires      time.Time `json:"expires"`
+}
+
+// Stale reports whether the tile has expired and should be revalidated
+func (t *CachedTile) Stale(now time.Time) bool {
+	return !now.Before(t.Expires)
+}
+
+// diskEntry tracks a cached tile file for LRU accounting
+type diskEntry struct {
+	path string // Path relative to the cache dir, without extension
+	size int64
+}
+
+// DiskCache is a persistent tile cache with a size cap and LRU eviction.
+// Tiles are stored as <dir>/<source>/<z>/<x>/<y>.tile with a JSON .meta sidecar.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+// DefaultDiskCacheDir returns the tile cache directory under the user cache dir
+func DefaultDiskCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user cache dir failed: %w", err)
+	}
+	return filepath.Join(base, "goliath", "tiles"), nil
+}
+
+// NewDiskCache opens (creating if needed) a disk cache rooted at dir.
+// maxBytes caps the total size of cached tile data; 0 means unlimited.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache dir %s failed: %w", dir, err)
+	}
+
+	dc := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	if err := dc.loadIndex(); err != nil {
+		return nil, err
+	}
+
+	dc.mu.Lock()
+	dc.evictLocked()
+	dc.mu.Unlock()
+
+	return dc, nil
+}
+
+// loadIndex scans the cache dir and rebuilds the LRU list from file modification times
+func (dc *DiskCache) loadIndex() error {
+	type found struct {
+		entry   *diskEntry
+		modTime time.Time
+	}
+	var files []found
+
+	err := filepath.WalkDir(dc.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != tileDataExt {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dc.dir, strings.TrimSuffix(path, tileDataExt))
+		if err != nil {
+			return err
+		}
+		files = append(files, found{
+			entry:   &diskEntry{path: rel, size: info.Size()},
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scanning cache dir %s failed: %w", dc.dir, err)
+	}
+
+	// Oldest first, so the most recently used tiles end up at the front
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		dc.entries[f.entry.path] = dc.lru.PushFront(f.entry)
+		dc.size += f.entry.size
+	}
+
+	return nil
+}
+
+// Size returns the total size of cached tile data in bytes
+func (dc *DiskCache) Size() int64 {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.size
+}
+
+// Has reports whether a tile is in the cache, without loading it or touching its LRU position
+func (dc *DiskCache) Has(source string, key TileKey) bool {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	_, ok := dc.entries[tilePath(source, key)]
+	return ok
+}
+
+// Get loads a cached tile. The tile is returned even if it is stale.
+func (dc *DiskCache) Get(source string, key TileKey) (*CachedTile, bool) {
+	rel := tilePath(source, key)
+	base := filepath.Join(dc.dir, rel)
+
+	data, err := os.ReadFile(base + tileDataExt)
+	if err != nil {
+		return nil, false
+	}
+
+	tile := &CachedTile{Data: data}
+	if meta, err := os.ReadFile(base + tileMetaExt); err == nil {
+		if err := json.Unmarshal(meta, tile); err != nil {
+			tile.Expires = time.Time{} // Corrupt metadata, force revalidation
+		}
+	}
+
+	// Record the access so LRU order survives restarts
+	now := time.Now()
+	_ = os.Chtimes(base+tileDataExt, now, now)
+
+	dc.mu.Lock()
+	if elem, ok := dc.entries[rel]; ok {
+		dc.lru.MoveToFront(elem)
+	} else {
+		dc.entries[rel] = dc.lru.PushFront(&diskEntry{path: rel, size: int64(len(data))})
+		dc.size += int64(len(data))
+	}
+	dc.mu.Unlock()
+
+	return tile, true
+}
+
+// Put stores a tile and its metadata, evicting least recently used tiles if over the size cap
+func (dc *DiskCache) Put(source string, key TileKey, tile *CachedTile) error {
+	rel := tilePath(source, key)
+	base := filepath.Join(dc.dir, rel)
+
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return fmt.Errorf("creating cache dir for %s failed: %w", rel, err)
+	}
+
+	meta, err := json.Marshal(tile)
+	if err != nil {
+		return fmt.Errorf("encoding metadata for %s failed: %w", rel, err)
+	}
+	if err := writeFileAtomic(base+tileDataExt, tile.Data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(base+tileMetaExt, meta); err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	size := int64(len(tile.Data))
+	if elem, ok := dc.entries[rel]; ok {
+		entry := elem.Value.(*diskEntry)
+		dc.size += size - entry.size
+		entry.size = size
+		dc.lru.MoveToFront(elem)
+	} else {
+		dc.entries[rel] = dc.lru.PushFront(&diskEntry{path: rel, size: size})
+		dc.size += size
+	}
+	dc.evictLocked()
+
+	return nil
+}
+
+// UpdateMeta replaces the validators and expiry of a cached tile without rewriting its data
+func (dc *DiskCache) UpdateMeta(source string, key TileKey, tile *CachedTile) error {
+	base := filepath.Join(dc.dir, tilePath(source, key))
+	meta, err := json.Marshal(tile)
+	if err != nil {
+		return fmt.Errorf("encoding metadata failed: %w", err)
+	}
+	return writeFileAtomic(base+tileMetaExt, meta)
+}
+
+// evictLocked removes least recently used tiles until the cache fits its size cap.
+// dc.mu must be held.
+func (dc *DiskCache) evictLocked() {
+	if dc.maxBytes <= 0 {
+		return
+	}
+	for dc.size > dc.maxBytes {
+		elem := dc.lru.Back()
+		if elem == nil {
+			return
+		}
+		entry := elem.Value.(*diskEntry)
+		base := filepath.Join(dc.dir, entry.path)
+		_ = os.Remove(base + tileDataExt)
+		_ = os.Remove(base + tileMetaExt)
+
+		dc.lru.Remove(elem)
+		delete(dc.entries, entry.path)
+		dc.size -= entry.size
+	}
+}
+
+// tilePath returns the cache path of a tile relative to the cache dir, without extension
+func tilePath(source string, key TileKey) string {
+	return filepath.Join(sanitizeSourceName(source),
+		fmt.Sprint(key.Zoom), fmt.Sprint(key.X), fmt.Sprint(key.Y))
+}
+
+// sanitizeSourceName makes a source name safe to use as a directory name.
+// Lowercase letters, digits and '-' are kept and every other byte becomes
+// _XX in hex, so distinct names never share a directory, even on
+// case-insensitive file systems.
+func sanitizeSourceName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// writeFileAtomic writes data to a temp file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file for %s failed: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing %s failed: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing %s failed: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("renaming %s failed: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package tilemap
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func putTile(t *testing.T, dc *DiskCache, source string, key TileKey, size int) {
+	t.Helper()
+	tile := &CachedTile{Data: bytes.Repeat([]byte{byte(key.X)}, size), Expires: time.Now().Add(time.Hour)}
+	if err := dc.Put(source, key, tile); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskCachePutGet(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := TileKey{Zoom: 3, X: 2, Y: 5}
+	want := &CachedTile{Data: []byte("tile"), ETag: `"abc"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT", Expires: time.Now().Add(time.Hour).Round(time.Second)}
+	if err := dc.Put("osm", key, want); err != nil {
+		t.Fatal(err)
+	}
+
+	if !dc.Has("osm", key) || dc.Has("other", key) || dc.Has("osm", TileKey{Zoom: 3, X: 2, Y: 6}) {
+		t.Error("Has reports the wrong tiles")
+	}
+	got, ok := dc.Get("osm", key)
+	if !ok {
+		t.Fatal("Get missed a stored tile")
+	}
+	if string(got.Data) != "tile" || got.ETag != want.ETag || got.LastModified != want.LastModified || !got.Expires.Equal(want.Expires) {
+		t.Errorf("Get = %+v; want %+v", got, want)
+	}
+	if got.Stale(time.Now()) {
+		t.Error("fresh tile is stale")
+	}
+	if dc.Size() != 4 {
+		t.Errorf("Size = %d; want 4", dc.Size())
+	}
+
+	// Replacing a tile replaces its size
+	putTile(t, dc, "osm", key, 10)
+	if dc.Size() != 10 {
+		t.Errorf("Size after replacing = %d; want 10", dc.Size())
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := TileKey{Zoom: 1, X: 0}, TileKey{Zoom: 1, X: 1}, TileKey{Zoom: 1, X: 1, Y: 1}
+	putTile(t, dc, "osm", a, 100)
+	putTile(t, dc, "osm", b, 100)
+	if _, ok := dc.Get("osm", a); !ok { // a is now more recently used than b
+		t.Fatal("Get missed a stored tile")
+	}
+	putTile(t, dc, "osm", c, 100)
+
+	if !dc.Has("osm", a) || dc.Has("osm", b) || !dc.Has("osm", c) {
+		t.Errorf("after eviction has a=%v b=%v c=%v; want b evicted",
+			dc.Has("osm", a), dc.Has("osm", b), dc.Has("osm", c))
+	}
+	if dc.Size() != 200 {
+		t.Errorf("Size = %d; want 200", dc.Size())
+	}
+	base := filepath.Join(dc.dir, tilePath("osm", b))
+	for _, ext := range []string{tileDataExt, tileMetaExt} {
+		if _, err := os.Stat(base + ext); !os.IsNotExist(err) {
+			t.Errorf("evicted %s file still exists", ext)
+		}
+	}
+}
+
+func TestDiskCacheLoadIndex(t *testing.T) {
+	dir := t.TempDir()
+	dc, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []TileKey{{Zoom: 2, X: 0}, {Zoom: 2, X: 1}, {Zoom: 2, X: 2}}
+	for _, key := range keys {
+		putTile(t, dc, "osm", key, 100)
+	}
+
+	// The index is rebuilt from modification times, so make the middle tile
+	// the least recently used
+	old := time.Now().Add(-time.Hour)
+	for i, key := range []TileKey{keys[1], keys[0], keys[2]} {
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, tilePath("osm", key))+tileDataExt, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Size() != 300 {
+		t.Errorf("reopened Size = %d; want 300", reopened.Size())
+	}
+	for _, key := range keys {
+		if !reopened.Has("osm", key) {
+			t.Errorf("reopened cache lost %v", key)
+		}
+	}
+
+	capped, err := NewDiskCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capped.Size() != 200 || capped.Has("osm", keys[1]) || !capped.Has("osm", keys[0]) || !capped.Has("osm", keys[2]) {
+		t.Errorf("reopening with a smaller cap kept %v %v %v with size %d; want the oldest evicted",
+			capped.Has("osm", keys[0]), capped.Has("osm", keys[1]), capped.Has("osm", keys[2]), capped.Size())
+	}
+}
+
+func TestDiskCacheCorruptMeta(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := TileKey{Zoom: 4, X: 3, Y: 2}
+	putTile(t, dc, "osm", key, 10)
+	if err := os.WriteFile(filepath.Join(dc.dir, tilePath("osm", key))+tileMetaExt, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tile, ok := dc.Get("osm", key)
+	if !ok {
+		t.Fatal("Get missed a tile with corrupt metadata")
+	}
+	if len(tile.Data) != 10 || !tile.Stale(time.Now()) {
+		t.Errorf("tile with corrupt metadata has %d bytes, stale %v; want 10 bytes, stale", len(tile.Data), tile.Stale(time.Now()))
+	}
+}
+
+func TestTilePathDistinct(t *testing.T) {
+	names := []string{"", "_", "a.b", "a_b", "a/b", "a\\b", "A_b", "a_2Eb", "osm", "OSM", "osm@512px", "..", "a b"}
+	seen := map[string]string{}
+	for _, name := range names {
+		dir := sanitizeSourceName(name)
+		if other, ok := seen[dir]; ok {
+			t.Errorf("sources %q and %q share directory %q", name, other, dir)
+		}
+		seen[dir] = name
+		if dir == "." || dir == ".." || filepath.Base(dir) != dir {
+			t.Errorf("source %q maps to unsafe directory %q", name, dir)
+		}
+	}
+}
+
+func TestResponseExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(2 * time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Time
+	}{
+		{"No headers", http.Header{}, now.Add(DefaultTileMaxAge)},
+		{"Max-age", http.Header{"Cache-Control": {"public, max-age=3600"}}, now.Add(time.Hour)},
+		{"Max-age upper case", http.Header{"Cache-Control": {"MAX-AGE=60"}}, now.Add(time.Minute)},
+		{"Max-age zero", http.Header{"Cache-Control": {"max-age=0"}}, now},
+		{"Expires", http.Header{"Expires": {expires}}, now.Add(2 * time.Hour)},
+		{"Max-age wins over Expires", http.Header{"Cache-Control": {"max-age=60"}, "Expires": {expires}}, now.Add(time.Minute)},
+		{"Invalid max-age", http.Header{"Cache-Control": {"max-age=soon"}, "Expires": {expires}}, now.Add(2 * time.Hour)},
+		{"Invalid Expires", http.Header{"Expires": {"0"}}, now.Add(DefaultTileMaxAge)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := responseExpiry(&http.Response{Header: tt.header}, now)
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchConditional(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/tile":
+			if r.Header.Get("If-None-Match") == etag {
+				w.Header().Set("Cache-Control", "max-age=600")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte("tile data"))
+		case "/dated":
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+			w.Write([]byte("dated data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetch := func(path string, cached *CachedTile) (*CachedTile, bool, error) {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fetchConditional(server.Client(), req, cached)
+	}
+
+	tile, notModified, err := fetch("/tile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notModified || string(tile.Data) != "tile data" || tile.ETag != etag {
+		t.Fatalf("first fetch = %+v, notModified %v", tile, notModified)
+	}
+	if d := time.Until(tile.Expires); d < 50*time.Second || d > 70*time.Second {
+		t.Errorf("first fetch expires in %v; want about a minute", d)
+	}
+
+	revalidated, notModified, err := fetch("/tile", tile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notModified || string(revalidated.Data) != "tile data" || revalidated.ETag != etag {
+		t.Errorf("revalidation = %+v, notModified %v; want the cached data", revalidated, notModified)
+	}
+	if d := time.Until(revalidated.Expires); d < 590*time.Second || d > 610*time.Second {
+		t.Errorf("revalidated tile expires in %v; want about ten minutes", d)
+	}
+
+	dated, _, err := fetch("/dated", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dated.LastModified != lastModified || dated.ETag != "" {
+		t.Errorf("dated tile has validators %q, %q", dated.ETag, dated.LastModified)
+	}
+	if _, notModified, err = fetch("/dated", dated); err != nil || !notModified {
+		t.Errorf("revalidating by date gives notModified %v, %v", notModified, err)
+	}
+
+	if _, _, err := fetch("/missing", nil); err == nil {
+		t.Error("404 is not an error")
+	}
+	if requests != 5 {
+		t.Errorf("server saw %d requests; want 5", requests)
+	}
+}
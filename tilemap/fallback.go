@@ -0,0 +1,83 @@
+package tilemap
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// maxFallbackDepth limits how many zoom levels up we search for an ancestor tile.
+// Beyond 8 levels a 256px tile would be scaled up from less than a single pixel.
+const maxFallbackDepth = 8
+
+// drawFallback draws a stand-in for a tile that isn't loaded yet, using the closest
+// cached ancestor scaled up, or failing that, any cached children at the next zoom
+// level scaled down. Tiles it draws are recorded in used. It reports whether the
+// whole tile area was covered.
+func (tm *TileMap) drawFallback(screen *ebiten.Image, key TileKey, draw layerDraw, drawX, drawY, tileSize float64, used map[TileKey]bool) bool {
+	if tm.drawAncestor(screen, key, draw, drawX, drawY, tileSize, used) {
+		return true
+	}
+	return tm.drawChildren(screen, key, draw, drawX, drawY, tileSize, used)
+}
+
+// drawAncestor draws the matching sub-rectangle of the nearest cached ancestor tile
+func (tm *TileMap) drawAncestor(screen *ebiten.Image, key TileKey, draw layerDraw, drawX, drawY, tileSize float64, used map[TileKey]bool) bool {
+	for dz := 1; dz <= maxFallbackDepth && dz <= key.Zoom; dz++ {
+		parent := key.ancestor(dz)
+		parentImg, found := tm.tileCache.peek(parent)
+		if !found || parentImg == nil {
+			continue
+		}
+
+		// Size of this tile within the parent, in parent pixels
+		subSize := parentImg.Bounds().Dx() >> dz
+		offsetX := (key.X - parent.X<<dz) * subSize
+		offsetY := (key.Y - parent.Y<<dz) * subSize
+		sub := parentImg.SubImage(image.Rect(offsetX, offsetY, offsetX+subSize, offsetY+subSize)).(*ebiten.Image)
+
+		screen.DrawImage(sub, draw.options(sub, drawX, drawY, tileSize))
+
+		used[parent] = true
+		return true
+	}
+	return false
+}
+
+// drawChildren composites whatever children of the tile are cached at the next zoom level
+func (tm *TileMap) drawChildren(screen *ebiten.Image, key TileKey, draw layerDraw, drawX, drawY, tileSize float64, used map[TileKey]bool) bool {
+	if key.Zoom >= MaxZoomLevel {
+		return false
+	}
+
+	type child struct {
+		key TileKey
+		img *ebiten.Image
+	}
+	var children []child
+	for dy := 0; dy < 2; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			childKey := TileKey{Layer: key.Layer, Zoom: key.Zoom + 1, X: key.X*2 + dx, Y: key.Y*2 + dy}
+			if childImg, found := tm.tileCache.peek(childKey); found && childImg != nil {
+				children = append(children, child{childKey, childImg})
+			}
+		}
+	}
+	if len(children) == 0 {
+		return false
+	}
+
+	// Fill the gaps between partial children
+	if len(children) < 4 && draw.bottom && tm.placeholderTile != nil {
+		screen.DrawImage(tm.placeholderTile, draw.options(tm.placeholderTile, drawX, drawY, tileSize))
+	}
+
+	half := tileSize / 2
+	for _, c := range children {
+		x := drawX + float64(c.key.X-key.X*2)*half
+		y := drawY + float64(c.key.Y-key.Y*2)*half
+		screen.DrawImage(c.img, draw.options(c.img, x, y, half))
+		used[c.key] = true
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+package tilemap
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultFetchWorkers is the default number of concurrent tile downloads
+const DefaultFetchWorkers = 4
+
+const (
+	// fetchRetryMin is how long a tile that failed to fetch waits before it is
+	// requested again. The wait doubles with each failure up to fetchRetryMax,
+	// so a missing tile or a down server isn't hammered every frame.
+	fetchRetryMin = 2 * time.Second
+	fetchRetryMax = 5 * time.Minute
+
+	// maxFetchFailures bounds the failures remembered before those whose wait
+	// is over are forgotten
+	maxFetchFailures = 4096
+)
+
+// fetchRequest is a queued or in-flight tile fetch
+type fetchRequest struct {
+	key      TileKey
+	priority float64 // Lower is more urgent
+	index    int     // Position in the queue, -1 once picked up by a worker
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// fetchQueue is a min-heap of fetch requests ordered by priority
+type fetchQueue []*fetchRequest
+
+func (q fetchQueue) Len() int           { return len(q) }
+func (q fetchQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+
+func (q fetchQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *fetchQueue) Push(x any) {
+	req := x.(*fetchRequest)
+	req.index = len(*q)
+	*q = append(*q, req)
+}
+
+func (q *fetchQueue) Pop() any {
+	old := *q
+	n := len(old)
+	req := old[n-1]
+	old[n-1] = nil
+	req.index = -1
+	*q = old[:n-1]
+	return req
+}
+
+// fetchFailure records a tile that failed to fetch and when to try it again
+type fetchFailure struct {
+	attempts int
+	retryAt  time.Time
+}
+
+// tileFetcher runs a fixed pool of workers that fetch tiles in priority order.
+// Requests that are no longer wanted are cancelled through their context.
+// Tiles that fail are not requested again until their backoff has passed.
+type tileFetcher struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    fetchQueue
+	requests map[TileKey]*fetchRequest // Queued and in-flight requests
+	failures map[TileKey]*fetchFailure
+	closed   bool
+
+	fetch func(ctx context.Context, key TileKey) error
+	now   func() time.Time
+	wg    sync.WaitGroup
+}
+
+// newTileFetcher starts workers goroutines that call fetch for each requested tile
+func newTileFetcher(workers int, fetch func(ctx context.Context, key TileKey) error) *tileFetcher {
+	f := &tileFetcher{
+		requests: make(map[TileKey]*fetchRequest),
+		failures: make(map[TileKey]*fetchFailure),
+		fetch:    fetch,
+		now:      time.Now,
+	}
+	f.cond = sync.NewCond(&f.mu)
+
+	f.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go f.worker()
+	}
+	return f
+}
+
+// request queues a tile fetch, or updates the priority of an already queued
+// one. Tiles waiting to be retried after a failure are ignored.
+func (f *tileFetcher) request(key TileKey, priority float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	if failure, ok := f.failures[key]; ok && f.now().Before(failure.retryAt) {
+		return
+	}
+
+	if req, ok := f.requests[key]; ok {
+		if req.index >= 0 && req.priority != priority {
+			req.priority = priority
+			heap.Fix(&f.queue, req.index)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := &fetchRequest{key: key, priority: priority, ctx: ctx, cancel: cancel}
+	f.requests[key] = req
+	heap.Push(&f.queue, req)
+	f.cond.Signal()
+}
+
+// retain cancels queued and in-flight requests for which keep returns false
+func (f *tileFetcher) retain(keep func(TileKey) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, req := range f.requests {
+		if keep(key) {
+			continue
+		}
+		req.cancel()
+		if req.index >= 0 {
+			heap.Remove(&f.queue, req.index)
+		}
+		delete(f.requests, key)
+	}
+}
+
+// status reports whether a tile is waiting in the queue or being fetched
+func (f *tileFetcher) status(key TileKey) (queued, inFlight bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	req, ok := f.requests[key]
+	if !ok {
+		return false, false
+	}
+	return req.index >= 0, req.index < 0
+}
+
+// backingOff reports whether a tile failed and is waiting to be retried
+func (f *tileFetcher) backingOff(key TileKey) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	failure, ok := f.failures[key]
+	return ok && f.now().Before(failure.retryAt)
+}
+
+// forgetFailures clears the failures of tiles for which forget returns true,
+// so they are fetched again as soon as they are requested
+func (f *tileFetcher) forgetFailures(forget func(TileKey) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key := range f.failures {
+		if forget(key) {
+			delete(f.failures, key)
+		}
+	}
+}
+
+// recordResult updates the failures after a fetch. f.mu must be held.
+func (f *tileFetcher) recordResult(key TileKey, err error) {
+	if err == nil {
+		delete(f.failures, key)
+		return
+	}
+
+	now := f.now()
+	failure, ok := f.failures[key]
+	if !ok {
+		if len(f.failures) >= maxFetchFailures {
+			for k, old := range f.failures {
+				if !now.Before(old.retryAt) {
+					delete(f.failures, k)
+				}
+			}
+		}
+		failure = &fetchFailure{}
+		f.failures[key] = failure
+	}
+	failure.attempts++
+	wait := fetchRetryMin
+	for i := 1; i < failure.attempts && wait < fetchRetryMax; i++ {
+		wait *= 2
+	}
+	if wait > fetchRetryMax {
+		wait = fetchRetryMax
+	}
+	failure.retryAt = now.Add(wait)
+}
+
+// close cancels all requests and waits for the workers to exit
+func (f *tileFetcher) close() {
+	f.mu.Lock()
+	f.closed = true
+	for key, req := range f.requests {
+		req.cancel()
+		delete(f.requests, key)
+	}
+	f.queue = nil
+	f.cond.Broadcast()
+	f.mu.Unlock()
+
+	f.wg.Wait()
+}
+
+func (f *tileFetcher) worker() {
+	defer f.wg.Done()
+
+	for {
+		f.mu.Lock()
+		for len(f.queue) == 0 && !f.closed {
+			f.cond.Wait()
+		}
+		if f.closed {
+			f.mu.Unlock()
+			return
+		}
+		req := heap.Pop(&f.queue).(*fetchRequest)
+		f.mu.Unlock()
+
+		err := f.fetch(req.ctx, req.key)
+		cancelled := req.ctx.Err() != nil || errors.Is(err, context.Canceled)
+		req.cancel()
+
+		f.mu.Lock()
+		// The request may have been cancelled and re-queued while in flight
+		if f.requests[req.key] == req {
+			delete(f.requests, req.key)
+		}
+		if !cancelled {
+			f.recordResult(req.key, err)
+		}
+		f.mu.Unlock()
+	}
+}
@@ -0,0 +1,139 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitIdle waits until the fetcher has no queued or in-flight requests
+func waitIdle(t *testing.T, f *tileFetcher) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		f.mu.Lock()
+		idle := len(f.requests) == 0
+		f.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("fetcher still busy")
+}
+
+func TestTileFetcherBackoff(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[TileKey]int{}
+	failing := map[TileKey]bool{}
+	f := newTileFetcher(2, func(ctx context.Context, key TileKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[key]++
+		if failing[key] {
+			return errors.New("404 Not Found")
+		}
+		return nil
+	})
+	defer f.close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.mu.Lock()
+	f.now = func() time.Time { return now }
+	f.mu.Unlock()
+
+	good, bad := TileKey{Zoom: 1}, TileKey{Zoom: 1, X: 1}
+	failing[bad] = true
+	request := func() {
+		f.request(good, 0)
+		f.request(bad, 1)
+		waitIdle(t, f)
+	}
+
+	// Each frame requests both tiles again, but the failed one waits
+	request()
+	request()
+	request()
+	if calls[good] != 3 || calls[bad] != 1 {
+		t.Fatalf("calls = %d good, %d bad; want 3, 1", calls[good], calls[bad])
+	}
+	if !f.backingOff(bad) || f.backingOff(good) {
+		t.Error("backingOff reports the wrong tiles")
+	}
+
+	// The wait doubles with each failure
+	waits := []time.Duration{fetchRetryMin, 2 * fetchRetryMin, 4 * fetchRetryMin}
+	for i, wait := range waits {
+		now = now.Add(wait - time.Millisecond)
+		request()
+		if calls[bad] != i+1 {
+			t.Fatalf("retried %v after failure %d; want a wait of %v", wait-time.Millisecond, i+1, wait)
+		}
+		now = now.Add(time.Millisecond)
+		request()
+		if calls[bad] != i+2 {
+			t.Fatalf("not retried %v after failure %d", wait, i+1)
+		}
+	}
+
+	// The wait is capped
+	for i := 0; i < 20; i++ {
+		now = now.Add(fetchRetryMax)
+		request()
+	}
+	f.mu.Lock()
+	wait := f.failures[bad].retryAt.Sub(now)
+	f.mu.Unlock()
+	if wait != fetchRetryMax {
+		t.Errorf("wait after many failures = %v; want %v", wait, fetchRetryMax)
+	}
+
+	// A success clears the failure
+	mu.Lock()
+	failing[bad] = false
+	mu.Unlock()
+	now = now.Add(fetchRetryMax)
+	request()
+	if f.backingOff(bad) {
+		t.Error("still backing off after a success")
+	}
+
+	// Forgotten failures are retried straight away
+	mu.Lock()
+	failing[bad] = true
+	mu.Unlock()
+	request()
+	before := calls[bad]
+	request()
+	if calls[bad] != before {
+		t.Fatal("retried without waiting")
+	}
+	f.forgetFailures(func(key TileKey) bool { return key.Layer == bad.Layer })
+	request()
+	if calls[bad] != before+1 {
+		t.Error("forgotten failure not retried")
+	}
+}
+
+func TestTileFetcherCancelledNotFailed(t *testing.T) {
+	started := make(chan struct{})
+	f := newTileFetcher(1, func(ctx context.Context, key TileKey) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	key := TileKey{Zoom: 2}
+	f.request(key, 0)
+	<-started
+	f.retain(func(TileKey) bool { return false })
+	f.close() // Waits for the worker to finish with the request
+
+	f.mu.Lock()
+	_, failed := f.failures[key]
+	f.mu.Unlock()
+	if failed {
+		t.Error("cancelled fetch recorded as a failure")
+	}
+}
@@ -0,0 +1,127 @@
+package tilemap
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultPanFriction is the default decay rate of kinetic panning, per second.
+	// Velocity is multiplied by e^(-friction*dt), so higher values stop sooner.
+	DefaultPanFriction = 4.0
+
+	// velocityWindow is how far back drag samples count towards the release velocity
+	velocityWindow = 100 * time.Millisecond
+	// minKineticSpeed is the speed in pixels per second below which motion stops
+	minKineticSpeed = 20.0
+)
+
+// dragSample is a single drag movement in screen pixels
+type dragSample struct {
+	dx, dy float64
+	t      time.Time
+}
+
+// kineticPan tracks drag velocity and the inertial motion after release
+type kineticPan struct {
+	samples    []dragSample
+	dragStart  time.Time
+	active     bool
+	vx, vy     float64 // Pixels per second
+	lastUpdate time.Time
+}
+
+// BeginDrag starts a drag gesture, stopping any kinetic motion
+func (tm *TileMap) BeginDrag() {
+	tm.StopKinetic()
+	tm.kinetic.samples = tm.kinetic.samples[:0]
+	tm.kinetic.dragStart = time.Now()
+}
+
+// DragBy pans the map by a drag movement and records it for velocity tracking.
+// Call it every frame while dragging, even when the pointer didn't move.
+func (tm *TileMap) DragBy(dx, dy float64) {
+	now := time.Now()
+	tm.kinetic.samples = append(tm.kinetic.samples, dragSample{dx: dx, dy: dy, t: now})
+
+	// Drop samples that fall outside the velocity window
+	cutoff := now.Add(-velocityWindow)
+	i := 0
+	for i < len(tm.kinetic.samples) && tm.kinetic.samples[i].t.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		tm.kinetic.dragStart = tm.kinetic.samples[i-1].t
+		tm.kinetic.samples = append(tm.kinetic.samples[:0], tm.kinetic.samples[i:]...)
+	}
+
+	if dx != 0 || dy != 0 {
+		tm.PanBy(dx, dy)
+	}
+}
+
+// EndDrag finishes a drag gesture and starts kinetic motion with the recent drag velocity
+func (tm *TileMap) EndDrag() {
+	now := time.Now()
+	samples := tm.kinetic.samples
+	tm.kinetic.samples = samples[:0]
+
+	if len(samples) == 0 || now.Sub(samples[len(samples)-1].t) > velocityWindow {
+		return
+	}
+
+	// Average velocity over the window
+	start := tm.kinetic.dragStart
+	if cutoff := now.Add(-velocityWindow); start.Before(cutoff) {
+		start = cutoff
+	}
+	elapsed := now.Sub(start).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.dx
+		sumY += s.dy
+	}
+	vx, vy := sumX/elapsed, sumY/elapsed
+	if math.Hypot(vx, vy) < minKineticSpeed {
+		return
+	}
+
+	tm.kinetic.active = true
+	tm.kinetic.vx = vx
+	tm.kinetic.vy = vy
+	tm.kinetic.lastUpdate = now
+}
+
+// StopKinetic cancels any kinetic panning in progress
+func (tm *TileMap) StopKinetic() {
+	tm.kinetic.active = false
+}
+
+// IsPanningKinetically reports whether the map is still gliding after a drag
+func (tm *TileMap) IsPanningKinetically() bool {
+	return tm.kinetic.active
+}
+
+// updateKinetic applies inertial motion since the last update
+func (tm *TileMap) updateKinetic() {
+	if !tm.kinetic.active {
+		return
+	}
+
+	now := time.Now()
+	dt := now.Sub(tm.kinetic.lastUpdate).Seconds()
+	tm.kinetic.lastUpdate = now
+
+	tm.PanBy(tm.kinetic.vx*dt, tm.kinetic.vy*dt)
+
+	decay := math.Exp(-tm.PanFriction * dt)
+	tm.kinetic.vx *= decay
+	tm.kinetic.vy *= decay
+	if math.Hypot(tm.kinetic.vx, tm.kinetic.vy) < minKineticSpeed {
+		tm.kinetic.active = false
+	}
+}
@@ -0,0 +1,180 @@
+package tilemap
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Blend modes for layers, in ebiten's premultiplied-alpha terms
+var (
+	// BlendNormal draws a layer over the ones below it
+	BlendNormal = ebiten.BlendSourceOver
+
+	// BlendMultiply darkens the layers below by the layer's colors, which
+	// suits hillshades
+	BlendMultiply = ebiten.Blend{
+		BlendFactorSourceRGB:        ebiten.BlendFactorDestinationColor,
+		BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+		BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendOperationRGB:           ebiten.BlendOperationAdd,
+		BlendOperationAlpha:         ebiten.BlendOperationAdd,
+	}
+
+	// BlendScreen lightens the layers below by the layer's colors
+	BlendScreen = ebiten.Blend{
+		BlendFactorSourceRGB:        ebiten.BlendFactorOne,
+		BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+		BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceColor,
+		BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendOperationRGB:           ebiten.BlendOperationAdd,
+		BlendOperationAlpha:         ebiten.BlendOperationAdd,
+	}
+
+	// BlendAdd adds the layer's colors to the layers below
+	BlendAdd = ebiten.BlendLighter
+)
+
+// BlendModeByName returns a blend mode by name: normal, multiply, screen or add
+func BlendModeByName(name string) (ebiten.Blend, bool) {
+	switch name {
+	case "normal", "":
+		return BlendNormal, true
+	case "multiply":
+		return BlendMultiply, true
+	case "screen":
+		return BlendScreen, true
+	case "add":
+		return BlendAdd, true
+	}
+	return ebiten.Blend{}, false
+}
+
+// Layer is one raster layer in a TileMap's stack, such as a basemap, a
+// hillshade or a parcel overlay. Layers share the map's fetch workers and
+// memory cache; their tiles are told apart by TileKey.Layer.
+type Layer struct {
+	Opacity float64 // 0 is invisible, 1 is fully opaque
+	Visible bool
+
+	// Zoom levels the layer is drawn at. Past the source's own zoom range the
+	// layer is overzoomed, so these can be wider or narrower than it.
+	MinZoom int
+	MaxZoom int
+
+	// Blend is how the layer is composited onto the layers below it
+	Blend ebiten.Blend
+
+	source TileSource
+	id     int
+}
+
+// Source returns the tile source the layer draws from
+func (l *Layer) Source() TileSource {
+	return l.source
+}
+
+// drawnAt reports whether the layer is drawn at a zoom level
+func (l *Layer) drawnAt(zoom int) bool {
+	return l.Visible && l.Opacity > 0 && zoom >= l.MinZoom && zoom <= l.MaxZoom
+}
+
+// alpha returns the opacity clamped to the range ColorScale accepts
+func (l *Layer) alpha() float32 {
+	if l.Opacity > 1 {
+		return 1
+	}
+	return float32(l.Opacity)
+}
+
+// newLayer creates a fully opaque, visible layer with the next free id
+func (tm *TileMap) newLayer(source TileSource) *Layer {
+	tm.layerMu.Lock()
+	defer tm.layerMu.Unlock()
+
+	l := &Layer{
+		Opacity: 1,
+		Visible: true,
+		MinZoom: 0,
+		MaxZoom: MaxZoomLevel,
+		Blend:   BlendNormal,
+		source:  source,
+		id:      tm.nextLayerID,
+	}
+	tm.nextLayerID++
+	tm.layerSources[l.id] = source
+	return l
+}
+
+// AddLayer adds a layer on top of the stack
+func (tm *TileMap) AddLayer(source TileSource) *Layer {
+	l := tm.newLayer(source)
+	tm.layers = append(tm.layers, l)
+	return l
+}
+
+// RemoveLayer removes a layer from the stack. Its queued fetches are
+// cancelled and its tiles freed on the next frame.
+func (tm *TileMap) RemoveLayer(l *Layer) {
+	i := slices.Index(tm.layers, l)
+	if i < 0 {
+		return
+	}
+	tm.layers = slices.Delete(tm.layers, i, i+1)
+
+	tm.layerMu.Lock()
+	delete(tm.layerSources, l.id)
+	tm.layerMu.Unlock()
+
+	tm.fetcher.forgetFailures(func(key TileKey) bool {
+		return key.Layer == l.id
+	})
+}
+
+// MoveLayer moves a layer to a position in the stack, where 0 is the bottom
+func (tm *TileMap) MoveLayer(l *Layer, index int) {
+	i := slices.Index(tm.layers, l)
+	if i < 0 {
+		return
+	}
+	tm.layers = slices.Delete(tm.layers, i, i+1)
+	index = max(0, min(index, len(tm.layers)))
+	tm.layers = slices.Insert(tm.layers, index, l)
+}
+
+// Layers returns the layer stack from bottom to top
+func (tm *TileMap) Layers() []*Layer {
+	return tm.layers
+}
+
+// layerSource returns the source of the layer a tile belongs to. It is safe
+// to call from fetch workers.
+func (tm *TileMap) layerSource(key TileKey) (TileSource, bool) {
+	tm.layerMu.RLock()
+	defer tm.layerMu.RUnlock()
+	src, ok := tm.layerSources[key.Layer]
+	return src, ok
+}
+
+// layerDraw holds how a layer's tiles are composited in one frame
+type layerDraw struct {
+	alpha float32
+	blend ebiten.Blend
+	// bottom is set for the lowest layer drawn, which covers gaps with the
+	// placeholder tile; gaps in layers above it stay transparent
+	bottom bool
+}
+
+// options returns draw options for the layer that scale img to a square of
+// size pixels with its top left corner at x, y. Tiles can be any pixel size.
+func (d layerDraw) options(img *ebiten.Image, x, y, size float64) *ebiten.DrawImageOptions {
+	op := &ebiten.DrawImageOptions{}
+	scale := size / float64(img.Bounds().Dx())
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleAlpha(d.alpha)
+	op.Blend = d.blend
+	op.Filter = ebiten.FilterLinear
+	return op
+}
@@ -1,106 +1,242 @@
 package tilemap
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
 	"log"
 	"math"
-	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 
+	_ "golang.org/x/image/webp" // WebP tiles from offline basemaps
+
 	"github.com/OpticalFlyer/goliath/proj"
 )
 
 const (
-	// TileSize is the size of map tiles in pixels
+	// TileSize is the size of map tiles in logical pixels. Tile images may
+	// have more pixels for HiDPI screens.
 	TileSize = 256
 	// MaxZoomLevel is the maximum zoom level supported
 	MaxZoomLevel = 19
 )
 
-// TileRange defines the range of tiles needed to cover the viewport
+// TileRange defines the range of tiles needed to cover the viewport.
+// The world wraps horizontally, so MinX and MaxX may lie outside [0, 2^Zoom);
+// use WrapTileX to get the X coordinate of the tile to draw.
 type TileRange struct {
+	Zoom       int // Integer zoom level of the tiles
 	MinX, MaxX int
 	MinY, MaxY int
 }
 
+// Contains reports whether the range covers a tile on any copy of the world
+func (r TileRange) Contains(key TileKey) bool {
+	if key.Zoom != r.Zoom || key.Y < r.MinY || key.Y > r.MaxY {
+		return false
+	}
+	n := 1 << r.Zoom
+	if r.MaxX-r.MinX+1 >= n {
+		return true
+	}
+	return WrapTileX(key.X-r.MinX, r.Zoom) <= r.MaxX-r.MinX
+}
+
 // TileKey uniquely identifies a map tile
 type TileKey struct {
-	Zoom int
-	X    int
-	Y    int
+	Layer int // Id of the layer within a TileMap; sources ignore it
+	Zoom  int
+	X     int
+	Y     int
+}
+
+// ancestor returns the key of the tile dz zoom levels up that contains this one
+func (k TileKey) ancestor(dz int) TileKey {
+	return TileKey{Layer: k.Layer, Zoom: k.Zoom - dz, X: k.X >> dz, Y: k.Y >> dz}
 }
 
 // TileMap manages the slippy map tile system
 type TileMap struct {
 	// View state
-	CenterLat    float64
-	CenterLon    float64
-	Zoom         int
-	ScreenWidth  int
-	ScreenHeight int
+	Viewport
+
+	// ZoomDuration is how long animated zoom transitions take
+	ZoomDuration time.Duration
+	zoomAnim     zoomAnimation
+
+	// PanFriction is the decay rate of kinetic panning after a drag, per second
+	PanFriction float64
+	kinetic     kineticPan
 
 	// Tile management
-	tileCache       map[TileKey]*ebiten.Image
-	cacheMu         sync.RWMutex
+	layers          []*Layer // Bottom first
+	diskCache       *DiskCache
+	tileCache       *memoryCache
 	placeholderTile *ebiten.Image
-	fetching        map[TileKey]bool
-	fetchingMu      sync.Mutex
+	fetcher         *tileFetcher
+	rotationBuffer  *ebiten.Image
+	vectorStyle     atomic.Pointer[VectorStyle]
+
+	// Sources by layer id, for the fetch workers
+	layerMu      sync.RWMutex
+	layerSources map[int]TileSource
+	nextLayerID  int
+
+	// Device pixels per logical pixel on HiDPI screens, and the pixel size
+	// vector tiles are rendered at to match
+	deviceScale    float64
+	vectorTileSize atomic.Int32
 }
 
-// New creates a new TileMap instance
-func New(screenWidth, screenHeight int, lat, lon float64, zoom int) *TileMap {
+// New creates a new TileMap instance with a basemap layer that loads its tiles from source
+func New(screenWidth, screenHeight int, lat, lon, zoom float64, source TileSource) *TileMap {
 	placeholder := ebiten.NewImage(TileSize, TileSize)
 	placeholder.Fill(color.Black) // Black placeholder
 
-	return &TileMap{
-		ScreenWidth:     screenWidth,
-		ScreenHeight:    screenHeight,
-		CenterLat:       lat,
-		CenterLon:       lon,
-		Zoom:            zoom,
-		tileCache:       make(map[TileKey]*ebiten.Image),
+	tm := &TileMap{
+		Viewport: Viewport{
+			ScreenWidth:  screenWidth,
+			ScreenHeight: screenHeight,
+			CenterLat:    lat,
+			CenterLon:    lon,
+			Zoom:         zoom,
+		},
+		ZoomDuration:    DefaultZoomDuration,
+		PanFriction:     DefaultPanFriction,
+		tileCache:       newMemoryCache(DefaultMemoryCacheTiles, DefaultMemoryCacheBytes),
 		placeholderTile: placeholder,
-		fetching:        make(map[TileKey]bool),
+		layerSources:    make(map[int]TileSource),
+		deviceScale:     1,
 	}
+	tm.vectorTileSize.Store(TileSize)
+	tm.AddLayer(source)
+	tm.vectorStyle.Store(LightVectorStyle())
+	tm.fetcher = newTileFetcher(DefaultFetchWorkers, tm.fetchAndCacheTile)
+
+	return tm
 }
 
-// CalculateVisibleTileRange determines which tiles are needed for the current view
+// Close cancels pending tile fetches and stops the fetch workers
+func (tm *TileMap) Close() {
+	tm.fetcher.close()
+}
+
+// CalculateVisibleTileRange determines which tiles are needed for the current view.
+// Tiles come from the integer zoom level nearest to the fractional zoom. When the
+// map is rotated, the range covers the whole rotated viewport.
 func (tm *TileMap) CalculateVisibleTileRange() (TileRange, float64, float64) {
-	centerXTileF, centerYTileF := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, tm.Zoom)
+	width, height := tm.viewExtent()
+	return tm.calculateTileRange(width, height)
+}
 
-	topLeftXTileF := centerXTileF - float64(tm.ScreenWidth)/2.0/TileSize
-	topLeftYTileF := centerYTileF - float64(tm.ScreenHeight)/2.0/TileSize
-	bottomRightXTileF := centerXTileF + float64(tm.ScreenWidth)/2.0/TileSize
-	bottomRightYTileF := centerYTileF + float64(tm.ScreenHeight)/2.0/TileSize
+// calculateTileRange determines which tiles cover an unrotated view of the given size
+func (tm *TileMap) calculateTileRange(width, height float64) (TileRange, float64, float64) {
+	zoom := tm.TileZoom()
+	tileSize := TileSize * tm.TileScale()
+	centerXTileF, centerYTileF := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, zoom)
+
+	topLeftXTileF := centerXTileF - width/2.0/tileSize
+	topLeftYTileF := centerYTileF - height/2.0/tileSize
+	bottomRightXTileF := centerXTileF + width/2.0/tileSize
+	bottomRightYTileF := centerYTileF + height/2.0/tileSize
 
 	minTileX := int(math.Floor(topLeftXTileF))
 	minTileY := int(math.Floor(topLeftYTileF))
 	maxTileX := int(math.Floor(bottomRightXTileF))
 	maxTileY := int(math.Floor(bottomRightYTileF))
 
-	maxCoord := 1 << tm.Zoom
+	maxCoord := 1 << zoom
 	return TileRange{
-		MinX: max(0, minTileX),
-		MaxX: min(maxCoord-1, maxTileX),
+		Zoom: zoom,
+		MinX: minTileX,
+		MaxX: maxTileX,
 		MinY: max(0, minTileY),
 		MaxY: min(maxCoord-1, maxTileY),
 	}, centerXTileF, centerYTileF
 }
 
-// Draw renders the visible tiles to the screen
+// Draw renders the visible tiles to the screen, rotated by Bearing. The screen
+// is in device pixels, DeviceScale times the logical screen size.
 func (tm *TileMap) Draw(screen *ebiten.Image, debugMode bool) TileRange {
-	tileRange, centerXTileF, centerYTileF := tm.CalculateVisibleTileRange()
+	if tm.Bearing == 0 {
+		return tm.drawTiles(screen, float64(tm.ScreenWidth), float64(tm.ScreenHeight), debugMode)
+	}
+
+	// Draw north-up into a buffer big enough to cover the rotated screen,
+	// then rotate the buffer around the screen center
+	ds := tm.deviceScale
+	width, height := tm.viewExtent()
+	buffer := tm.getRotationBuffer(int(math.Ceil(width*ds)), int(math.Ceil(height*ds)))
+	buffer.Clear()
+	bufferWidth := float64(buffer.Bounds().Dx())
+	bufferHeight := float64(buffer.Bounds().Dy())
+	tileRange := tm.drawTiles(buffer, bufferWidth/ds, bufferHeight/ds, debugMode)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-bufferWidth/2, -bufferHeight/2)
+	op.GeoM.Rotate(-tm.Bearing * math.Pi / 180)
+	op.GeoM.Translate(float64(tm.ScreenWidth)*ds/2, float64(tm.ScreenHeight)*ds/2)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(buffer, op)
 
-	tm.cacheMu.RLock()
-	tm.fetchingMu.Lock()
-	defer tm.fetchingMu.Unlock()
-	defer tm.cacheMu.RUnlock()
+	return tileRange
+}
+
+// drawTiles renders the visible tiles of each layer north-up onto a screen of
+// the given logical size
+func (tm *TileMap) drawTiles(screen *ebiten.Image, width, height float64, debugMode bool) TileRange {
+	tileRange, centerXTileF, centerYTileF := tm.calculateTileRange(width, height)
+
+	// Tiles requested or drawn this frame; these are kept in the fetch queue and cache
+	wanted := make(map[TileKey]bool)
+	used := make(map[TileKey]bool)
+	drawn := make(map[int]bool)
+
+	for _, layer := range tm.layers {
+		if !layer.drawnAt(tileRange.Zoom) {
+			continue
+		}
+		draw := layerDraw{alpha: layer.alpha(), blend: layer.Blend, bottom: len(drawn) == 0}
+		tm.drawLayer(screen, layer, draw, tileRange, centerXTileF, centerYTileF, width, height, debugMode, wanted, used)
+		drawn[layer.id] = true
+	}
+
+	// Cancel fetches for tiles that left the view or belong to hidden layers.
+	// Visible tiles already in the memory cache may still be fetching, such
+	// as stale disk cache tiles being revalidated.
+	tm.fetcher.retain(func(key TileKey) bool {
+		return wanted[key] || used[key] || drawn[key.Layer] && tileRange.Contains(key)
+	})
+
+	// Free memory for tiles that scrolled out of view, keeping everything visible
+	tm.tileCache.prune(func(key TileKey) bool {
+		return drawn[key.Layer] && tileRange.Contains(key) || used[key]
+	})
+
+	return tileRange
+}
+
+// drawLayer draws one layer's tiles, requesting those that are missing.
+// Debug tints and labels are drawn over the bottom layer only.
+func (tm *TileMap) drawLayer(screen *ebiten.Image, layer *Layer, draw layerDraw, tileRange TileRange,
+	centerXTileF, centerYTileF, width, height float64, debugMode bool, wanted, used map[TileKey]bool) {
+	zoom := tileRange.Zoom
+	// Positions and sizes on screen are in device pixels
+	tileSize := TileSize * tm.TileScale() * tm.deviceScale
+	originX, originY := width/2*tm.deviceScale, height/2*tm.deviceScale
+	inSourceRange := zoom >= layer.source.MinZoom()
+	debugMode = debugMode && draw.bottom
 
 	// Debug colors
 	redColor := color.RGBA{R: 255, A: 255}
@@ -109,112 +245,224 @@ func (tm *TileMap) Draw(screen *ebiten.Image, debugMode bool) TileRange {
 	// Iterate through the required tile grid
 	for ty := tileRange.MinY; ty <= tileRange.MaxY; ty++ {
 		for tx := tileRange.MinX; tx <= tileRange.MaxX; tx++ {
-			key := TileKey{Zoom: tm.Zoom, X: tx, Y: ty}
-			tileImg, found := tm.tileCache[key]
-			isFetching := tm.fetching[key]
-
-			drawX := float64(tm.ScreenWidth)/2 - (centerXTileF-float64(tx))*TileSize
-			drawY := float64(tm.ScreenHeight)/2 - (centerYTileF-float64(ty))*TileSize
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(drawX, drawY)
-
-			if !found && !isFetching {
-				tm.fetching[key] = true
-				go tm.fetchAndCacheTile(key)
+			key := TileKey{Layer: layer.id, Zoom: zoom, X: WrapTileX(tx, zoom), Y: ty}
+			tileImg, found := tm.tileCache.get(key)
+			isQueued, isFetching := tm.fetcher.status(key)
+
+			drawX := originX - (centerXTileF-float64(tx))*tileSize
+			drawY := originY - (centerYTileF-float64(ty))*tileSize
+
+			if !found && inSourceRange {
+				// Past the source's max zoom, fetch the ancestor to overzoom from
+				fetchKey := key
+				if dz := key.Zoom - layer.source.MaxZoom(); dz > 0 {
+					fetchKey = key.ancestor(dz)
+				}
+				// Skip tiles already fetched, including those the source
+				// doesn't have, and tiles outside the source's bounds
+				_, fetched := tm.tileCache.peek(fetchKey)
+				if !fetched && !wanted[fetchKey] && sourceCovers(layer.source, fetchKey) {
+					// Fetch tiles closest to the view center first
+					dx := float64(tx) + 0.5 - centerXTileF
+					dy := float64(ty) + 0.5 - centerYTileF
+					tm.fetcher.request(fetchKey, dx*dx+dy*dy)
+					wanted[fetchKey] = true
+				}
+				isQueued, isFetching = tm.fetcher.status(fetchKey)
 			}
 
 			if found && tileImg != nil {
-				screen.DrawImage(tileImg, op)
+				screen.DrawImage(tileImg, draw.options(tileImg, drawX, drawY, tileSize))
 				if debugMode {
 					// Draw blue tint and grid for loaded tiles
 					vector.DrawFilledRect(screen, float32(drawX), float32(drawY),
-						float32(TileSize), float32(TileSize),
+						float32(tileSize), float32(tileSize),
 						color.RGBA{B: 100, A: 100}, false)
 					vector.StrokeRect(screen, float32(drawX), float32(drawY),
-						float32(TileSize), float32(TileSize),
+						float32(tileSize), float32(tileSize),
 						strokeWidth, redColor, false)
 					ebitenutil.DebugPrintAt(screen,
-						fmt.Sprintf("%d/%d/%d", tm.Zoom, tx, ty),
+						fmt.Sprintf("%d/%d/%d", zoom, key.X, ty),
 						int(drawX)+2, int(drawY)+2)
 				}
 			} else {
-				if tm.placeholderTile != nil {
-					screen.DrawImage(tm.placeholderTile, op)
+				if !tm.drawFallback(screen, key, draw, drawX, drawY, tileSize, used) && draw.bottom && tm.placeholderTile != nil {
+					screen.DrawImage(tm.placeholderTile, draw.options(tm.placeholderTile, drawX, drawY, tileSize))
 				}
 				if debugMode {
 					// Draw yellow or red tint for loading/needed tiles
 					fillColor := color.RGBA{R: 50, A: 50} // Red tint for needed
-					if isFetching {
+					if isFetching || isQueued {
 						fillColor = color.RGBA{R: 100, G: 100, B: 0, A: 50} // Yellow tint for fetching
 					}
 					vector.DrawFilledRect(screen, float32(drawX), float32(drawY),
-						float32(TileSize), float32(TileSize),
+						float32(tileSize), float32(tileSize),
 						fillColor, false)
 					vector.StrokeRect(screen, float32(drawX), float32(drawY),
-						float32(TileSize), float32(TileSize),
+						float32(tileSize), float32(tileSize),
 						strokeWidth, redColor, false)
 					status := "Needed"
-					if isFetching {
+					if tm.fetcher.backingOff(key) {
+						status = "Failed"
+					} else if isFetching {
 						status = "Fetching"
+					} else if isQueued {
+						status = "Queued"
 					}
 					ebitenutil.DebugPrintAt(screen,
-						fmt.Sprintf("%s: %d/%d/%d", status, tm.Zoom, tx, ty),
+						fmt.Sprintf("%s: %d/%d/%d", status, zoom, key.X, ty),
 						int(drawX)+2, int(drawY)+2)
 				}
 			}
 		}
 	}
-
-	return tileRange
 }
 
-// fetchAndCacheTile fetches and caches a single tile
-func (tm *TileMap) fetchAndCacheTile(key TileKey) {
-	defer func() {
-		tm.fetchingMu.Lock()
-		delete(tm.fetching, key)
-		tm.fetchingMu.Unlock()
-	}()
+// fetchAndCacheTile fetches and caches a single tile. It runs on a fetch
+// worker, which backs off from tiles that return an error.
+func (tm *TileMap) fetchAndCacheTile(ctx context.Context, key TileKey) error {
+	source, ok := tm.layerSource(key)
+	if !ok {
+		return nil // The layer was removed
+	}
+	if src, ok := source.(VectorTileSource); ok && src.IsVector() {
+		return tm.fetchVectorTile(ctx, src, key)
+	}
+	if src, ok := source.(ConditionalSource); ok && tm.diskCache != nil {
+		return tm.fetchWithDiskCache(ctx, src, key)
+	}
 
-	tileImg, err := fetchTile(key.Zoom, key.X, key.Y)
+	data, err := source.FetchTile(ctx, key)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, ErrTileNotFound) {
+		tm.storeTile(key, nil)
+		return nil
+	}
 	if err != nil {
 		log.Printf("Error fetching tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
-		return
+		return err
 	}
 
-	tm.cacheMu.Lock()
-	tm.tileCache[key] = tileImg
-	tm.cacheMu.Unlock()
+	tileImg, err := decodeTile(data)
+	if err != nil {
+		log.Printf("Error decoding tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		return err
+	}
+
+	tm.storeTile(key, tileImg)
+	return nil
 }
 
-// Helper functions
-func fetchTile(zoom, x, y int) (*ebiten.Image, error) {
-	maxCoord := 1 << zoom
-	if x < 0 || x >= maxCoord || y < 0 || y >= maxCoord {
-		return nil, fmt.Errorf("tile coordinates (%d, %d) out of range for zoom %d", x, y, zoom)
+// fetchWithDiskCache serves a tile from the disk cache when possible.
+// Stale tiles are shown immediately and then revalidated with a conditional request.
+func (tm *TileMap) fetchWithDiskCache(ctx context.Context, src ConditionalSource, key TileKey) error {
+	cached, found := tm.diskCache.Get(src.Name(), key)
+	if found {
+		tileImg, err := decodeTile(cached.Data)
+		if err != nil {
+			log.Printf("Discarding corrupt cached tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+			cached = nil
+		} else {
+			tm.storeTile(key, tileImg)
+			if !cached.Stale(time.Now()) {
+				return nil
+			}
+		}
 	}
 
-	tileURL := fmt.Sprintf("https://tile.openstreetmap.org/%d/%d/%d.png", zoom, x, y)
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", tileURL, nil)
+	tile, notModified, err := src.FetchTileConditional(ctx, key, cached)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, ErrTileNotFound) {
+		tm.storeTile(key, nil)
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("creating request for %s failed: %w", tileURL, err)
+		// Keep showing the stale tile, if any
+		log.Printf("Error fetching tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		return err
 	}
-	req.Header.Set("User-Agent", "FiberForge 1.0")
 
-	resp, err := client.Do(req)
+	if notModified {
+		if err := tm.diskCache.UpdateMeta(src.Name(), key, tile); err != nil {
+			log.Printf("Error updating cached tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		}
+		return nil
+	}
+
+	tileImg, err := decodeTile(tile.Data)
 	if err != nil {
-		return nil, fmt.Errorf("fetching %s failed: %w", tileURL, err)
+		log.Printf("Error decoding tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		return err
+	}
+	tm.storeTile(key, tileImg)
+
+	if err := tm.diskCache.Put(src.Name(), key, tile); err != nil {
+		log.Printf("Error caching tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
+
+// storeTile adds a decoded tile to the memory cache. A nil image marks a tile
+// the source doesn't have.
+func (tm *TileMap) storeTile(key TileKey, tileImg *ebiten.Image) {
+	tm.tileCache.put(key, tileImg)
+}
+
+// SetMemoryCacheLimits sets the maximum number of tiles and bytes of decoded
+// tile images kept in memory. A limit of 0 means unlimited.
+func (tm *TileMap) SetMemoryCacheLimits(maxTiles int, maxBytes int64) {
+	tm.tileCache.setLimits(maxTiles, maxBytes)
+}
+
+// CacheStats returns memory tile cache hit, miss and eviction counters
+func (tm *TileMap) CacheStats() CacheStats {
+	return tm.tileCache.stats()
+}
+
+// SetDiskCache enables persistent caching of tiles from ConditionalSource sources
+func (tm *TileMap) SetDiskCache(dc *DiskCache) {
+	tm.diskCache = dc
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch tile %s: %s", tileURL, resp.Status)
+// DeviceScale returns the number of device pixels per logical pixel
+func (tm *TileMap) DeviceScale() float64 {
+	return tm.deviceScale
+}
+
+// SetDeviceScale sets the number of device pixels per logical pixel, such as
+// ebiten.Monitor().DeviceScaleFactor(). Draw then fills a screen that many
+// times the logical size, while ScreenWidth, ScreenHeight and all positions
+// passed to the map stay in logical pixels, so zoom levels match other maps.
+// Vector tiles are rendered again at the new resolution.
+func (tm *TileMap) SetDeviceScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
 	}
+	if scale == tm.deviceScale {
+		return
+	}
+	tm.deviceScale = scale
+	tm.vectorTileSize.Store(int32(math.Round(TileSize * scale)))
+	tm.clearVectorTiles()
+}
 
-	img, _, err := image.Decode(resp.Body)
+// Source returns the tile source of the bottom layer, or nil if there are no layers
+func (tm *TileMap) Source() TileSource {
+	if len(tm.layers) == 0 {
+		return nil
+	}
+	return tm.layers[0].source
+}
+
+// Helper functions
+func decodeTile(data []byte) (*ebiten.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("decoding image %s failed: %w", tileURL, err)
+		return nil, fmt.Errorf("decoding image failed: %w", err)
 	}
 
 	return ebiten.NewImageFromImage(img), nil
@@ -0,0 +1,91 @@
+package tilemap
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// pngTile returns an encoded blank tile
+func pngTile(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDrawKeepsRevalidation(t *testing.T) {
+	const etag = `"v1"`
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	cancelled := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != etag {
+			http.Error(w, "unconditional request", http.StatusBadRequest)
+			return
+		}
+		started <- struct{}{}
+		select {
+		case <-release:
+			cancelled <- false
+			w.Header().Set("Cache-Control", "max-age=600")
+			w.WriteHeader(http.StatusNotModified)
+		case <-r.Context().Done():
+			cancelled <- true
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// The view at zoom 0 shows a single tile, stale in the disk cache
+	src := NewXYZSource("test", server.URL+"/{z}/{x}/{y}.png")
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := TileKey{Zoom: 0}
+	stale := &CachedTile{Data: pngTile(t), ETag: etag, Expires: time.Now().Add(-time.Hour)}
+	if err := dc.Put(src.Name(), key, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := New(TileSize, TileSize, 0, 0, 0, src)
+	defer tm.Close()
+	tm.SetDiskCache(dc)
+	screen := ebiten.NewImage(TileSize, TileSize)
+
+	// The first frame requests the tile, which shows the stale copy and then
+	// revalidates it
+	tm.Draw(screen, false)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no conditional request")
+	}
+	if _, ok := tm.tileCache.peek(key); !ok {
+		t.Fatal("stale tile not shown while revalidating")
+	}
+
+	// The next frame finds the tile cached and must leave the request alone
+	tm.Draw(screen, false)
+	select {
+	case release <- struct{}{}:
+	case <-cancelled:
+		t.Fatal("revalidation cancelled by the next frame")
+	}
+	if <-cancelled {
+		t.Fatal("revalidation cancelled")
+	}
+	waitIdle(t, tm.fetcher)
+	if cached, ok := dc.Get(src.Name(), key); !ok || cached.Stale(time.Now()) {
+		t.Error("disk cache not refreshed by the revalidation")
+	}
+}
@@ -0,0 +1,111 @@
+package tilemap
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MBTilesMetadata holds the parsed contents of an MBTiles metadata table
+type MBTilesMetadata struct {
+	Name        string
+	Format      string // Tile format: png, jpg, webp or pbf
+	Attribution string
+	MinZoom     int
+	MaxZoom     int
+
+	// Bounds of the tileset, nil if not specified
+	Bounds *BBox
+
+	// Default view, valid if HasCenter is set
+	HasCenter  bool
+	CenterLat  float64
+	CenterLon  float64
+	CenterZoom float64
+
+	// All metadata rows, including those not parsed above
+	Raw map[string]string
+}
+
+// Attribution implements AttributedSource, using the file's metadata
+func (s *MBTilesSource) Attribution() Attribution {
+	return ParseAttribution(s.Metadata.Attribution)
+}
+
+// TileBounds implements BoundedSource, using the file's metadata
+func (s *MBTilesSource) TileBounds() *BBox {
+	return s.Metadata.Bounds
+}
+
+// parseMBTilesMetadata interprets the name/value rows of an MBTiles metadata table.
+// Zoom levels are left at -1 when missing so the caller can fill them in from the tiles.
+func parseMBTilesMetadata(path string, rows map[string]string) MBTilesMetadata {
+	meta := MBTilesMetadata{
+		Name:        rows["name"],
+		Format:      strings.ToLower(rows["format"]),
+		Attribution: rows["attribution"],
+		MinZoom:     -1,
+		MaxZoom:     -1,
+		Raw:         rows,
+	}
+	if meta.Name == "" {
+		meta.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if meta.Format == "" {
+		meta.Format = "png"
+	}
+
+	if z, err := strconv.Atoi(strings.TrimSpace(rows["minzoom"])); err == nil {
+		meta.MinZoom = z
+	}
+	if z, err := strconv.Atoi(strings.TrimSpace(rows["maxzoom"])); err == nil {
+		meta.MaxZoom = z
+	}
+
+	// bounds is "left,bottom,right,top" in degrees
+	if v, ok := parseFloatList(rows["bounds"], 4); ok {
+		meta.Bounds = &BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	}
+
+	// center is "lon,lat,zoom"
+	if v, ok := parseFloatList(rows["center"], 3); ok {
+		meta.HasCenter = true
+		meta.CenterLon = v[0]
+		meta.CenterLat = v[1]
+		meta.CenterZoom = v[2]
+	}
+
+	return meta
+}
+
+// InitialView returns v moved to the tileset's default view: its center if
+// given, otherwise fitted to its bounds. v is returned unchanged if neither is set.
+func (m MBTilesMetadata) InitialView(v Viewport) Viewport {
+	switch {
+	case m.HasCenter:
+		v.CenterLat = m.CenterLat
+		v.CenterLon = m.CenterLon
+		v.Zoom = m.CenterZoom
+		return v.Constrain()
+	case m.Bounds != nil:
+		return v.FitBounds(*m.Bounds, 0)
+	}
+	return v
+}
+
+// parseFloatList parses a comma separated list of exactly n numbers
+func parseFloatList(s string, n int) ([]float64, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+	values := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
@@ -0,0 +1,81 @@
+//go:build js
+
+package tilemap
+
+import (
+	"context"
+	"errors"
+)
+
+// errMBTilesUnsupported is returned in the browser, which has no SQLite driver
+var errMBTilesUnsupported = errors.New("MBTiles is not supported in the browser")
+
+// MBTilesSource reads raster tiles from a local MBTiles (SQLite) file.
+// It is unavailable in WASM builds.
+type MBTilesSource struct {
+	Path     string
+	Metadata MBTilesMetadata
+}
+
+var (
+	_ AttributedSource = (*MBTilesSource)(nil)
+	_ BoundedSource    = (*MBTilesSource)(nil)
+)
+
+// OpenMBTiles always fails in WASM builds
+func OpenMBTiles(path string) (*MBTilesSource, error) {
+	return nil, errMBTilesUnsupported
+}
+
+// Name implements TileSource
+func (s *MBTilesSource) Name() string {
+	return "mbtiles-" + s.Metadata.Name
+}
+
+// MinZoom implements TileSource
+func (s *MBTilesSource) MinZoom() int {
+	return s.Metadata.MinZoom
+}
+
+// MaxZoom implements TileSource
+func (s *MBTilesSource) MaxZoom() int {
+	return s.Metadata.MaxZoom
+}
+
+// FetchTile implements TileSource
+func (s *MBTilesSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	return nil, errMBTilesUnsupported
+}
+
+// Close does nothing in WASM builds
+func (s *MBTilesSource) Close() error {
+	return nil
+}
+
+// MBTilesWriter stores seeded tiles in an MBTiles file.
+// It is unavailable in WASM builds.
+type MBTilesWriter struct {
+	Path string
+}
+
+var _ SeedSink = (*MBTilesWriter)(nil)
+
+// CreateMBTiles always fails in WASM builds
+func CreateMBTiles(path string, source TileSource, area SeedArea, format string) (*MBTilesWriter, error) {
+	return nil, errMBTilesUnsupported
+}
+
+// HasTile implements SeedSink
+func (w *MBTilesWriter) HasTile(key TileKey) bool {
+	return false
+}
+
+// PutTile implements SeedSink
+func (w *MBTilesWriter) PutTile(key TileKey, tile *CachedTile) error {
+	return errMBTilesUnsupported
+}
+
+// Close does nothing in WASM builds
+func (w *MBTilesWriter) Close() error {
+	return nil
+}
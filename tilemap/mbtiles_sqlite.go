@@ -0,0 +1,199 @@
+//go:build !js
+
+package tilemap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver
+)
+
+// MBTilesSource reads raster tiles from a local MBTiles (SQLite) file.
+// MBTiles stores rows in TMS order, so Y is flipped on lookup.
+type MBTilesSource struct {
+	Path     string
+	Metadata MBTilesMetadata
+
+	db *sql.DB
+}
+
+var (
+	_ AttributedSource = (*MBTilesSource)(nil)
+	_ BoundedSource    = (*MBTilesSource)(nil)
+)
+
+// OpenMBTiles opens an MBTiles file read-only and loads its metadata
+func OpenMBTiles(path string) (*MBTilesSource, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("opening MBTiles %s failed: %w", path, err)
+	}
+
+	rows, err := readMBTilesMetadata(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("reading MBTiles metadata from %s failed: %w", path, err)
+	}
+	meta := parseMBTilesMetadata(path, rows)
+
+	// Fill in missing zoom levels from the tiles themselves
+	if meta.MinZoom < 0 || meta.MaxZoom < 0 {
+		var minZoom, maxZoom sql.NullInt64
+		err := db.QueryRow("SELECT MIN(zoom_level), MAX(zoom_level) FROM tiles").Scan(&minZoom, &maxZoom)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("reading MBTiles zoom range from %s failed: %w", path, err)
+		}
+		if meta.MinZoom < 0 {
+			meta.MinZoom = int(minZoom.Int64)
+		}
+		if meta.MaxZoom < 0 {
+			meta.MaxZoom = int(maxZoom.Int64)
+		}
+	}
+
+	return &MBTilesSource{Path: path, Metadata: meta, db: db}, nil
+}
+
+// readMBTilesMetadata loads the name/value rows of the metadata table
+func readMBTilesMetadata(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query("SELECT name, value FROM metadata")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, rows.Err()
+}
+
+// Name implements TileSource
+func (s *MBTilesSource) Name() string {
+	return "mbtiles-" + s.Metadata.Name
+}
+
+// MinZoom implements TileSource
+func (s *MBTilesSource) MinZoom() int {
+	return s.Metadata.MinZoom
+}
+
+// MaxZoom implements TileSource
+func (s *MBTilesSource) MaxZoom() int {
+	return s.Metadata.MaxZoom
+}
+
+// FetchTile implements TileSource
+func (s *MBTilesSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	if err := checkTileKey(s, key); err != nil {
+		return nil, err
+	}
+
+	tmsY := (1 << key.Zoom) - 1 - key.Y
+	var data []byte
+	err := s.db.QueryRowContext(ctx,
+		"SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		key.Zoom, key.X, tmsY).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("tile %d/%d/%d not in %s: %w", key.Zoom, key.X, key.Y, s.Path, ErrTileNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tile %d/%d/%d from %s failed: %w", key.Zoom, key.X, key.Y, s.Path, err)
+	}
+
+	return data, nil
+}
+
+// Close closes the underlying database
+func (s *MBTilesSource) Close() error {
+	return s.db.Close()
+}
+
+// MBTilesWriter stores seeded tiles in an MBTiles file. Existing tiles are
+// kept, so an interrupted seed can resume into the same file.
+type MBTilesWriter struct {
+	Path string
+
+	db *sql.DB
+}
+
+var _ SeedSink = (*MBTilesWriter)(nil)
+
+// CreateMBTiles opens an MBTiles file for writing, creating it if needed, and
+// records metadata describing the seeded area
+func CreateMBTiles(path string, source TileSource, area SeedArea, format string) (*MBTilesWriter, error) {
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		return nil, fmt.Errorf("creating MBTiles %s failed: %w", path, err)
+	}
+
+	schema := []string{
+		"PRAGMA journal_mode=WAL",
+		"CREATE TABLE IF NOT EXISTS metadata (name TEXT PRIMARY KEY, value TEXT)",
+		"CREATE TABLE IF NOT EXISTS tiles (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_data BLOB)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS tile_index ON tiles (zoom_level, tile_column, tile_row)",
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("creating MBTiles schema in %s failed: %w", path, err)
+		}
+	}
+
+	b := area.Bounds
+	centerLon := (b.MinLon + b.MaxLon) / 2
+	if b.MinLon > b.MaxLon {
+		centerLon = NormalizeLon(centerLon + 180)
+	}
+	meta := map[string]string{
+		"name":    source.Name(),
+		"format":  format,
+		"type":    "baselayer",
+		"minzoom": fmt.Sprint(area.MinZoom),
+		"maxzoom": fmt.Sprint(area.MaxZoom),
+		"bounds":  fmt.Sprintf("%g,%g,%g,%g", b.MinLon, b.MinLat, b.MaxLon, b.MaxLat),
+		"center":  fmt.Sprintf("%g,%g,%d", centerLon, (b.MinLat+b.MaxLat)/2, area.MinZoom),
+	}
+	for name, value := range meta {
+		if _, err := db.Exec("INSERT OR REPLACE INTO metadata (name, value) VALUES (?, ?)", name, value); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("writing MBTiles metadata to %s failed: %w", path, err)
+		}
+	}
+
+	return &MBTilesWriter{Path: path, db: db}, nil
+}
+
+// HasTile implements SeedSink
+func (w *MBTilesWriter) HasTile(key TileKey) bool {
+	tmsY := (1 << key.Zoom) - 1 - key.Y
+	var one int
+	err := w.db.QueryRow("SELECT 1 FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		key.Zoom, key.X, tmsY).Scan(&one)
+	return err == nil
+}
+
+// PutTile implements SeedSink
+func (w *MBTilesWriter) PutTile(key TileKey, tile *CachedTile) error {
+	tmsY := (1 << key.Zoom) - 1 - key.Y
+	_, err := w.db.Exec("INSERT OR REPLACE INTO tiles (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)",
+		key.Zoom, key.X, tmsY, tile.Data)
+	if err != nil {
+		return fmt.Errorf("writing tile %d/%d/%d to %s failed: %w", key.Zoom, key.X, key.Y, w.Path, err)
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (w *MBTilesWriter) Close() error {
+	return w.db.Close()
+}
@@ -0,0 +1,146 @@
+//go:build !js
+
+package tilemap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// tileData returns test tile contents naming the tile's position
+func tileData(key TileKey) string {
+	return fmt.Sprintf("%d/%d/%d", key.Zoom, key.X, key.Y)
+}
+
+// writeMBTiles seeds an MBTiles file with one tile per key, holding the key's
+// position so reads can be checked
+func writeMBTiles(t *testing.T, path string, area SeedArea, keys ...TileKey) {
+	t.Helper()
+	w, err := CreateMBTiles(path, NewXYZSource("county", "https://tiles.example.com/{z}/{x}/{y}.png"), area, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, key := range keys {
+		if w.HasTile(key) {
+			t.Errorf("new file already has tile %v", key)
+		}
+		if err := w.PutTile(key, &CachedTile{Data: []byte(tileData(key))}); err != nil {
+			t.Fatal(err)
+		}
+		if !w.HasTile(key) {
+			t.Errorf("tile %v missing after PutTile", key)
+		}
+	}
+}
+
+func TestMBTilesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "county.mbtiles")
+	area := SeedArea{Bounds: BBox{MinLon: -105.3, MinLat: 39.9, MaxLon: -105.1, MaxLat: 40.1}, MinZoom: 1, MaxZoom: 3}
+	keys := []TileKey{{Zoom: 1}, {Zoom: 2, X: 1, Y: 0}, {Zoom: 3, X: 1, Y: 3}}
+	writeMBTiles(t, path, area, keys...)
+
+	// Rows are stored in TMS order, counting up from the south
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row int
+	if err := db.QueryRow("SELECT tile_row FROM tiles WHERE zoom_level = 2 AND tile_column = 1").Scan(&row); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if row != 3 {
+		t.Errorf("tile 2/1/0 stored in row %d; want 3", row)
+	}
+
+	src, err := OpenMBTiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	meta := src.Metadata
+	if meta.Name != "county" || meta.Format != "png" || src.MinZoom() != 1 || src.MaxZoom() != 3 {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if meta.Bounds == nil || *meta.Bounds != area.Bounds {
+		t.Errorf("bounds = %v; want %v", meta.Bounds, area.Bounds)
+	}
+	if !meta.HasCenter || math.Abs(meta.CenterLon - -105.2) > 1e-9 || math.Abs(meta.CenterLat-40) > 1e-9 || meta.CenterZoom != 1 {
+		t.Errorf("center = %g, %g z%g; want -105.2, 40 z1", meta.CenterLon, meta.CenterLat, meta.CenterZoom)
+	}
+
+	for _, key := range keys {
+		data, err := src.FetchTile(context.Background(), key)
+		if err != nil {
+			t.Errorf("FetchTile(%v): %v", key, err)
+			continue
+		}
+		if string(data) != tileData(key) {
+			t.Errorf("FetchTile(%v) = %q", key, data)
+		}
+	}
+
+	// A gap in the tiles is reported as not found, unlike a bad request
+	if _, err := src.FetchTile(context.Background(), TileKey{Zoom: 3, X: 2, Y: 2}); !errors.Is(err, ErrTileNotFound) {
+		t.Errorf("missing tile gives %v; want ErrTileNotFound", err)
+	}
+	if _, err := src.FetchTile(context.Background(), TileKey{Zoom: 4}); err == nil || errors.Is(err, ErrTileNotFound) {
+		t.Errorf("tile past the zoom range gives %v", err)
+	}
+}
+
+func TestMBTilesResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "county.mbtiles")
+	area := SeedArea{Bounds: BBox{MinLon: -180, MinLat: -85, MaxLon: 180, MaxLat: 85}, MinZoom: 0, MaxZoom: 1}
+	writeMBTiles(t, path, area, TileKey{Zoom: 0})
+
+	// Opening the file again keeps its tiles
+	w, err := CreateMBTiles(path, NewXYZSource("county", "https://tiles.example.com/{z}/{x}/{y}.png"), area, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if !w.HasTile(TileKey{Zoom: 0}) {
+		t.Error("tile lost when the file was opened again")
+	}
+	if err := w.PutTile(TileKey{Zoom: 0}, &CachedTile{Data: []byte("new")}); err != nil {
+		t.Errorf("replacing a tile failed: %v", err)
+	}
+}
+
+func TestOpenMBTilesMissingZooms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "county.mbtiles")
+	area := SeedArea{Bounds: BBox{MinLon: -180, MinLat: -85, MaxLon: 180, MaxLat: 85}, MinZoom: 0, MaxZoom: 5}
+	writeMBTiles(t, path, area, TileKey{Zoom: 2, X: 1, Y: 1}, TileKey{Zoom: 5, X: 3, Y: 4})
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM metadata WHERE name IN ('minzoom', 'maxzoom')"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	src, err := OpenMBTiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if src.MinZoom() != 2 || src.MaxZoom() != 5 {
+		t.Errorf("zoom range = %d-%d; want 2-5 from the tiles", src.MinZoom(), src.MaxZoom())
+	}
+}
+
+func TestOpenMBTilesNotMBTiles(t *testing.T) {
+	if _, err := OpenMBTiles(filepath.Join(t.TempDir(), "missing.mbtiles")); err == nil {
+		t.Error("opened a missing file")
+	}
+}
@@ -0,0 +1,89 @@
+package tilemap
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseMBTilesMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		rows map[string]string
+		want MBTilesMetadata
+	}{
+		{
+			name: "Full",
+			path: "/maps/county.mbtiles",
+			rows: map[string]string{
+				"name":        "County",
+				"format":      "JPG",
+				"attribution": `<a href="https://gis.example.com/">County GIS</a>`,
+				"minzoom":     "2",
+				"maxzoom":     " 14 ",
+				"bounds":      "-105.3, 39.9, -105.1, 40.1",
+				"center":      "-105.2,40,12",
+			},
+			want: MBTilesMetadata{
+				Name: "County", Format: "jpg", Attribution: `<a href="https://gis.example.com/">County GIS</a>`,
+				MinZoom: 2, MaxZoom: 14,
+				Bounds:    &BBox{MinLon: -105.3, MinLat: 39.9, MaxLon: -105.1, MaxLat: 40.1},
+				HasCenter: true, CenterLon: -105.2, CenterLat: 40, CenterZoom: 12,
+			},
+		},
+		{
+			name: "Defaults",
+			path: "/maps/county.mbtiles",
+			rows: map[string]string{},
+			want: MBTilesMetadata{Name: "county", Format: "png", MinZoom: -1, MaxZoom: -1},
+		},
+		{
+			name: "Invalid values ignored",
+			path: "county.mbtiles",
+			rows: map[string]string{
+				"minzoom": "low",
+				"maxzoom": "",
+				"bounds":  "-105.3,39.9,-105.1",
+				"center":  "a,b,c",
+			},
+			want: MBTilesMetadata{Name: "county", Format: "png", MinZoom: -1, MaxZoom: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMBTilesMetadata(tt.path, tt.rows)
+			tt.want.Raw = tt.rows
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMBTilesMetadata() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMBTilesInitialView(t *testing.T) {
+	start := Viewport{CenterLat: 10, CenterLon: 20, Zoom: 3, ScreenWidth: 800, ScreenHeight: 600}
+	bounds := BBox{MinLon: -105.3, MinLat: 39.9, MaxLon: -105.1, MaxLat: 40.1}
+
+	// The center wins over the bounds
+	meta := MBTilesMetadata{Bounds: &bounds, HasCenter: true, CenterLat: 40, CenterLon: -105.2, CenterZoom: 12}
+	if v := meta.InitialView(start); v.CenterLat != 40 || v.CenterLon != -105.2 || v.Zoom != 12 {
+		t.Errorf("view with a center = %g, %g z%g; want 40, -105.2 z12", v.CenterLat, v.CenterLon, v.Zoom)
+	}
+
+	// Without one the view fits the bounds
+	meta = MBTilesMetadata{Bounds: &bounds}
+	v := meta.InitialView(start)
+	if want := start.FitBounds(bounds, 0); v != want {
+		t.Errorf("view with bounds = %+v; want %+v", v, want)
+	}
+	if math.Abs(v.CenterLat-40) > 0.01 || math.Abs(v.CenterLon - -105.2) > 1e-9 || v.Zoom < 11 {
+		t.Errorf("view with bounds = %g, %g z%g; want around 40, -105.2", v.CenterLat, v.CenterLon, v.Zoom)
+	}
+
+	// With neither the view is left alone
+	if v := (MBTilesMetadata{}).InitialView(start); v != start {
+		t.Errorf("view without a center or bounds = %+v; want %+v", v, start)
+	}
+}
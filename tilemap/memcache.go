@@ -0,0 +1,208 @@
+package tilemap
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// DefaultMemoryCacheTiles is the default maximum number of decoded tiles kept in memory
+	DefaultMemoryCacheTiles = 512
+	// DefaultMemoryCacheBytes is the default maximum size of decoded tiles kept in memory
+	DefaultMemoryCacheBytes = 256 << 20
+)
+
+// CacheStats reports memory tile cache usage
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Tiles     int
+	Bytes     int64
+}
+
+// memEntry is a decoded tile held in the memory cache
+type memEntry struct {
+	key   TileKey
+	img   *ebiten.Image
+	bytes int64
+}
+
+// memoryCache is an LRU cache of decoded tile images with a tile and byte budget.
+// Evicted images are disposed to free GPU memory. Since ebiten panics when drawing
+// a disposed image, disposal only happens in prune, which must be called from the
+// draw goroutine.
+type memoryCache struct {
+	mu       sync.Mutex
+	maxTiles int   // 0 means unlimited
+	maxBytes int64 // 0 means unlimited
+
+	lru     *list.List // Front is most recently used
+	entries map[TileKey]*list.Element
+	bytes   int64
+
+	// Images replaced by newer versions, waiting to be disposed by prune
+	retired []*ebiten.Image
+
+	hits, misses, evictions uint64
+}
+
+func newMemoryCache(maxTiles int, maxBytes int64) *memoryCache {
+	return &memoryCache{
+		maxTiles: maxTiles,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[TileKey]*list.Element),
+	}
+}
+
+// get returns a cached tile and marks it as recently used. The image is nil
+// for tiles the source doesn't have.
+func (c *memoryCache) get(key TileKey) (*ebiten.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*memEntry).img, true
+}
+
+// peek returns a cached tile and marks it as recently used without
+// counting towards the hit and miss statistics
+func (c *memoryCache) peek(key TileKey) (*ebiten.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*memEntry).img, true
+}
+
+// put adds or replaces a tile. A nil image records that the source has no
+// such tile, so it isn't fetched again. Eviction is deferred to prune.
+func (c *memoryCache) put(key TileKey, img *ebiten.Image) {
+	var size int64
+	if img != nil {
+		bounds := img.Bounds()
+		size = int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*memEntry)
+		if entry.img != img && entry.img != nil {
+			c.retired = append(c.retired, entry.img)
+		}
+		c.bytes += size - entry.bytes
+		entry.img = img
+		entry.bytes = size
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&memEntry{key: key, img: img, bytes: size})
+	c.bytes += size
+}
+
+// clear removes every tile. The images are disposed by the next prune.
+func (c *memoryCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		if img := elem.Value.(*memEntry).img; img != nil {
+			c.retired = append(c.retired, img)
+		}
+	}
+	c.lru.Init()
+	c.entries = make(map[TileKey]*list.Element)
+	c.bytes = 0
+}
+
+// removeLayer removes every tile of a layer. The images are disposed by the
+// next prune.
+func (c *memoryCache) removeLayer(layer int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*memEntry)
+		if entry.key.Layer == layer {
+			if entry.img != nil {
+				c.retired = append(c.retired, entry.img)
+			}
+			c.lru.Remove(elem)
+			delete(c.entries, entry.key)
+			c.bytes -= entry.bytes
+		}
+		elem = next
+	}
+}
+
+// setLimits changes the cache budget
+func (c *memoryCache) setLimits(maxTiles int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxTiles = maxTiles
+	c.maxBytes = maxBytes
+}
+
+// overBudget reports whether the cache exceeds its limits. c.mu must be held.
+func (c *memoryCache) overBudget() bool {
+	return (c.maxTiles > 0 && c.lru.Len() > c.maxTiles) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+// prune disposes retired images and evicts least recently used tiles until the
+// cache fits its budget. Tiles for which pinned returns true are never evicted.
+func (c *memoryCache) prune(pinned func(TileKey) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, img := range c.retired {
+		img.Dispose()
+	}
+	c.retired = c.retired[:0]
+
+	elem := c.lru.Back()
+	for elem != nil && c.overBudget() {
+		prev := elem.Prev()
+		entry := elem.Value.(*memEntry)
+		if !pinned(entry.key) {
+			if entry.img != nil {
+				entry.img.Dispose()
+			}
+			c.lru.Remove(elem)
+			delete(c.entries, entry.key)
+			c.bytes -= entry.bytes
+			c.evictions++
+		}
+		elem = prev
+	}
+}
+
+// stats returns a snapshot of the cache counters
+func (c *memoryCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Tiles:     c.lru.Len(),
+		Bytes:     c.bytes,
+	}
+}
@@ -0,0 +1,216 @@
+package tilemap
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// disposed reports whether an image has been disposed, which ebiten only
+// reveals by panicking
+func disposed(img *ebiten.Image) (d bool) {
+	defer func() {
+		if recover() != nil {
+			d = true
+		}
+	}()
+	img.Bounds()
+	return false
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	key := func(x int) TileKey { return TileKey{Zoom: 4, X: x} }
+	none := func(TileKey) bool { return false }
+
+	tests := []struct {
+		name     string
+		maxTiles int
+		maxBytes int64
+		put      []int        // Tiles added in order, each 16x16
+		touch    []int        // Tiles then read
+		pinned   map[int]bool // Tiles that prune must keep
+		want     []int        // Tiles left after prune
+	}{
+		{
+			name:     "Under budget",
+			maxTiles: 3,
+			put:      []int{0, 1, 2},
+			want:     []int{0, 1, 2},
+		},
+		{
+			name:     "Least recently added evicted",
+			maxTiles: 2,
+			put:      []int{0, 1, 2},
+			want:     []int{1, 2},
+		},
+		{
+			name:     "Read moves to front",
+			maxTiles: 2,
+			put:      []int{0, 1, 2},
+			touch:    []int{0},
+			want:     []int{0, 2},
+		},
+		{
+			name:     "Byte budget",
+			maxBytes: 2 * 16 * 16 * 4,
+			put:      []int{0, 1, 2, 3},
+			want:     []int{2, 3},
+		},
+		{
+			name:     "Pinned tiles kept",
+			maxTiles: 2,
+			put:      []int{0, 1, 2, 3},
+			pinned:   map[int]bool{0: true},
+			want:     []int{0, 3},
+		},
+		{
+			name:     "All pinned stays over budget",
+			maxTiles: 1,
+			put:      []int{0, 1, 2},
+			pinned:   map[int]bool{0: true, 1: true, 2: true},
+			want:     []int{0, 1, 2},
+		},
+		{
+			name: "Unlimited",
+			put:  []int{0, 1, 2, 3, 4},
+			want: []int{0, 1, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newMemoryCache(tt.maxTiles, tt.maxBytes)
+			imgs := map[int]*ebiten.Image{}
+			for _, x := range tt.put {
+				imgs[x] = ebiten.NewImage(16, 16)
+				c.put(key(x), imgs[x])
+			}
+			for _, x := range tt.touch {
+				c.get(key(x))
+			}
+			pinned := none
+			if tt.pinned != nil {
+				pinned = func(k TileKey) bool { return tt.pinned[k.X] }
+			}
+			c.prune(pinned)
+
+			want := map[int]bool{}
+			for _, x := range tt.want {
+				want[x] = true
+			}
+			for _, x := range tt.put {
+				_, ok := c.peek(key(x))
+				if ok != want[x] {
+					t.Errorf("tile %d cached = %v; want %v", x, ok, want[x])
+				}
+				if disposed(imgs[x]) == want[x] {
+					t.Errorf("tile %d disposed = %v; want %v", x, !want[x], !want[x])
+				}
+			}
+			stats := c.stats()
+			if stats.Tiles != len(tt.want) || stats.Bytes != int64(len(tt.want))*16*16*4 {
+				t.Errorf("stats = %+v; want %d tiles", stats, len(tt.want))
+			}
+			if int(stats.Evictions) != len(tt.put)-len(tt.want) {
+				t.Errorf("evictions = %d; want %d", stats.Evictions, len(tt.put)-len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMemoryCacheReplace(t *testing.T) {
+	c := newMemoryCache(0, 0)
+	key := TileKey{Zoom: 1}
+	old, replacement := ebiten.NewImage(16, 16), ebiten.NewImage(32, 32)
+	c.put(key, old)
+	c.put(key, replacement)
+
+	// The replaced image stays usable until the next prune, as it may be
+	// drawn this frame
+	if disposed(old) {
+		t.Error("replaced image disposed before prune")
+	}
+	if stats := c.stats(); stats.Tiles != 1 || stats.Bytes != 32*32*4 {
+		t.Errorf("stats = %+v; want 1 tile of 32x32", stats)
+	}
+	c.prune(func(TileKey) bool { return false })
+	if !disposed(old) || disposed(replacement) {
+		t.Errorf("after prune old disposed = %v, replacement disposed = %v", disposed(old), disposed(replacement))
+	}
+	if img, ok := c.get(key); !ok || img != replacement {
+		t.Error("get doesn't return the replacement")
+	}
+
+	c.clear()
+	if _, ok := c.get(key); ok {
+		t.Error("tile cached after clear")
+	}
+	if disposed(replacement) {
+		t.Error("cleared image disposed before prune")
+	}
+	c.prune(func(TileKey) bool { return false })
+	if !disposed(replacement) {
+		t.Error("cleared image not disposed by prune")
+	}
+	if stats := c.stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Tiles != 0 || stats.Bytes != 0 {
+		t.Errorf("stats = %+v; want 1 hit, 1 miss and no tiles", stats)
+	}
+}
+
+func TestMemoryCacheMissingTile(t *testing.T) {
+	c := newMemoryCache(1, 0)
+	missing, present := TileKey{Zoom: 3}, TileKey{Zoom: 3, X: 1}
+	c.put(missing, nil)
+	img := ebiten.NewImage(16, 16)
+	c.put(present, img)
+
+	if got, ok := c.get(missing); !ok || got != nil {
+		t.Errorf("get missing tile = %v, %v; want nil, true", got, ok)
+	}
+	if stats := c.stats(); stats.Tiles != 2 || stats.Bytes != 16*16*4 {
+		t.Errorf("stats = %+v; want 2 tiles of which one is empty", stats)
+	}
+
+	// Empty entries count towards the tile limit like any other
+	c.prune(func(TileKey) bool { return false })
+	if _, ok := c.peek(present); ok {
+		t.Error("least recently used tile not evicted")
+	}
+	if _, ok := c.peek(missing); !ok {
+		t.Error("missing tile forgotten")
+	}
+
+	c.clear()
+	c.prune(func(TileKey) bool { return false })
+	if _, ok := c.peek(missing); ok {
+		t.Error("missing tile cached after clear")
+	}
+}
+
+func TestMemoryCacheRemoveLayer(t *testing.T) {
+	c := newMemoryCache(0, 0)
+	kept, removed := TileKey{Layer: 0, Zoom: 2}, TileKey{Layer: 1, Zoom: 2}
+	keptImg, removedImg := ebiten.NewImage(16, 16), ebiten.NewImage(16, 16)
+	c.put(kept, keptImg)
+	c.put(removed, removedImg)
+	c.put(TileKey{Layer: 1, Zoom: 3}, nil)
+
+	c.removeLayer(1)
+	if _, ok := c.peek(removed); ok {
+		t.Error("tile of removed layer still cached")
+	}
+	if _, ok := c.peek(TileKey{Layer: 1, Zoom: 3}); ok {
+		t.Error("missing tile of removed layer still cached")
+	}
+	if img, ok := c.peek(kept); !ok || img != keptImg {
+		t.Error("tile of other layer removed")
+	}
+	if stats := c.stats(); stats.Tiles != 1 || stats.Bytes != 16*16*4 {
+		t.Errorf("stats = %+v; want 1 tile", stats)
+	}
+
+	c.prune(func(TileKey) bool { return false })
+	if !disposed(removedImg) || disposed(keptImg) {
+		t.Errorf("after prune removed disposed = %v, kept disposed = %v", disposed(removedImg), disposed(keptImg))
+	}
+}
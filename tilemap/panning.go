@@ -21,6 +21,7 @@ const PanSpeed = 50
 
 // Pan moves the map center in the specified direction by a fixed number of pixels
 func (tm *TileMap) Pan(dir PanDirection) {
+	tm.StopKinetic()
 	switch dir {
 	case PanLeft:
 		tm.PanBy(PanSpeed, 0)
@@ -36,29 +37,30 @@ func (tm *TileMap) Pan(dir PanDirection) {
 // PanBy moves the map by pixel offsets
 // dx,dy are in screen pixels, positive dx moves map west (view east), positive dy moves map south (view north)
 func (tm *TileMap) PanBy(dx, dy float64) {
-	// Convert pixel offsets to tile coordinates at current zoom level
-	pixelsToTiles := 1.0 / TileSize
+	// Convert pixel offsets to tile coordinates at current zoom level, undoing map rotation
+	zoom := tm.TileZoom()
+	pixelsToTiles := 1.0 / (TileSize * tm.TileScale())
+	dx, dy = tm.screenToMapOffset(dx, dy)
 	tileDX := dx * pixelsToTiles
 	tileDY := dy * pixelsToTiles
 
 	// Get current center in tile coordinates
-	centerTileX, centerTileY := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, tm.Zoom)
+	centerTileX, centerTileY := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, zoom)
 
 	// Move in tile space
 	newCenterTileX := centerTileX - tileDX
 	newCenterTileY := centerTileY - tileDY
 
 	// Get max tile coordinate for current zoom
-	maxTileCoord := float64(uint(1) << uint(tm.Zoom))
+	maxTileCoord := float64(uint(1) << uint(zoom))
 
-	// Clamp X and Y to valid tile ranges (no wrapping)
-	newCenterTileX = math.Max(0, math.Min(maxTileCoord, newCenterTileX))
+	// Clamp Y to the valid tile range; X wraps around the antimeridian
 	newCenterTileY = math.Max(0, math.Min(maxTileCoord, newCenterTileY))
 
 	// Convert tile coordinates back to lat/lon
-	lon := (newCenterTileX/maxTileCoord)*360.0 - 180.0
-	lat := math.Atan(math.Sinh(math.Pi*(1-2*newCenterTileY/maxTileCoord))) * 180.0 / math.Pi
+	lat, lon := proj.TileCoordsToLatLon(newCenterTileX, newCenterTileY, zoom)
 
-	tm.CenterLon = lon
+	tm.CenterLon = NormalizeLon(lon)
 	tm.CenterLat = lat
+	tm.Viewport = tm.Viewport.Constrain()
 }
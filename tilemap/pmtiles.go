@@ -0,0 +1,152 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/OpticalFlyer/goliath/pmtiles"
+)
+
+// PMTilesSource reads tiles from a PMTiles v3 archive, either a local file or
+// a URL fetched with HTTP range requests
+type PMTilesSource struct {
+	Location string
+	Reader   *pmtiles.Reader
+	Metadata map[string]any
+
+	name string
+}
+
+var (
+	_ VectorTileSource = (*PMTilesSource)(nil)
+	_ AttributedSource = (*PMTilesSource)(nil)
+	_ BoundedSource    = (*PMTilesSource)(nil)
+)
+
+// OpenPMTiles opens a PMTiles archive from a path or an http(s) URL
+func OpenPMTiles(location string) (*PMTilesSource, error) {
+	var reader *pmtiles.Reader
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		reader, err = pmtiles.OpenURL(location)
+	} else {
+		reader, err = pmtiles.Open(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := reader.Metadata()
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("reading PMTiles metadata from %s failed: %w", location, err)
+	}
+
+	name, _ := meta["name"].(string)
+	if name == "" {
+		base := path.Base(strings.ReplaceAll(location, "\\", "/"))
+		name = strings.TrimSuffix(base, path.Ext(base))
+	}
+
+	return &PMTilesSource{Location: location, Reader: reader, Metadata: meta, name: name}, nil
+}
+
+// Name implements TileSource
+func (s *PMTilesSource) Name() string {
+	return "pmtiles-" + s.name
+}
+
+// MinZoom implements TileSource
+func (s *PMTilesSource) MinZoom() int {
+	return int(s.Reader.Header.MinZoom)
+}
+
+// MaxZoom implements TileSource
+func (s *PMTilesSource) MaxZoom() int {
+	return int(s.Reader.Header.MaxZoom)
+}
+
+// Attribution implements AttributedSource, using the archive's metadata
+func (s *PMTilesSource) Attribution() Attribution {
+	attribution, _ := s.Metadata["attribution"].(string)
+	return ParseAttribution(attribution)
+}
+
+// TileType returns the format of the archive's tiles
+func (s *PMTilesSource) TileType() pmtiles.TileType {
+	return s.Reader.Header.TileType
+}
+
+// IsVector implements VectorTileSource; it reports whether the archive holds Mapbox Vector Tiles
+func (s *PMTilesSource) IsVector() bool {
+	return s.Reader.Header.TileType == pmtiles.TileTypeMVT
+}
+
+// FetchTile implements TileSource. Only raster archives can be drawn directly;
+// vector archives are read with FetchVectorTile and rendered by the map.
+func (s *PMTilesSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	if s.IsVector() {
+		return nil, fmt.Errorf("%s holds vector tiles, which need a vector renderer", s.Location)
+	}
+	return s.fetch(ctx, key)
+}
+
+// FetchVectorTile implements VectorTileSource, returning the uncompressed MVT protobuf for a tile
+func (s *PMTilesSource) FetchVectorTile(ctx context.Context, key TileKey) ([]byte, error) {
+	if !s.IsVector() {
+		return nil, fmt.Errorf("%s holds %s tiles, not vector tiles", s.Location, s.TileType())
+	}
+	return s.fetch(ctx, key)
+}
+
+// fetch reads a tile's data from the archive
+func (s *PMTilesSource) fetch(ctx context.Context, key TileKey) ([]byte, error) {
+	if err := checkTileKey(s, key); err != nil {
+		return nil, err
+	}
+
+	data, err := s.Reader.Tile(ctx, uint8(key.Zoom), uint32(key.X), uint32(key.Y))
+	if errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+	if errors.Is(err, pmtiles.ErrTileNotFound) {
+		return nil, fmt.Errorf("tile %d/%d/%d not in %s: %w", key.Zoom, key.X, key.Y, s.Location, ErrTileNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tile %d/%d/%d from %s failed: %w", key.Zoom, key.X, key.Y, s.Location, err)
+	}
+	return data, nil
+}
+
+// TileBounds implements BoundedSource, using the archive's header
+func (s *PMTilesSource) TileBounds() *BBox {
+	h := s.Reader.Header
+	if h.MinLon >= h.MaxLon || h.MinLat >= h.MaxLat {
+		return nil
+	}
+	return &BBox{MinLat: h.MinLat, MinLon: h.MinLon, MaxLat: h.MaxLat, MaxLon: h.MaxLon}
+}
+
+// InitialView returns v moved to the archive's default center and zoom, or
+// fitted to its bounds if it has no center
+func (s *PMTilesSource) InitialView(v Viewport) Viewport {
+	h := s.Reader.Header
+	if h.CenterLat != 0 || h.CenterLon != 0 || h.CenterZoom != 0 {
+		v.CenterLat = h.CenterLat
+		v.CenterLon = h.CenterLon
+		v.Zoom = float64(h.CenterZoom)
+		return v.Constrain()
+	}
+	if h.MinLon < h.MaxLon && h.MinLat < h.MaxLat {
+		return v.FitBounds(BBox{MinLat: h.MinLat, MinLon: h.MinLon, MaxLat: h.MaxLat, MaxLon: h.MaxLon}, 0)
+	}
+	return v
+}
+
+// Close closes the archive
+func (s *PMTilesSource) Close() error {
+	return s.Reader.Close()
+}
@@ -0,0 +1,43 @@
+package tilemap
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SetBearing sets the map rotation in degrees clockwise from north
+func (tm *TileMap) SetBearing(bearing float64) {
+	bearing = math.Mod(bearing, 360)
+	if bearing < 0 {
+		bearing += 360
+	}
+	tm.Bearing = bearing
+
+	// The rotated view covers a different area, so keep it inside any max bounds
+	tm.Viewport = tm.Viewport.Constrain()
+}
+
+// RotateBy rotates the map by the given number of degrees. Positive values
+// turn the map content clockwise on screen.
+func (tm *TileMap) RotateBy(degrees float64) {
+	tm.SetBearing(tm.Bearing - degrees)
+}
+
+// ResetNorth rotates the map back to north-up
+func (tm *TileMap) ResetNorth() {
+	tm.SetBearing(0)
+}
+
+// getRotationBuffer returns an offscreen image of the given size for rotated drawing
+func (tm *TileMap) getRotationBuffer(width, height int) *ebiten.Image {
+	if tm.rotationBuffer != nil {
+		bounds := tm.rotationBuffer.Bounds()
+		if bounds.Dx() == width && bounds.Dy() == height {
+			return tm.rotationBuffer
+		}
+		tm.rotationBuffer.Dispose()
+	}
+	tm.rotationBuffer = ebiten.NewImage(width, height)
+	return tm.rotationBuffer
+}
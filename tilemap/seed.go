@@ -0,0 +1,268 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSeedRate is the default download rate in tiles per second. Public
+	// tile servers such as OpenStreetMap's discourage bulk downloads, so keep it low.
+	DefaultSeedRate = 4.0
+
+	// DefaultSeedWorkers is the default number of concurrent seed downloads
+	DefaultSeedWorkers = 2
+
+	// EstimatedTileBytes is the average tile size used for download estimates
+	EstimatedTileBytes = 20 << 10
+
+	// seedProgressInterval limits how often progress is reported
+	seedProgressInterval = 250 * time.Millisecond
+)
+
+// SeedArea is a region and zoom range to download for offline use
+type SeedArea struct {
+	Bounds  BBox
+	MinZoom int
+	MaxZoom int
+}
+
+// Validate checks that the area's zoom range is not empty or negative
+func (a SeedArea) Validate() error {
+	if a.MinZoom > a.MaxZoom {
+		return fmt.Errorf("invalid zoom range %d-%d: minimum is above maximum", a.MinZoom, a.MaxZoom)
+	}
+	if a.MinZoom < 0 {
+		return fmt.Errorf("invalid zoom range %d-%d: zoom levels start at 0", a.MinZoom, a.MaxZoom)
+	}
+	return nil
+}
+
+// TileRanges returns the tiles covering the area at each zoom level, or nil
+// if the zoom range is empty
+func (a SeedArea) TileRanges() []TileRange {
+	if a.MinZoom > a.MaxZoom {
+		return nil
+	}
+	ranges := make([]TileRange, 0, a.MaxZoom-a.MinZoom+1)
+	for z := a.MinZoom; z <= a.MaxZoom; z++ {
+		ranges = append(ranges, a.Bounds.TileRange(z))
+	}
+	return ranges
+}
+
+// TileCount returns the number of tiles in the area
+func (a SeedArea) TileCount() int {
+	count := 0
+	for _, r := range a.TileRanges() {
+		count += (r.MaxX - r.MinX + 1) * (r.MaxY - r.MinY + 1)
+	}
+	return count
+}
+
+// EstimateBytes returns the approximate download size of the area
+func (a SeedArea) EstimateBytes() int64 {
+	return int64(a.TileCount()) * EstimatedTileBytes
+}
+
+// forEachTile calls fn for every tile in the area, lowest zoom first, until fn returns false
+func (a SeedArea) forEachTile(fn func(TileKey) bool) {
+	for _, r := range a.TileRanges() {
+		for y := r.MinY; y <= r.MaxY; y++ {
+			for x := r.MinX; x <= r.MaxX; x++ {
+				if !fn(TileKey{Zoom: r.Zoom, X: WrapTileX(x, r.Zoom), Y: y}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SeedSink stores seeded tiles. HasTile lets an interrupted seed resume
+// without downloading tiles again.
+type SeedSink interface {
+	HasTile(key TileKey) bool
+	PutTile(key TileKey, tile *CachedTile) error
+}
+
+// diskCacheSink stores seeded tiles in a DiskCache under a source's name
+type diskCacheSink struct {
+	cache  *DiskCache
+	source string
+}
+
+// NewDiskCacheSink returns a sink that seeds a disk cache with tiles from a source
+func NewDiskCacheSink(dc *DiskCache, source TileSource) SeedSink {
+	return &diskCacheSink{cache: dc, source: source.Name()}
+}
+
+func (s *diskCacheSink) HasTile(key TileKey) bool {
+	return s.cache.Has(s.source, key)
+}
+
+func (s *diskCacheSink) PutTile(key TileKey, tile *CachedTile) error {
+	return s.cache.Put(s.source, key, tile)
+}
+
+// SeedProgress reports how far a seed has got
+type SeedProgress struct {
+	Total   int   // Tiles in the area
+	Done    int   // Tiles handled so far, including skipped and failed ones
+	Skipped int   // Tiles already in the sink
+	Failed  int   // Tiles that could not be downloaded or stored
+	Bytes   int64 // Bytes downloaded
+	Zoom    int   // Zoom level being worked on
+	Elapsed time.Duration
+}
+
+// Remaining estimates the time left, based on the rate so far
+func (p SeedProgress) Remaining() time.Duration {
+	downloaded := p.Done - p.Skipped
+	if downloaded <= 0 || p.Done >= p.Total {
+		return 0
+	}
+	perTile := p.Elapsed / time.Duration(downloaded)
+	return perTile * time.Duration(p.Total-p.Done)
+}
+
+// Seeder downloads every tile in an area from a source into a sink
+type Seeder struct {
+	Source  TileSource
+	Sink    SeedSink
+	Rate    float64 // Tiles per second; 0 means unlimited
+	Workers int
+
+	// OnProgress, if set, is called periodically from the seeding goroutines
+	OnProgress func(SeedProgress)
+}
+
+// NewSeeder creates a seeder with the default rate and worker count
+func NewSeeder(source TileSource, sink SeedSink) *Seeder {
+	return &Seeder{
+		Source:  source,
+		Sink:    sink,
+		Rate:    DefaultSeedRate,
+		Workers: DefaultSeedWorkers,
+	}
+}
+
+// Run downloads the area's tiles, skipping tiles the sink already has. Failed
+// tiles are counted and skipped; running again retries them. It returns when
+// all tiles are handled or ctx is canceled.
+func (s *Seeder) Run(ctx context.Context, area SeedArea) (SeedProgress, error) {
+	if err := area.Validate(); err != nil {
+		return SeedProgress{}, err
+	}
+	if area.MinZoom < s.Source.MinZoom() || area.MaxZoom > s.Source.MaxZoom() {
+		return SeedProgress{}, fmt.Errorf("zoom range %d-%d is outside %s's range %d-%d",
+			area.MinZoom, area.MaxZoom, s.Source.Name(), s.Source.MinZoom(), s.Source.MaxZoom())
+	}
+
+	start := time.Now()
+	progress := SeedProgress{Total: area.TileCount(), Zoom: area.MinZoom}
+	var mu sync.Mutex
+	var lastReport time.Time
+
+	// record updates progress and reports it, at most every seedProgressInterval
+	record := func(update func(p *SeedProgress)) {
+		mu.Lock()
+		defer mu.Unlock()
+		update(&progress)
+		progress.Done++
+		progress.Elapsed = time.Since(start)
+		if s.OnProgress != nil && (progress.Done == progress.Total || time.Since(lastReport) >= seedProgressInterval) {
+			lastReport = time.Now()
+			s.OnProgress(progress)
+		}
+	}
+
+	var limiter <-chan time.Time
+	if s.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / s.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	keys := make(chan TileKey)
+	var wg sync.WaitGroup
+	for i := 0; i < max(1, s.Workers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				if s.Sink.HasTile(key) {
+					record(func(p *SeedProgress) { p.Skipped++ })
+					continue
+				}
+
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-ctx.Done():
+						continue
+					}
+				}
+
+				size, err := s.seedTile(ctx, key)
+				if errors.Is(err, context.Canceled) {
+					continue
+				}
+				if err != nil {
+					log.Printf("Error seeding tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+				}
+				record(func(p *SeedProgress) {
+					p.Zoom = key.Zoom
+					p.Bytes += size
+					if err != nil {
+						p.Failed++
+					}
+				})
+			}
+		}()
+	}
+
+	area.forEachTile(func(key TileKey) bool {
+		select {
+		case keys <- key:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(keys)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	progress.Elapsed = time.Since(start)
+	if s.OnProgress != nil {
+		s.OnProgress(progress)
+	}
+	return progress, ctx.Err()
+}
+
+// seedTile downloads one tile into the sink and returns its size
+func (s *Seeder) seedTile(ctx context.Context, key TileKey) (int64, error) {
+	var tile *CachedTile
+	if src, ok := s.Source.(ConditionalSource); ok {
+		var err error
+		if tile, _, err = src.FetchTileConditional(ctx, key, nil); err != nil {
+			return 0, err
+		}
+	} else {
+		data, err := s.Source.FetchTile(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		tile = &CachedTile{Data: data, Expires: time.Now().Add(DefaultTileMaxAge)}
+	}
+
+	if err := s.Sink.PutTile(key, tile); err != nil {
+		return 0, fmt.Errorf("storing tile failed: %w", err)
+	}
+	return int64(len(tile.Data)), nil
+}
@@ -0,0 +1,72 @@
+package tilemap
+
+import "testing"
+
+func TestSeedAreaTileRanges(t *testing.T) {
+	denver := BBox{MinLon: -105.3, MinLat: 39.9, MaxLon: -105.1, MaxLat: 40.1}
+	world := BBox{MinLon: -180, MinLat: -85.0511, MaxLon: 180, MaxLat: 85.0511}
+
+	tests := []struct {
+		name    string
+		area    SeedArea
+		want    []TileRange
+		count   int
+		invalid bool
+	}{
+		{
+			name:  "Single level",
+			area:  SeedArea{Bounds: denver, MinZoom: 10, MaxZoom: 10},
+			want:  []TileRange{{Zoom: 10, MinX: 212, MaxX: 213, MinY: 387, MaxY: 388}},
+			count: 4,
+		},
+		{
+			name: "Several levels",
+			area: SeedArea{Bounds: world, MinZoom: 0, MaxZoom: 2},
+			want: []TileRange{
+				{Zoom: 0},
+				{Zoom: 1, MaxX: 1, MaxY: 1},
+				{Zoom: 2, MaxX: 3, MaxY: 3},
+			},
+			count: 1 + 4 + 16,
+		},
+		{
+			name:  "Crossing the antimeridian",
+			area:  SeedArea{Bounds: BBox{MinLon: 170, MinLat: -20, MaxLon: -170, MaxLat: -10}, MinZoom: 2, MaxZoom: 2},
+			want:  []TileRange{{Zoom: 2, MinX: 3, MaxX: 4, MinY: 2, MaxY: 2}},
+			count: 2,
+		},
+		{
+			name:    "Inverted zoom range",
+			area:    SeedArea{Bounds: denver, MinZoom: 12, MaxZoom: 10},
+			invalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.area.Validate(); (err != nil) != tt.invalid {
+				t.Fatalf("Validate() = %v; want invalid %v", err, tt.invalid)
+			}
+
+			got := tt.area.TileRanges()
+			if (got == nil) != (tt.want == nil) || len(got) != len(tt.want) {
+				t.Fatalf("TileRanges() = %+v; want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("TileRanges()[%d] = %+v; want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if got := tt.area.TileCount(); got != tt.count {
+				t.Errorf("TileCount() = %d; want %d", got, tt.count)
+			}
+			if got := tt.area.EstimateBytes(); got != int64(tt.count)*EstimatedTileBytes {
+				t.Errorf("EstimateBytes() = %d; want %d", got, int64(tt.count)*EstimatedTileBytes)
+			}
+		})
+	}
+
+	if err := (SeedArea{Bounds: denver, MinZoom: -1, MaxZoom: 2}).Validate(); err == nil {
+		t.Error("Validate() accepted a negative zoom")
+	}
+}
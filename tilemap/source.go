@@ -0,0 +1,312 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is sent with tile requests when a source doesn't set its own
+const DefaultUserAgent = "FiberForge 1.0"
+
+// TileSource supplies raw encoded tile images (PNG, JPEG, ...) to a TileMap
+type TileSource interface {
+	// Name uniquely identifies the source
+	Name() string
+	// MinZoom is the lowest zoom level the source has tiles for
+	MinZoom() int
+	// MaxZoom is the highest zoom level the source has tiles for
+	MaxZoom() int
+	// FetchTile returns the encoded image bytes for a tile.
+	// Implementations should abort when ctx is cancelled.
+	FetchTile(ctx context.Context, key TileKey) ([]byte, error)
+}
+
+// ErrTileNotFound is wrapped by sources that know they have no tile at a
+// position, such as archives with gaps. The map remembers these tiles as empty
+// rather than fetching them again.
+var ErrTileNotFound = errors.New("tile not found")
+
+// BoundedSource is a TileSource that only has tiles inside an area. Tiles
+// outside it are not fetched.
+type BoundedSource interface {
+	TileSource
+	// TileBounds returns the area the source has tiles for, or nil if unknown
+	TileBounds() *BBox
+}
+
+// ConditionalSource is a TileSource that supports HTTP-style revalidation.
+// Tiles from these sources are kept in the persistent DiskCache.
+type ConditionalSource interface {
+	TileSource
+	// FetchTileConditional fetches a tile, revalidating cached when it is non-nil.
+	// notModified is true when the cached copy is still current; the returned
+	// tile then carries cached's data with refreshed validators and expiry.
+	FetchTileConditional(ctx context.Context, key TileKey, cached *CachedTile) (tile *CachedTile, notModified bool, err error)
+}
+
+// XYZSource fetches tiles from an HTTP server using a URL template.
+//
+// The template supports the following placeholders:
+//   - {z}, {x}, {y}: tile zoom and coordinates
+//   - {-y}: TMS-style Y coordinate (flipped vertically)
+//   - {s}: subdomain, chosen from Subdomains based on tile position
+//   - {r}: "@2x" when TilePixels is twice TileSize, for high-resolution tiles
+type XYZSource struct {
+	ID          string
+	URLTemplate string
+	Subdomains  []string
+
+	// TilePixels is the pixel size of the tile images, such as 512 for @2x or
+	// 512px tiles. Tiles always cover the same area; 0 means TileSize.
+	TilePixels int
+
+	// Request customization
+	UserAgent   string
+	Headers     map[string]string
+	APIKeyParam string // Query parameter name for APIKey, e.g. "access_token"
+	APIKey      string
+
+	MinZoomLevel int
+	MaxZoomLevel int
+
+	// Credit is shown on the map while the source is drawn
+	Credit Attribution
+
+	Client *http.Client
+}
+
+var (
+	_ ConditionalSource = (*XYZSource)(nil)
+	_ AttributedSource  = (*XYZSource)(nil)
+)
+
+// NewXYZSource creates a new XYZ source covering the full default zoom range
+func NewXYZSource(id, urlTemplate string) *XYZSource {
+	return &XYZSource{
+		ID:           id,
+		URLTemplate:  urlTemplate,
+		UserAgent:    DefaultUserAgent,
+		MinZoomLevel: 0,
+		MaxZoomLevel: MaxZoomLevel,
+		Client:       &http.Client{},
+	}
+}
+
+// NewOpenStreetMapSource returns a source for the standard OpenStreetMap tile server
+func NewOpenStreetMapSource() *XYZSource {
+	s := NewXYZSource("osm", "https://tile.openstreetmap.org/{z}/{x}/{y}.png")
+	s.Credit = OpenStreetMapAttribution
+	return s
+}
+
+// Name implements TileSource
+func (s *XYZSource) Name() string {
+	return tileSourceName(s.ID, s.TilePixels)
+}
+
+// MinZoom implements TileSource
+func (s *XYZSource) MinZoom() int {
+	return s.MinZoomLevel
+}
+
+// MaxZoom implements TileSource
+func (s *XYZSource) MaxZoom() int {
+	return s.MaxZoomLevel
+}
+
+// Attribution implements AttributedSource
+func (s *XYZSource) Attribution() Attribution {
+	return s.Credit
+}
+
+// TileURL expands the URL template for the given tile
+func (s *XYZSource) TileURL(key TileKey) (string, error) {
+	replacements := []string{
+		"{z}", strconv.Itoa(key.Zoom),
+		"{x}", strconv.Itoa(key.X),
+		"{y}", strconv.Itoa(key.Y),
+		"{-y}", strconv.Itoa((1 << key.Zoom) - 1 - key.Y),
+		"{r}", "",
+	}
+	if s.TilePixels == 2*TileSize {
+		replacements[len(replacements)-1] = "@2x"
+	}
+	if len(s.Subdomains) > 0 {
+		sub := s.Subdomains[(key.X+key.Y)%len(s.Subdomains)]
+		replacements = append(replacements, "{s}", sub)
+	}
+	tileURL := strings.NewReplacer(replacements...).Replace(s.URLTemplate)
+
+	if s.APIKeyParam == "" || s.APIKey == "" {
+		return tileURL, nil
+	}
+
+	u, err := url.Parse(tileURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing tile URL %s failed: %w", tileURL, err)
+	}
+	q := u.Query()
+	q.Set(s.APIKeyParam, s.APIKey)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// NewRequest builds the HTTP request for a tile, including custom headers
+func (s *XYZSource) NewRequest(ctx context.Context, key TileKey) (*http.Request, error) {
+	tileURL, err := s.TileURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s failed: %w", tileURL, err)
+	}
+
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// FetchTile implements TileSource
+func (s *XYZSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	tile, _, err := s.FetchTileConditional(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tile.Data, nil
+}
+
+// FetchTileConditional implements ConditionalSource
+func (s *XYZSource) FetchTileConditional(ctx context.Context, key TileKey, cached *CachedTile) (*CachedTile, bool, error) {
+	if err := checkTileKey(s, key); err != nil {
+		return nil, false, err
+	}
+
+	req, err := s.NewRequest(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return fetchConditional(s.Client, req, cached)
+}
+
+// fetchConditional sends a tile request, revalidating cached when it is non-nil.
+// A nil client means http.DefaultClient.
+func fetchConditional(client *http.Client, req *http.Request, cached *CachedTile) (*CachedTile, bool, error) {
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("fetching %s failed: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		tile := &CachedTile{
+			Data:         cached.Data,
+			ETag:         cached.ETag,
+			LastModified: cached.LastModified,
+			Expires:      responseExpiry(resp, time.Now()),
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			tile.ETag = etag
+		}
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			tile.LastModified = lastModified
+		}
+		return tile, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch tile %s: %s", req.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading tile %s failed: %w", req.URL, err)
+	}
+
+	return &CachedTile{
+		Data:         data,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      responseExpiry(resp, time.Now()),
+	}, false, nil
+}
+
+// responseExpiry works out when a response goes stale from its Cache-Control
+// max-age or Expires header, falling back to DefaultTileMaxAge
+func responseExpiry(resp *http.Response, now time.Time) time.Time {
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if secs, err := strconv.Atoi(value); err == nil {
+				return now.Add(time.Duration(secs) * time.Second)
+			}
+		}
+	}
+
+	if t, err := http.ParseTime(resp.Header.Get("Expires")); err == nil {
+		return t
+	}
+
+	return now.Add(DefaultTileMaxAge)
+}
+
+// tileSourceName names a source by its id, marking high-resolution variants so
+// their tiles are cached apart from standard ones
+func tileSourceName(id string, tilePixels int) string {
+	if tilePixels > TileSize {
+		return fmt.Sprintf("%s@%dpx", id, tilePixels)
+	}
+	return id
+}
+
+// sourceCovers reports whether a tile may exist in a source, judging by the
+// source's bounds if it has them
+func sourceCovers(src TileSource, key TileKey) bool {
+	bounded, ok := src.(BoundedSource)
+	if !ok {
+		return true
+	}
+	bounds := bounded.TileBounds()
+	return bounds == nil || bounds.intersectsTile(key)
+}
+
+// checkTileKey verifies a tile lies within the source's zoom range and the tile grid
+func checkTileKey(src TileSource, key TileKey) error {
+	if key.Zoom < src.MinZoom() || key.Zoom > src.MaxZoom() {
+		return fmt.Errorf("zoom %d outside range %d-%d for source %s",
+			key.Zoom, src.MinZoom(), src.MaxZoom(), src.Name())
+	}
+	maxCoord := 1 << key.Zoom
+	if key.X < 0 || key.X >= maxCoord || key.Y < 0 || key.Y >= maxCoord {
+		return fmt.Errorf("tile coordinates (%d, %d) out of range for zoom %d", key.X, key.Y, key.Zoom)
+	}
+	return nil
+}
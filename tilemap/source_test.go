@@ -0,0 +1,174 @@
+package tilemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestXYZSourceTileURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		source XYZSource
+		key    TileKey
+		want   string
+	}{
+		{
+			name:   "XYZ",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png"},
+			key:    TileKey{Zoom: 3, X: 2, Y: 5},
+			want:   "https://tiles.example.com/3/2/5.png",
+		},
+		{
+			name:   "TMS row",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{-y}.png"},
+			key:    TileKey{Zoom: 3, X: 2, Y: 5},
+			want:   "https://tiles.example.com/3/2/2.png",
+		},
+		{
+			name:   "TMS row at zoom 0",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{-y}.png"},
+			key:    TileKey{Zoom: 0},
+			want:   "https://tiles.example.com/0/0/0.png",
+		},
+		{
+			name:   "First subdomain",
+			source: XYZSource{URLTemplate: "https://{s}.tiles.example.com/{z}/{x}/{y}.png", Subdomains: []string{"a", "b", "c"}},
+			key:    TileKey{Zoom: 4, X: 3, Y: 3},
+			want:   "https://a.tiles.example.com/4/3/3.png",
+		},
+		{
+			name:   "Subdomains rotate with the tile",
+			source: XYZSource{URLTemplate: "https://{s}.tiles.example.com/{z}/{x}/{y}.png", Subdomains: []string{"a", "b", "c"}},
+			key:    TileKey{Zoom: 4, X: 3, Y: 5},
+			want:   "https://c.tiles.example.com/4/3/5.png",
+		},
+		{
+			name:   "Standard resolution",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}{r}.png"},
+			key:    TileKey{Zoom: 1, X: 1, Y: 0},
+			want:   "https://tiles.example.com/1/1/0.png",
+		},
+		{
+			name:   "High resolution",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}{r}.png", TilePixels: 2 * TileSize},
+			key:    TileKey{Zoom: 1, X: 1, Y: 0},
+			want:   "https://tiles.example.com/1/1/0@2x.png",
+		},
+		{
+			name:   "API key",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png", APIKeyParam: "access_token", APIKey: "k&1"},
+			key:    TileKey{Zoom: 2, X: 1, Y: 1},
+			want:   "https://tiles.example.com/2/1/1.png?access_token=k%261",
+		},
+		{
+			name:   "API key added to an existing query",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png?style=dark", APIKeyParam: "key", APIKey: "abc"},
+			key:    TileKey{Zoom: 2, X: 1, Y: 1},
+			want:   "https://tiles.example.com/2/1/1.png?key=abc&style=dark",
+		},
+		{
+			name:   "API key replaces a placeholder value",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png?key=KEY", APIKeyParam: "key", APIKey: "abc"},
+			key:    TileKey{Zoom: 2, X: 1, Y: 1},
+			want:   "https://tiles.example.com/2/1/1.png?key=abc",
+		},
+		{
+			name:   "API key parameter without a key",
+			source: XYZSource{URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png?style=dark", APIKeyParam: "key"},
+			key:    TileKey{Zoom: 2, X: 1, Y: 1},
+			want:   "https://tiles.example.com/2/1/1.png?style=dark",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.TileURL(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TileURL(%v) = %s; want %s", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXYZSourceNewRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		userAgent     string
+		headers       map[string]string
+		wantUserAgent string
+	}{
+		{name: "Default User-Agent", wantUserAgent: DefaultUserAgent},
+		{name: "Custom User-Agent", userAgent: "county-viewer/2.0", wantUserAgent: "county-viewer/2.0"},
+		{
+			name:          "Custom headers",
+			headers:       map[string]string{"Referer": "https://gis.example.com/", "X-Api-Key": "abc"},
+			wantUserAgent: DefaultUserAgent,
+		},
+		{
+			name:          "Header overrides the User-Agent",
+			userAgent:     "county-viewer/2.0",
+			headers:       map[string]string{"User-Agent": "override/1.0"},
+			wantUserAgent: "override/1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &XYZSource{
+				URLTemplate: "https://tiles.example.com/{z}/{x}/{y}.png",
+				UserAgent:   tt.userAgent,
+				Headers:     tt.headers,
+			}
+			req, err := src.NewRequest(context.Background(), TileKey{Zoom: 1, X: 1, Y: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Method != "GET" || req.URL.String() != "https://tiles.example.com/1/1/1.png" {
+				t.Errorf("request = %s %s", req.Method, req.URL)
+			}
+			if got := req.Header.Get("User-Agent"); got != tt.wantUserAgent {
+				t.Errorf("User-Agent = %q; want %q", got, tt.wantUserAgent)
+			}
+			for name, value := range tt.headers {
+				if name != "User-Agent" && req.Header.Get(name) != value {
+					t.Errorf("%s = %q; want %q", name, req.Header.Get(name), value)
+				}
+			}
+		})
+	}
+}
+
+func TestXYZSourceFetchTile(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte("tile"))
+	}))
+	defer server.Close()
+
+	src := NewXYZSource("test", server.URL+"/{z}/{x}/{-y}.png?style=dark")
+	src.APIKeyParam, src.APIKey = "key", "abc"
+	src.Headers = map[string]string{"Referer": "https://gis.example.com/"}
+	data, err := src.FetchTile(context.Background(), TileKey{Zoom: 2, X: 1, Y: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "tile" {
+		t.Errorf("data = %q", data)
+	}
+	if got.URL.Path != "/2/1/3.png" || got.URL.Query().Get("key") != "abc" || got.URL.Query().Get("style") != "dark" {
+		t.Errorf("requested %s", got.URL)
+	}
+	if got.Header.Get("Referer") != "https://gis.example.com/" || got.Header.Get("User-Agent") != DefaultUserAgent {
+		t.Errorf("headers = %v", got.Header)
+	}
+
+	if _, err := src.FetchTile(context.Background(), TileKey{Zoom: 2, X: 4, Y: 0}); err == nil {
+		t.Error("fetched a tile outside the world")
+	}
+}
@@ -0,0 +1,189 @@
+package tilemap
+
+import (
+	"image/color"
+	"slices"
+
+	"github.com/OpticalFlyer/goliath/mvt"
+)
+
+// ZoomStop is a value at a zoom level
+type ZoomStop struct {
+	Zoom  float64
+	Value float64
+}
+
+// ZoomStops is a function of zoom, interpolated linearly between stops sorted
+// by zoom and held constant beyond the first and last stop
+type ZoomStops []ZoomStop
+
+// At returns the value at a zoom level, or 0 if there are no stops
+func (s ZoomStops) At(zoom float64) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	if zoom <= s[0].Zoom {
+		return s[0].Value
+	}
+	for i := 1; i < len(s); i++ {
+		if zoom <= s[i].Zoom {
+			t := (zoom - s[i-1].Zoom) / (s[i].Zoom - s[i-1].Zoom)
+			return s[i-1].Value + t*(s[i].Value-s[i-1].Value)
+		}
+	}
+	return s[len(s)-1].Value
+}
+
+// FeatureFilter selects the features a style layer draws
+type FeatureFilter func(f *mvt.Feature) bool
+
+// PropertyIn matches features whose string property is one of values
+func PropertyIn(key string, values ...string) FeatureFilter {
+	return func(f *mvt.Feature) bool {
+		return slices.Contains(values, f.String(key))
+	}
+}
+
+// VectorStyleLayer says how to paint the features of one vector tile layer
+type VectorStyleLayer struct {
+	SourceLayer string        // Name of the MVT layer to draw
+	Filter      FeatureFilter // nil draws every feature
+	MinZoom     int
+	MaxZoom     int // 0 means no limit
+
+	Fill   color.Color // Polygon fill and point color; nil for none
+	Stroke color.Color // Line and polygon outline color; nil for none
+	Width  ZoomStops   // Stroke width in pixels
+}
+
+// visibleAt reports whether the layer is drawn at a zoom level
+func (l *VectorStyleLayer) visibleAt(zoom int) bool {
+	return zoom >= l.MinZoom && (l.MaxZoom == 0 || zoom <= l.MaxZoom)
+}
+
+// VectorStyle describes how vector tiles are drawn. Layers are painted in order,
+// so later layers appear on top.
+type VectorStyle struct {
+	Name       string
+	Background color.Color
+	Layers     []VectorStyleLayer
+}
+
+// vectorPalette holds the colors of a built-in style
+type vectorPalette struct {
+	background, water, park, wood, residential, building color.Color
+	minorRoad, majorRoad, motorway, casing, rail         color.Color
+	boundary                                             color.Color
+}
+
+// LightVectorStyle returns a daytime style for OpenMapTiles-schema vector tiles
+func LightVectorStyle() *VectorStyle {
+	return newVectorStyle("light", vectorPalette{
+		background:  color.RGBA{242, 239, 233, 255},
+		water:       color.RGBA{170, 211, 223, 255},
+		park:        color.RGBA{200, 230, 180, 255},
+		wood:        color.RGBA{173, 209, 158, 255},
+		residential: color.RGBA{232, 228, 220, 255},
+		building:    color.RGBA{217, 208, 201, 255},
+		minorRoad:   color.RGBA{255, 255, 255, 255},
+		majorRoad:   color.RGBA{252, 214, 164, 255},
+		motorway:    color.RGBA{233, 144, 160, 255},
+		casing:      color.RGBA{190, 180, 170, 255},
+		rail:        color.RGBA{150, 150, 150, 255},
+		boundary:    color.RGBA{160, 120, 170, 255},
+	})
+}
+
+// DarkVectorStyle returns a low-glare style for night use
+func DarkVectorStyle() *VectorStyle {
+	return newVectorStyle("dark", vectorPalette{
+		background:  color.RGBA{28, 30, 34, 255},
+		water:       color.RGBA{18, 38, 56, 255},
+		park:        color.RGBA{30, 44, 34, 255},
+		wood:        color.RGBA{32, 48, 36, 255},
+		residential: color.RGBA{36, 38, 42, 255},
+		building:    color.RGBA{48, 50, 56, 255},
+		minorRoad:   color.RGBA{70, 72, 78, 255},
+		majorRoad:   color.RGBA{110, 98, 70, 255},
+		motorway:    color.RGBA{140, 90, 60, 255},
+		casing:      color.RGBA{20, 20, 24, 255},
+		rail:        color.RGBA{80, 80, 86, 255},
+		boundary:    color.RGBA{120, 100, 130, 255},
+	})
+}
+
+// HighContrastVectorStyle returns a style for bright sunlight and low vision:
+// black roads and outlines on a white background
+func HighContrastVectorStyle() *VectorStyle {
+	return newVectorStyle("high-contrast", vectorPalette{
+		background:  color.RGBA{255, 255, 255, 255},
+		water:       color.RGBA{0, 90, 255, 255},
+		park:        color.RGBA{120, 220, 120, 255},
+		wood:        color.RGBA{60, 170, 60, 255},
+		residential: color.RGBA{255, 255, 255, 255},
+		building:    color.RGBA{160, 160, 160, 255},
+		minorRoad:   color.RGBA{60, 60, 60, 255},
+		majorRoad:   color.RGBA{0, 0, 0, 255},
+		motorway:    color.RGBA{200, 0, 0, 255},
+		casing:      color.RGBA{255, 255, 255, 255},
+		rail:        color.RGBA{0, 0, 0, 255},
+		boundary:    color.RGBA{160, 0, 160, 255},
+	})
+}
+
+// VectorStyleByName returns a built-in style: light, dark or high-contrast
+func VectorStyleByName(name string) (*VectorStyle, bool) {
+	switch name {
+	case "light":
+		return LightVectorStyle(), true
+	case "dark":
+		return DarkVectorStyle(), true
+	case "high-contrast":
+		return HighContrastVectorStyle(), true
+	}
+	return nil, false
+}
+
+// newVectorStyle builds the layer list shared by the built-in styles
+func newVectorStyle(name string, p vectorPalette) *VectorStyle {
+	majorClasses := []string{"trunk", "primary", "secondary", "tertiary"}
+	minorClasses := []string{"minor", "service", "track", "path", "residential", "unclassified"}
+
+	return &VectorStyle{
+		Name:       name,
+		Background: p.background,
+		Layers: []VectorStyleLayer{
+			{SourceLayer: "landuse", Filter: PropertyIn("class", "residential", "suburb", "neighbourhood"), Fill: p.residential},
+			{SourceLayer: "landcover", Filter: PropertyIn("class", "wood", "forest"), Fill: p.wood},
+			{SourceLayer: "landcover", Filter: PropertyIn("class", "grass", "farmland", "scrub"), Fill: p.park},
+			{SourceLayer: "park", Fill: p.park},
+			{SourceLayer: "water", Fill: p.water},
+			{SourceLayer: "waterway", Stroke: p.water, Width: ZoomStops{{8, 0.5}, {14, 2}, {18, 6}}},
+			{SourceLayer: "building", MinZoom: 13, Fill: p.building},
+			{SourceLayer: "boundary", Filter: adminLevelAtMost(4), Stroke: p.boundary, Width: ZoomStops{{2, 0.5}, {10, 1.5}}},
+
+			{SourceLayer: "transportation", Filter: PropertyIn("class", "rail"), MinZoom: 10,
+				Stroke: p.rail, Width: ZoomStops{{10, 0.5}, {16, 1.5}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", minorClasses...), MinZoom: 12,
+				Stroke: p.casing, Width: ZoomStops{{12, 1}, {14, 3}, {18, 16}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", minorClasses...), MinZoom: 12,
+				Stroke: p.minorRoad, Width: ZoomStops{{12, 0.5}, {14, 2}, {18, 13}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", majorClasses...), MinZoom: 6,
+				Stroke: p.casing, Width: ZoomStops{{6, 0.5}, {12, 3}, {18, 22}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", majorClasses...), MinZoom: 6,
+				Stroke: p.majorRoad, Width: ZoomStops{{6, 0.5}, {12, 2}, {18, 18}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", "motorway"), MinZoom: 4,
+				Stroke: p.casing, Width: ZoomStops{{4, 0.5}, {10, 3}, {18, 26}}},
+			{SourceLayer: "transportation", Filter: PropertyIn("class", "motorway"), MinZoom: 4,
+				Stroke: p.motorway, Width: ZoomStops{{4, 0.5}, {10, 2}, {18, 22}}},
+		},
+	}
+}
+
+// adminLevelAtMost matches boundaries of country, state and similar levels
+func adminLevelAtMost(level float64) FeatureFilter {
+	return func(f *mvt.Feature) bool {
+		v, ok := f.Number("admin_level")
+		return ok && v <= level
+	}
+}
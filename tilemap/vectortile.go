@@ -0,0 +1,357 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/OpticalFlyer/goliath/mvt"
+)
+
+// VectorTileSource is a TileSource that can serve Mapbox Vector Tiles. When
+// IsVector is true the map renders its tiles with the map's VectorStyle.
+type VectorTileSource interface {
+	TileSource
+	IsVector() bool
+	FetchVectorTile(ctx context.Context, key TileKey) ([]byte, error)
+}
+
+// maxStrokePoints splits long lines so each stroke stays within ebiten's
+// 16-bit vertex index limit
+const maxStrokePoints = 1024
+
+var (
+	whiteImageOnce sync.Once
+	whiteSubImage  *ebiten.Image
+)
+
+// getWhiteSubImage returns a white image for DrawTriangles sources. The inner
+// pixel of a 3x3 image is used so sampling never bleeds past its edge.
+func getWhiteSubImage() *ebiten.Image {
+	whiteImageOnce.Do(func() {
+		img := ebiten.NewImage(3, 3)
+		img.Fill(color.White)
+		whiteSubImage = img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+	})
+	return whiteSubImage
+}
+
+// fetchVectorTile fetches, decodes and renders a vector tile into the memory cache
+func (tm *TileMap) fetchVectorTile(ctx context.Context, src VectorTileSource, key TileKey) error {
+	data, err := src.FetchVectorTile(ctx, key)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, ErrTileNotFound) {
+		tm.storeTile(key, nil)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error fetching tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		return err
+	}
+
+	tile, err := mvt.Decode(data)
+	if err != nil {
+		log.Printf("Error decoding vector tile %d/%d/%d: %v", key.Zoom, key.X, key.Y, err)
+		return err
+	}
+
+	tm.storeTile(key, renderVectorTile(tile, tm.VectorStyle(), key.Zoom, int(tm.vectorTileSize.Load())))
+	return nil
+}
+
+// renderVectorTile draws a vector tile into a new image of size pixels.
+// Line widths are in logical pixels and scale with the image.
+func renderVectorTile(tile *mvt.Tile, style *VectorStyle, zoom, size int) *ebiten.Image {
+	img := ebiten.NewImage(size, size)
+	pixelRatio := float32(size) / TileSize
+	if style.Background != nil {
+		img.Fill(style.Background)
+	}
+
+	for i := range style.Layers {
+		styleLayer := &style.Layers[i]
+		if !styleLayer.visibleAt(zoom) {
+			continue
+		}
+		layer := tile.Layer(styleLayer.SourceLayer)
+		if layer == nil || layer.Extent <= 0 {
+			continue
+		}
+
+		scale := float32(size) / float32(layer.Extent)
+		width := float32(styleLayer.Width.At(float64(zoom))) * pixelRatio
+		for _, f := range layer.Features {
+			if styleLayer.Filter != nil && !styleLayer.Filter(f) {
+				continue
+			}
+			drawFeature(img, f, styleLayer, scale, width)
+		}
+	}
+	return img
+}
+
+// drawFeature paints one feature with a style layer
+func drawFeature(dst *ebiten.Image, f *mvt.Feature, l *VectorStyleLayer, scale, width float32) {
+	switch f.Type {
+	case mvt.GeomPoint:
+		if l.Fill == nil {
+			return
+		}
+		radius := width / 2
+		if radius < 2 {
+			radius = 2
+		}
+		for _, part := range f.Geometry {
+			p := part[0]
+			vector.DrawFilledCircle(dst, float32(p.X)*scale, float32(p.Y)*scale, radius, l.Fill, true)
+		}
+
+	case mvt.GeomLineString:
+		if l.Stroke == nil || width <= 0 {
+			return
+		}
+		for _, line := range f.Geometry {
+			strokeLine(dst, line, l.Stroke, scale, width)
+		}
+
+	case mvt.GeomPolygon:
+		if l.Fill != nil {
+			rings := make([][]fillPoint, 0, len(f.Geometry))
+			for _, ring := range f.Geometry {
+				if len(ring) >= 3 {
+					rings = append(rings, scaleRing(ring, scale))
+				}
+			}
+			for _, group := range splitRings(rings, ebiten.MaxVertexCount, 0) {
+				var path vector.Path
+				for _, ring := range group {
+					path.MoveTo(ring[0].X, ring[0].Y)
+					for _, p := range ring[1:] {
+						path.LineTo(p.X, p.Y)
+					}
+					path.Close()
+				}
+				FillPath(dst, &path, l.Fill)
+			}
+		}
+		if l.Stroke != nil && width > 0 {
+			for _, ring := range f.Geometry {
+				if len(ring) < 2 {
+					continue
+				}
+				strokeLine(dst, append(ring[:len(ring):len(ring)], ring[0]), l.Stroke, scale, width)
+			}
+		}
+	}
+}
+
+// strokeLine outlines a line in tile coordinates, in pieces of at most
+// maxStrokePoints. Round caps hide the seams between pieces.
+func strokeLine(dst *ebiten.Image, line []mvt.Point, clr color.Color, scale, width float32) {
+	for start := 0; start < len(line)-1; start += maxStrokePoints - 1 {
+		end := min(len(line), start+maxStrokePoints)
+		var path vector.Path
+		appendPath(&path, line[start:end], scale)
+		StrokePath(dst, &path, clr, width)
+	}
+}
+
+// appendPath adds a line in tile coordinates to a path in pixels
+func appendPath(path *vector.Path, points []mvt.Point, scale float32) {
+	if len(points) == 0 {
+		return
+	}
+	path.MoveTo(float32(points[0].X)*scale, float32(points[0].Y)*scale)
+	for _, p := range points[1:] {
+		path.LineTo(float32(p.X)*scale, float32(p.Y)*scale)
+	}
+}
+
+// fillPoint is a polygon vertex in pixels
+type fillPoint struct{ X, Y float32 }
+
+// maxFillSplits bounds how often splitRings halves a polygon, for rings that
+// cross the split line so often that halving doesn't shrink them
+const maxFillSplits = 16
+
+// scaleRing converts a ring in tile coordinates to pixels
+func scaleRing(ring []mvt.Point, scale float32) []fillPoint {
+	points := make([]fillPoint, len(ring))
+	for i, p := range ring {
+		points[i] = fillPoint{float32(p.X) * scale, float32(p.Y) * scale}
+	}
+	return points
+}
+
+// splitRings divides a polygon's rings into groups of at most limit points,
+// each filled with its own draw call, by clipping them to halves of their
+// bounding box. Clipping to a convex area keeps the winding number of every
+// point inside it, so holes still stay empty under the nonzero rule.
+func splitRings(rings [][]fillPoint, limit, depth int) [][][]fillPoint {
+	count := 0
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, ring := range rings {
+		count += len(ring)
+		for _, p := range ring {
+			if p.X < minX {
+				minX = p.X
+			}
+			if p.X > maxX {
+				maxX = p.X
+			}
+			if p.Y < minY {
+				minY = p.Y
+			}
+			if p.Y > maxY {
+				maxY = p.Y
+			}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	if count <= limit || depth >= maxFillSplits {
+		return [][][]fillPoint{rings}
+	}
+
+	vertical := maxX-minX >= maxY-minY
+	mid := (minX + maxX) / 2
+	if !vertical {
+		mid = (minY + maxY) / 2
+	}
+
+	var groups [][][]fillPoint
+	for _, below := range []bool{true, false} {
+		var half [][]fillPoint
+		for _, ring := range rings {
+			if clipped := clipRing(ring, vertical, mid, below); len(clipped) >= 3 {
+				half = append(half, clipped)
+			}
+		}
+		groups = append(groups, splitRings(half, limit, depth+1)...)
+	}
+	return groups
+}
+
+// clipRing clips a ring to one side of a vertical or horizontal line at mid,
+// the side below it when below is true
+func clipRing(ring []fillPoint, vertical bool, mid float32, below bool) []fillPoint {
+	coord := func(p fillPoint) float32 {
+		if vertical {
+			return p.X
+		}
+		return p.Y
+	}
+	inside := func(p fillPoint) bool {
+		if below {
+			return coord(p) <= mid
+		}
+		return coord(p) >= mid
+	}
+	crossing := func(a, b fillPoint) fillPoint {
+		t := (mid - coord(a)) / (coord(b) - coord(a))
+		p := fillPoint{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)}
+		if vertical {
+			p.X = mid
+		} else {
+			p.Y = mid
+		}
+		return p
+	}
+
+	var clipped []fillPoint
+	prev := ring[len(ring)-1]
+	for _, cur := range ring {
+		switch {
+		case inside(cur):
+			if !inside(prev) {
+				clipped = append(clipped, crossing(prev, cur))
+			}
+			clipped = append(clipped, cur)
+		case inside(prev):
+			clipped = append(clipped, crossing(prev, cur))
+		}
+		prev = cur
+	}
+	return clipped
+}
+
+// FillPath fills a path using the nonzero rule, so holes wound opposite to
+// their exterior ring stay empty
+func FillPath(dst *ebiten.Image, path *vector.Path, clr color.Color) {
+	vs, is := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	drawVertices(dst, vs, is, clr, ebiten.FillRuleNonZero)
+}
+
+// StrokePath outlines a path with round joins and caps
+func StrokePath(dst *ebiten.Image, path *vector.Path, clr color.Color, width float32) {
+	vs, is := path.AppendVerticesAndIndicesForStroke(nil, nil, &vector.StrokeOptions{
+		Width:    width,
+		LineJoin: vector.LineJoinRound,
+		LineCap:  vector.LineCapRound,
+	})
+	drawVertices(dst, vs, is, clr, ebiten.FillRuleFillAll)
+}
+
+// drawVertices draws triangles in a solid color
+func drawVertices(dst *ebiten.Image, vs []ebiten.Vertex, is []uint16, clr color.Color, fillRule ebiten.FillRule) {
+	if len(is) == 0 {
+		return
+	}
+	if len(vs) > ebiten.MaxVertexCount {
+		log.Printf("Skipping vector shape with %d vertices", len(vs))
+		return
+	}
+
+	r, g, b, a := clr.RGBA()
+	for i := range vs {
+		vs[i].SrcX = 1
+		vs[i].SrcY = 1
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+
+	op := &ebiten.DrawTrianglesOptions{
+		ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		FillRule:       fillRule,
+		AntiAlias:      true,
+	}
+	dst.DrawTriangles(vs, is, getWhiteSubImage(), op)
+}
+
+// VectorStyle returns the style used to render vector tiles
+func (tm *TileMap) VectorStyle() *VectorStyle {
+	return tm.vectorStyle.Load()
+}
+
+// SetVectorStyle changes how vector tiles are drawn; nil restores the light
+// style. Rendered tiles are dropped from the memory cache and redrawn.
+func (tm *TileMap) SetVectorStyle(style *VectorStyle) {
+	if style == nil {
+		style = LightVectorStyle()
+	}
+	tm.vectorStyle.Store(style)
+	tm.clearVectorTiles()
+}
+
+// clearVectorTiles drops the tiles of vector layers from the memory cache so
+// they are drawn again. Raster tiles are kept.
+func (tm *TileMap) clearVectorTiles() {
+	for _, layer := range tm.layers {
+		if src, ok := layer.source.(VectorTileSource); ok && src.IsVector() {
+			tm.tileCache.removeLayer(layer.id)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package tilemap
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// fakeVectorSource is a vector tile source with no tiles
+type fakeVectorSource struct{ XYZSource }
+
+func (s *fakeVectorSource) IsVector() bool { return true }
+
+func (s *fakeVectorSource) FetchVectorTile(ctx context.Context, key TileKey) ([]byte, error) {
+	return nil, ErrTileNotFound
+}
+
+func TestClearVectorTilesKeepsRaster(t *testing.T) {
+	tm := New(256, 256, 0, 0, 2, NewXYZSource("raster", "http://example.com/{z}/{x}/{y}.png"))
+	defer tm.Close()
+	vector := tm.AddLayer(&fakeVectorSource{*NewXYZSource("vector", "http://example.com/{z}/{x}/{y}.pbf")})
+
+	raster := TileKey{Layer: tm.layers[0].id, Zoom: 2}
+	rendered := TileKey{Layer: vector.id, Zoom: 2}
+	tm.storeTile(raster, ebiten.NewImage(16, 16))
+	tm.storeTile(rendered, ebiten.NewImage(16, 16))
+
+	tm.SetVectorStyle(DarkVectorStyle())
+	if _, ok := tm.tileCache.peek(rendered); ok {
+		t.Error("vector tile kept after style change")
+	}
+	if _, ok := tm.tileCache.peek(raster); !ok {
+		t.Error("raster tile dropped after style change")
+	}
+}
+
+// denseSquare returns a square ring with n points per side, wound clockwise
+// on screen when reverse is false
+func denseSquare(x0, y0, size float32, n int, reverse bool) []fillPoint {
+	corners := []fillPoint{{x0, y0}, {x0 + size, y0}, {x0 + size, y0 + size}, {x0, y0 + size}}
+	var ring []fillPoint
+	for i := range corners {
+		a, b := corners[i], corners[(i+1)%len(corners)]
+		for j := range n {
+			t := float32(j) / float32(n)
+			ring = append(ring, fillPoint{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)})
+		}
+	}
+	if reverse {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+	return ring
+}
+
+// ringsArea sums the signed areas of rings
+func ringsArea(rings [][]fillPoint) float64 {
+	var area float64
+	for _, ring := range rings {
+		for i, p := range ring {
+			q := ring[(i+1)%len(ring)]
+			area += (float64(p.X)*float64(q.Y) - float64(q.X)*float64(p.Y)) / 2
+		}
+	}
+	return area
+}
+
+func TestSplitRings(t *testing.T) {
+	rings := [][]fillPoint{
+		denseSquare(0, 0, 256, 250, false),
+		denseSquare(64, 64, 128, 100, true),
+	}
+	want := ringsArea(rings)
+	if math.Abs(want-(256*256-128*128)) > 1 {
+		t.Fatalf("area of test polygon = %v", want)
+	}
+
+	const limit = 200
+	groups := splitRings(rings, limit, 0)
+	if len(groups) < 2 {
+		t.Fatalf("got %d groups, want the polygon split", len(groups))
+	}
+	var got float64
+	for i, group := range groups {
+		count := 0
+		for _, ring := range group {
+			count += len(ring)
+		}
+		if count > limit {
+			t.Errorf("group %d has %d points, want at most %d", i, count, limit)
+		}
+		got += ringsArea(group)
+	}
+	if math.Abs(got-want) > 1e-3*want {
+		t.Errorf("split area = %v, want %v", got, want)
+	}
+
+	if groups := splitRings(rings[:1], 2000, 0); len(groups) != 1 {
+		t.Errorf("small polygon split into %d groups", len(groups))
+	}
+}
@@ -0,0 +1,350 @@
+package tilemap
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/OpticalFlyer/goliath/proj"
+)
+
+// BBox is a geographic bounding box in degrees. MinLon may be greater than
+// MaxLon for boxes that cross the antimeridian.
+type BBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// Contains reports whether a point lies inside the box
+func (b BBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// intersectsTile reports whether the box overlaps a tile
+func (b BBox) intersectsTile(key TileKey) bool {
+	maxLat, minLon := proj.TileCoordsToLatLon(float64(key.X), float64(key.Y), key.Zoom)
+	minLat, maxLon := proj.TileCoordsToLatLon(float64(key.X+1), float64(key.Y+1), key.Zoom)
+	if minLat > b.MaxLat || maxLat < b.MinLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return minLon <= b.MaxLon && maxLon >= b.MinLon
+	}
+	return maxLon >= b.MinLon || minLon <= b.MaxLon
+}
+
+// worldRect returns the box in zoom 0 tile coordinates, where the world is a unit square.
+// For boxes crossing the antimeridian maxX is greater than 1.
+func (b BBox) worldRect() (minX, minY, maxX, maxY float64) {
+	minX, maxY = proj.LatLonToTileCoords(b.MinLat, b.MinLon, 0)
+	maxX, minY = proj.LatLonToTileCoords(b.MaxLat, b.MaxLon, 0)
+	if maxX < minX {
+		maxX++
+	}
+	return minX, minY, maxX, maxY
+}
+
+// TileRange returns the tiles at a zoom level that cover the box. For boxes
+// crossing the antimeridian MaxX extends past the edge of the world; use
+// WrapTileX to get real tile columns.
+func (b BBox) TileRange(zoom int) TileRange {
+	n := 1 << zoom
+	minX, minY := proj.LatLonToTileCoords(b.MaxLat, b.MinLon, zoom)
+	maxX, maxY := proj.LatLonToTileCoords(b.MinLat, b.MaxLon, zoom)
+
+	r := TileRange{
+		Zoom: zoom,
+		MinX: max(0, min(n-1, int(math.Floor(minX)))),
+		MaxX: max(0, min(n-1, int(math.Floor(maxX)))),
+		MinY: max(0, min(n-1, int(math.Floor(minY)))),
+		MaxY: max(0, min(n-1, int(math.Floor(maxY)))),
+	}
+	if b.MinLon > b.MaxLon {
+		r.MaxX += n
+	}
+	return r
+}
+
+// ParseBBox parses a box written as "minLon,minLat,maxLon,maxLat", the order
+// used by MBTiles and most web map tools
+func ParseBBox(s string) (BBox, error) {
+	v, ok := parseFloatList(s, 4)
+	if !ok {
+		return BBox{}, fmt.Errorf("invalid bounding box %q: want minLon,minLat,maxLon,maxLat", s)
+	}
+	b := BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat {
+		return BBox{}, fmt.Errorf("invalid bounding box %q: minLat is above maxLat", s)
+	}
+	return b, nil
+}
+
+// Viewport describes what part of the map is on screen: center, zoom, rotation
+// and screen size, plus optional limits on where the view may go.
+type Viewport struct {
+	CenterLat    float64
+	CenterLon    float64
+	Zoom         float64 // Fractional zoom level
+	Bearing      float64 // Compass direction at the top of the screen, degrees clockwise from north
+	ScreenWidth  int
+	ScreenHeight int
+
+	// Optional constraints, applied by Constrain
+	MaxBounds *BBox   // Area the view must stay inside; nil means unbounded
+	MinZoom   float64 // Lowest allowed zoom
+	MaxZoom   float64 // Highest allowed zoom; 0 means MaxZoomLevel
+}
+
+// WorldSize returns the width of the whole world in screen pixels at the current zoom
+func (v Viewport) WorldSize() float64 {
+	return TileSize * math.Exp2(v.Zoom)
+}
+
+// TileZoom returns the integer zoom level tiles are drawn from, the level nearest to Zoom
+func (v Viewport) TileZoom() int {
+	z := int(math.Round(v.Zoom))
+	return max(0, min(MaxZoomLevel, z))
+}
+
+// TileScale returns how much tiles from TileZoom are scaled to display the fractional Zoom
+func (v Viewport) TileScale() float64 {
+	return math.Exp2(v.Zoom - float64(v.TileZoom()))
+}
+
+// ScreenToWorld converts screen coordinates to tile coordinates at TileZoom.
+// The X coordinate is not wrapped and may fall outside [0, 2^TileZoom).
+func (v Viewport) ScreenToWorld(screenX, screenY float64) (tileX, tileY float64) {
+	// Get current center in tile coordinates
+	centerTileX, centerTileY := proj.LatLonToTileCoords(v.CenterLat, v.CenterLon, v.TileZoom())
+
+	// Convert screen coords to tile coords relative to center, undoing map rotation
+	pixelsToTiles := 1.0 / (TileSize * v.TileScale())
+	offsetX, offsetY := v.screenToMapOffset(screenX-float64(v.ScreenWidth)/2, screenY-float64(v.ScreenHeight)/2)
+	tileX = centerTileX + offsetX*pixelsToTiles
+	tileY = centerTileY + offsetY*pixelsToTiles
+
+	return tileX, tileY
+}
+
+// Project converts a geographic coordinate to screen pixels. With the world
+// wrapping horizontally, the copy of the point nearest the view center is used.
+func (v Viewport) Project(lat, lon float64) (screenX, screenY float64) {
+	return v.project(lat, UnwrapLon(lon, v.CenterLon))
+}
+
+// ProjectPath converts a line or ring of lat/lon points to screen pixels.
+// The path is kept continuous across the antimeridian and drawn on the copy of
+// the world nearest the view center.
+func (v Viewport) ProjectPath(lats, lons []float64) (xs, ys []float64) {
+	xs = make([]float64, len(lats))
+	ys = make([]float64, len(lats))
+	if len(lats) == 0 {
+		return xs, ys
+	}
+
+	lon := UnwrapLon(lons[0], v.CenterLon)
+	for i := range lats {
+		if i > 0 {
+			lon = UnwrapLon(lons[i], lon)
+		}
+		xs[i], ys[i] = v.project(lats[i], lon)
+	}
+	return xs, ys
+}
+
+// project converts to screen pixels without wrapping; lon may lie outside [-180, 180)
+func (v Viewport) project(lat, lon float64) (screenX, screenY float64) {
+	worldSize := v.WorldSize()
+	centerX, centerY := proj.LatLonToTileCoords(v.CenterLat, v.CenterLon, 0)
+	x, y := proj.LatLonToTileCoords(lat, lon, 0)
+
+	offsetX, offsetY := v.mapToScreenOffset((x-centerX)*worldSize, (y-centerY)*worldSize)
+	screenX = float64(v.ScreenWidth)/2 + offsetX
+	screenY = float64(v.ScreenHeight)/2 + offsetY
+	return screenX, screenY
+}
+
+// Unproject converts screen pixels to a geographic coordinate with the
+// longitude normalized to [-180, 180)
+func (v Viewport) Unproject(screenX, screenY float64) (lat, lon float64) {
+	lat, lon = v.unproject(screenX, screenY)
+	return lat, NormalizeLon(lon)
+}
+
+// unproject converts screen pixels to lat/lon, leaving the longitude continuous
+// with the view center
+func (v Viewport) unproject(screenX, screenY float64) (lat, lon float64) {
+	worldSize := v.WorldSize()
+	centerX, centerY := proj.LatLonToTileCoords(v.CenterLat, v.CenterLon, 0)
+	offsetX, offsetY := v.screenToMapOffset(screenX-float64(v.ScreenWidth)/2, screenY-float64(v.ScreenHeight)/2)
+
+	return proj.TileCoordsToLatLon(centerX+offsetX/worldSize, centerY+offsetY/worldSize, 0)
+}
+
+// Bounds returns the lat/lon box covering the visible screen, including any
+// parts hidden by rotation. When the view crosses the antimeridian MinLon is
+// below -180 or MaxLon above 180.
+func (v Viewport) Bounds() BBox {
+	return v.screenRectBounds(0, 0, float64(v.ScreenWidth), float64(v.ScreenHeight))
+}
+
+// RectBounds returns the lat/lon box covering a rectangle drawn on screen
+// between two corners. Longitudes are normalized, so a rectangle crossing the
+// antimeridian gives MinLon greater than MaxLon.
+func (v Viewport) RectBounds(x0, y0, x1, y1 float64) BBox {
+	b := v.screenRectBounds(x0, y0, x1, y1)
+	if b.MaxLon-b.MinLon >= 360 {
+		b.MinLon, b.MaxLon = -180, 180
+		return b
+	}
+	b.MinLon = NormalizeLon(b.MinLon)
+	b.MaxLon = NormalizeLon(b.MaxLon)
+	if b.MaxLon == -180 {
+		b.MaxLon = 180 // A right edge on the antimeridian
+	}
+	return b
+}
+
+// screenRectBounds returns the box around the four corners of a screen
+// rectangle, with longitudes continuous with the view center
+func (v Viewport) screenRectBounds(x0, y0, x1, y1 float64) BBox {
+	corners := [4][2]float64{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}}
+
+	bounds := BBox{
+		MinLat: math.Inf(1), MinLon: math.Inf(1),
+		MaxLat: math.Inf(-1), MaxLon: math.Inf(-1),
+	}
+	for _, c := range corners {
+		lat, lon := v.unproject(c[0], c[1])
+		bounds.MinLat = math.Min(bounds.MinLat, lat)
+		bounds.MaxLat = math.Max(bounds.MaxLat, lat)
+		bounds.MinLon = math.Min(bounds.MinLon, lon)
+		bounds.MaxLon = math.Max(bounds.MaxLon, lon)
+	}
+	return bounds
+}
+
+// ContainsLatLon reports whether a geographic point is on screen
+func (v Viewport) ContainsLatLon(lat, lon float64) bool {
+	x, y := v.Project(lat, lon)
+	return x >= 0 && x <= float64(v.ScreenWidth) && y >= 0 && y <= float64(v.ScreenHeight)
+}
+
+// FitBounds returns a viewport centered on the box and zoomed so that it fits
+// on screen with padding pixels to spare on every side
+func (v Viewport) FitBounds(bbox BBox, padding float64) Viewport {
+	minX, minY, maxX, maxY := bbox.worldRect()
+	width, height := maxX-minX, maxY-minY
+
+	// Size of the box once rotated onto the screen
+	sin, cos := math.Sincos(v.Bearing * math.Pi / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	rotatedWidth := width*cos + height*sin
+	rotatedHeight := width*sin + height*cos
+
+	availWidth := math.Max(1, float64(v.ScreenWidth)-2*padding)
+	availHeight := math.Max(1, float64(v.ScreenHeight)-2*padding)
+
+	if rotatedWidth > 0 || rotatedHeight > 0 {
+		worldSize := math.Min(availWidth/rotatedWidth, availHeight/rotatedHeight)
+		v.Zoom = math.Log2(worldSize / TileSize)
+	} else {
+		v.Zoom = v.maxZoom() // A single point
+	}
+
+	lat, lon := proj.TileCoordsToLatLon((minX+maxX)/2, (minY+maxY)/2, 0)
+	v.CenterLat = lat
+	v.CenterLon = NormalizeLon(lon)
+
+	return v.Constrain()
+}
+
+// Constrain returns the viewport with its zoom clamped to the zoom limits and its
+// center moved so the view stays inside MaxBounds. If the view is larger than
+// MaxBounds, it is centered on them.
+func (v Viewport) Constrain() Viewport {
+	v.Zoom = v.clampZoom(v.Zoom)
+	v.CenterLat = math.Max(-85.0511, math.Min(85.0511, v.CenterLat))
+	v.CenterLon = NormalizeLon(v.CenterLon)
+
+	if v.MaxBounds == nil {
+		return v
+	}
+
+	minX, minY, maxX, maxY := v.MaxBounds.worldRect()
+	extentWidth, extentHeight := v.viewExtent()
+	halfWidth := extentWidth / 2 / v.WorldSize()
+	halfHeight := extentHeight / 2 / v.WorldSize()
+
+	centerX, centerY := proj.LatLonToTileCoords(v.CenterLat, v.CenterLon, 0)
+	// Use the copy of the center closest to the bounds
+	if centerX < minX-0.5 {
+		centerX++
+	} else if centerX > maxX+0.5 {
+		centerX--
+	}
+	centerX = clampSpan(centerX, minX+halfWidth, maxX-halfWidth)
+	centerY = clampSpan(centerY, minY+halfHeight, maxY-halfHeight)
+
+	lat, lon := proj.TileCoordsToLatLon(centerX, centerY, 0)
+	v.CenterLat = lat
+	v.CenterLon = NormalizeLon(lon)
+	return v
+}
+
+// clampZoom limits a zoom level to the viewport's zoom limits
+func (v Viewport) clampZoom(zoom float64) float64 {
+	return math.Max(math.Max(0, v.MinZoom), math.Min(v.maxZoom(), zoom))
+}
+
+// maxZoom returns the effective maximum zoom level
+func (v Viewport) maxZoom() float64 {
+	if v.MaxZoom <= 0 || v.MaxZoom > MaxZoomLevel {
+		return MaxZoomLevel
+	}
+	return v.MaxZoom
+}
+
+// screenToMapOffset converts an offset from the screen center into an offset
+// in the unrotated, north-up map
+func (v Viewport) screenToMapOffset(dx, dy float64) (float64, float64) {
+	if v.Bearing == 0 {
+		return dx, dy
+	}
+	sin, cos := math.Sincos(v.Bearing * math.Pi / 180)
+	return dx*cos - dy*sin, dx*sin + dy*cos
+}
+
+// mapToScreenOffset converts an offset in the north-up map into an offset from
+// the screen center
+func (v Viewport) mapToScreenOffset(dx, dy float64) (float64, float64) {
+	if v.Bearing == 0 {
+		return dx, dy
+	}
+	sin, cos := math.Sincos(-v.Bearing * math.Pi / 180)
+	return dx*cos - dy*sin, dx*sin + dy*cos
+}
+
+// viewExtent returns the size of the north-up area that covers the rotated screen
+func (v Viewport) viewExtent() (width, height float64) {
+	w, h := float64(v.ScreenWidth), float64(v.ScreenHeight)
+	if v.Bearing == 0 {
+		return w, h
+	}
+	sin, cos := math.Sincos(v.Bearing * math.Pi / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	return w*cos + h*sin, w*sin + h*cos
+}
+
+// clampSpan clamps x to [lo, hi], or returns the midpoint if the span is empty
+func clampSpan(x, lo, hi float64) float64 {
+	if lo > hi {
+		return (lo + hi) / 2
+	}
+	return math.Max(lo, math.Min(hi, x))
+}
@@ -0,0 +1,339 @@
+package tilemap
+
+import (
+	"math"
+	"testing"
+)
+
+func TestViewportProjectRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		view     Viewport
+		lat, lon float64
+	}{
+		{
+			name: "Center",
+			view: Viewport{CenterLat: 40, CenterLon: -105, Zoom: 10, ScreenWidth: 800, ScreenHeight: 600},
+			lat:  40, lon: -105,
+		},
+		{
+			name: "Off center at fractional zoom",
+			view: Viewport{CenterLat: 40, CenterLon: -105, Zoom: 10.4, ScreenWidth: 800, ScreenHeight: 600},
+			lat:  40.1, lon: -105.2,
+		},
+		{
+			name: "Rotated",
+			view: Viewport{CenterLat: -33.9, CenterLon: 151.2, Zoom: 12, Bearing: 37, ScreenWidth: 1024, ScreenHeight: 768},
+			lat:  -33.85, lon: 151.25,
+		},
+		{
+			name: "Across the antimeridian",
+			view: Viewport{CenterLat: -17, CenterLon: 179.9, Zoom: 8, ScreenWidth: 800, ScreenHeight: 600},
+			lat:  -17.1, lon: -179.8,
+		},
+		{
+			name: "Rotated across the antimeridian",
+			view: Viewport{CenterLat: 65, CenterLon: -179.5, Zoom: 6, Bearing: -120, ScreenWidth: 800, ScreenHeight: 600},
+			lat:  64, lon: 178,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := tt.view.Project(tt.lat, tt.lon)
+			if !tt.view.ContainsLatLon(tt.lat, tt.lon) {
+				t.Errorf("point projects off screen to (%g, %g)", x, y)
+			}
+			lat, lon := tt.view.Unproject(x, y)
+			if math.Abs(lat-tt.lat) > 1e-9 || math.Abs(lon-tt.lon) > 1e-9 {
+				t.Errorf("Unproject(Project(%g, %g)) = %g, %g", tt.lat, tt.lon, lat, lon)
+			}
+		})
+	}
+}
+
+func TestViewportProjectCenter(t *testing.T) {
+	v := Viewport{CenterLat: 51.5, CenterLon: -0.1, Zoom: 14, Bearing: 90, ScreenWidth: 640, ScreenHeight: 480}
+	x, y := v.Project(v.CenterLat, v.CenterLon)
+	if math.Abs(x-320) > 1e-6 || math.Abs(y-240) > 1e-6 {
+		t.Errorf("center projects to (%g, %g); want (320, 240)", x, y)
+	}
+
+	// With the map turned so east is up, a point east of the center is above it
+	x, y = v.Project(v.CenterLat, v.CenterLon+0.01)
+	if math.Abs(x-320) > 1e-6 || y >= 240 {
+		t.Errorf("point east of center projects to (%g, %g); want above (320, 240)", x, y)
+	}
+}
+
+func TestViewportFitBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		view    Viewport
+		bbox    BBox
+		padding float64
+	}{
+		{
+			name: "City",
+			view: Viewport{ScreenWidth: 800, ScreenHeight: 600},
+			bbox: BBox{MinLat: 39.9, MinLon: -105.3, MaxLat: 40.1, MaxLon: -105.1},
+		},
+		{
+			name:    "Wide box with padding",
+			view:    Viewport{ScreenWidth: 800, ScreenHeight: 600},
+			bbox:    BBox{MinLat: 30, MinLon: -120, MaxLat: 45, MaxLon: -70},
+			padding: 50,
+		},
+		{
+			name: "Crossing the antimeridian",
+			view: Viewport{ScreenWidth: 800, ScreenHeight: 600},
+			bbox: BBox{MinLat: -20, MinLon: 170, MaxLat: -10, MaxLon: -170},
+		},
+		{
+			name:    "Rotated",
+			view:    Viewport{ScreenWidth: 800, ScreenHeight: 600, Bearing: 45},
+			bbox:    BBox{MinLat: 10, MinLon: 10, MaxLat: 20, MaxLon: 30},
+			padding: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.view.FitBounds(tt.bbox, tt.padding)
+
+			// Every corner is on screen, inside the padding
+			corners := [][2]float64{
+				{tt.bbox.MinLat, tt.bbox.MinLon}, {tt.bbox.MinLat, tt.bbox.MaxLon},
+				{tt.bbox.MaxLat, tt.bbox.MinLon}, {tt.bbox.MaxLat, tt.bbox.MaxLon},
+			}
+			var minX, minY, maxX, maxY = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+			for _, c := range corners {
+				x, y := v.Project(c[0], c[1])
+				minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+				minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+			}
+			const eps = 1e-6
+			if minX < tt.padding-eps || minY < tt.padding-eps ||
+				maxX > float64(v.ScreenWidth)-tt.padding+eps || maxY > float64(v.ScreenHeight)-tt.padding+eps {
+				t.Errorf("box projects to (%g, %g)-(%g, %g), outside the padded screen", minX, minY, maxX, maxY)
+			}
+
+			// The box touches the padding on at least one axis, so zooming in
+			// any further would clip it
+			touchesX := math.Abs(minX-tt.padding) < 1e-6 && math.Abs(maxX-(float64(v.ScreenWidth)-tt.padding)) < 1e-6
+			touchesY := math.Abs(minY-tt.padding) < 1e-6 && math.Abs(maxY-(float64(v.ScreenHeight)-tt.padding)) < 1e-6
+			if !touchesX && !touchesY {
+				t.Errorf("box projects to (%g, %g)-(%g, %g); want it to fill the padded screen on one axis", minX, minY, maxX, maxY)
+			}
+			if v.CenterLon < -180 || v.CenterLon >= 180 {
+				t.Errorf("center longitude %g not normalized", v.CenterLon)
+			}
+		})
+	}
+}
+
+func TestViewportFitBoundsPoint(t *testing.T) {
+	v := Viewport{ScreenWidth: 800, ScreenHeight: 600, MaxZoom: 17}
+	v = v.FitBounds(BBox{MinLat: 10, MinLon: 20, MaxLat: 10, MaxLon: 20}, 0)
+	if v.Zoom != 17 || math.Abs(v.CenterLat-10) > 1e-9 || math.Abs(v.CenterLon-20) > 1e-9 {
+		t.Errorf("fitting a point gives %g, %g at zoom %g; want 10, 20 at zoom 17", v.CenterLat, v.CenterLon, v.Zoom)
+	}
+}
+
+func TestViewportConstrain(t *testing.T) {
+	bounds := &BBox{MinLat: 39, MinLon: -106, MaxLat: 41, MaxLon: -104}
+	tests := []struct {
+		name     string
+		view     Viewport
+		wantZoom float64
+		inside   bool // The visible area must lie inside MaxBounds
+	}{
+		{
+			name:     "Zoom below MinZoom",
+			view:     Viewport{Zoom: 1, MinZoom: 3, ScreenWidth: 800, ScreenHeight: 600},
+			wantZoom: 3,
+		},
+		{
+			name:     "Zoom above MaxZoom",
+			view:     Viewport{Zoom: 19, MaxZoom: 16, ScreenWidth: 800, ScreenHeight: 600},
+			wantZoom: 16,
+		},
+		{
+			name:     "Zoom above MaxZoomLevel",
+			view:     Viewport{Zoom: 40, ScreenWidth: 800, ScreenHeight: 600},
+			wantZoom: MaxZoomLevel,
+		},
+		{
+			name:     "Negative zoom",
+			view:     Viewport{Zoom: -2, ScreenWidth: 800, ScreenHeight: 600},
+			wantZoom: 0,
+		},
+		{
+			name:     "Inside MaxBounds",
+			view:     Viewport{CenterLat: 40, CenterLon: -105, Zoom: 10, ScreenWidth: 800, ScreenHeight: 600, MaxBounds: bounds},
+			wantZoom: 10,
+			inside:   true,
+		},
+		{
+			name:     "Panned past MaxBounds",
+			view:     Viewport{CenterLat: 45, CenterLon: -100, Zoom: 10, ScreenWidth: 800, ScreenHeight: 600, MaxBounds: bounds},
+			wantZoom: 10,
+			inside:   true,
+		},
+		{
+			name:     "Panned past MaxBounds while rotated",
+			view:     Viewport{CenterLat: 38, CenterLon: -107, Zoom: 10, Bearing: 30, ScreenWidth: 800, ScreenHeight: 600, MaxBounds: bounds},
+			wantZoom: 10,
+			inside:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.view.Constrain()
+			if v.Zoom != tt.wantZoom {
+				t.Errorf("zoom = %g; want %g", v.Zoom, tt.wantZoom)
+			}
+			if !tt.inside {
+				return
+			}
+			b := v.Bounds()
+			const eps = 1e-9
+			if b.MinLat < bounds.MinLat-eps || b.MaxLat > bounds.MaxLat+eps || b.MinLon < bounds.MinLon-eps || b.MaxLon > bounds.MaxLon+eps {
+				t.Errorf("visible area %+v is outside MaxBounds %+v", b, *bounds)
+			}
+			if again := v.Constrain(); math.Abs(again.CenterLat-v.CenterLat) > 1e-9 || math.Abs(again.CenterLon-v.CenterLon) > 1e-9 {
+				t.Errorf("constraining again moves the center from %g, %g to %g, %g", v.CenterLat, v.CenterLon, again.CenterLat, again.CenterLon)
+			}
+		})
+	}
+}
+
+func TestViewportConstrainLargerThanBounds(t *testing.T) {
+	bounds := &BBox{MinLat: 39, MinLon: -106, MaxLat: 41, MaxLon: -104}
+	v := Viewport{CenterLat: 10, CenterLon: 10, Zoom: 3, ScreenWidth: 800, ScreenHeight: 600, MaxBounds: bounds}.Constrain()
+
+	// Centered on the bounds in Web Mercator
+	lat, lon := v.Unproject(400, 300)
+	wantLat, wantLon := Viewport{}.FitBounds(*bounds, 0).CenterLat, -105.0
+	if math.Abs(lat-wantLat) > 1e-9 || math.Abs(lon-wantLon) > 1e-9 {
+		t.Errorf("center = %g, %g; want %g, %g", lat, lon, wantLat, wantLon)
+	}
+}
+
+func TestViewportConstrainNormalizes(t *testing.T) {
+	v := Viewport{CenterLat: 89, CenterLon: 190, Zoom: 2, ScreenWidth: 800, ScreenHeight: 600}.Constrain()
+	if v.CenterLat != 85.0511 || math.Abs(v.CenterLon - -170) > 1e-9 {
+		t.Errorf("center = %g, %g; want 85.0511, -170", v.CenterLat, v.CenterLon)
+	}
+}
+
+func TestBBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		lat, lon float64
+		contains bool
+		zoom     int
+		want     TileRange
+	}{
+		{
+			name: "Northern hemisphere", s: "-105.3,39.9,-105.1,40.1",
+			lat: 40, lon: -105.2, contains: true,
+			zoom: 10, want: TileRange{Zoom: 10, MinX: 212, MaxX: 213, MinY: 387, MaxY: 388},
+		},
+		{
+			name: "Point outside", s: "-105.3,39.9,-105.1,40.1",
+			lat: 40, lon: -104, contains: false,
+			zoom: 0, want: TileRange{Zoom: 0},
+		},
+		{
+			name: "Crossing the antimeridian", s: "170,-20,-170,-10",
+			lat: -15, lon: 179.5, contains: true,
+			zoom: 2, want: TileRange{Zoom: 2, MinX: 3, MaxX: 4, MinY: 2, MaxY: 2},
+		},
+		{
+			name: "Outside a box crossing the antimeridian", s: "170,-20,-170,-10",
+			lat: -15, lon: 0, contains: false,
+			zoom: 1, want: TileRange{Zoom: 1, MinX: 1, MaxX: 2, MinY: 1, MaxY: 1},
+		},
+		{
+			name: "Whole world", s: "-180,-85.0511,180,85.0511",
+			lat: 0, lon: 0, contains: true,
+			zoom: 2, want: TileRange{Zoom: 2, MinX: 0, MaxX: 3, MinY: 0, MaxY: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseBBox(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Contains(tt.lat, tt.lon); got != tt.contains {
+				t.Errorf("Contains(%g, %g) = %v; want %v", tt.lat, tt.lon, got, tt.contains)
+			}
+			if got := b.TileRange(tt.zoom); got != tt.want {
+				t.Errorf("TileRange(%d) = %+v; want %+v", tt.zoom, got, tt.want)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "1,2,3", "a,b,c,d", "0,10,1,5"} {
+		if _, err := ParseBBox(s); err == nil {
+			t.Errorf("ParseBBox(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSourceCovers(t *testing.T) {
+	denver := &BBox{MinLon: -105.3, MinLat: 39.9, MaxLon: -105.1, MaxLat: 40.1}
+	fiji := &BBox{MinLon: 170, MinLat: -20, MaxLon: -170, MaxLat: -10}
+
+	tests := []struct {
+		name   string
+		bounds *BBox
+		key    TileKey
+		want   bool
+	}{
+		{name: "No bounds", bounds: nil, key: TileKey{Zoom: 10, X: 5, Y: 5}, want: true},
+		{name: "Whole world tile", bounds: denver, key: TileKey{Zoom: 0}, want: true},
+		{name: "Inside", bounds: denver, key: TileKey{Zoom: 10, X: 212, Y: 387}, want: true},
+		{name: "Overlapping the corner", bounds: denver, key: TileKey{Zoom: 10, X: 213, Y: 388}, want: true},
+		{name: "East of the box", bounds: denver, key: TileKey{Zoom: 10, X: 214, Y: 387}, want: false},
+		{name: "South of the box", bounds: denver, key: TileKey{Zoom: 10, X: 212, Y: 389}, want: false},
+		{name: "West of the antimeridian", bounds: fiji, key: TileKey{Zoom: 2, X: 3, Y: 2}, want: true},
+		{name: "East of the antimeridian", bounds: fiji, key: TileKey{Zoom: 2, X: 0, Y: 2}, want: true},
+		{name: "Between the ends of a box crossing the antimeridian", bounds: fiji, key: TileKey{Zoom: 2, X: 1, Y: 2}, want: false},
+		{name: "North of a box crossing the antimeridian", bounds: fiji, key: TileKey{Zoom: 2, X: 3, Y: 1}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &MBTilesSource{Metadata: MBTilesMetadata{Bounds: tt.bounds}}
+			if got := sourceCovers(src, tt.key); got != tt.want {
+				t.Errorf("sourceCovers(%v) = %v; want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetNorthConstrains(t *testing.T) {
+	// A wide screen turned sideways shows little longitude, so the view can
+	// sit near the east edge of the bounds until it turns back north-up
+	tm := New(800, 200, 40, -104.6, 11, NewXYZSource("test", "http://example.com/{z}/{x}/{y}.png"))
+	defer tm.Close()
+	tm.MaxBounds = &BBox{MinLat: 39.5, MinLon: -105.5, MaxLat: 40.5, MaxLon: -104.5}
+	tm.SetBearing(90)
+	if math.Abs(tm.CenterLon - -104.6) > 1e-6 {
+		t.Fatalf("rotated view moved to %g", tm.CenterLon)
+	}
+
+	tm.ResetNorth()
+	if tm.Bearing != 0 {
+		t.Errorf("bearing = %g; want 0", tm.Bearing)
+	}
+	if _, east := tm.Unproject(800, 100); east > tm.MaxBounds.MaxLon+1e-9 {
+		t.Errorf("north-up view reaches %g, past the bounds", east)
+	}
+}
@@ -0,0 +1,205 @@
+package tilemap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/OpticalFlyer/goliath/proj"
+	"github.com/OpticalFlyer/goliath/wms"
+)
+
+// WMSSource fetches tiles from an OGC Web Map Service with one GetMap request
+// per tile. Tiles are requested in EPSG:3857 so they line up with the basemap.
+// Servers without Web Mercator can use EPSG:4326, which stretches each tile
+// slightly in latitude; the error is only visible at low zoom levels.
+type WMSSource struct {
+	ID string
+
+	// Request holds the service URL, version, layers, styles, format,
+	// transparency and CRS. The bounding box and size are set per tile.
+	Request wms.GetMap
+
+	// TilePixels is the width and height of the requested images, such as
+	// 512 for HiDPI screens; 0 means TileSize
+	TilePixels int
+
+	UserAgent string
+	Headers   map[string]string
+
+	MinZoomLevel int
+	MaxZoomLevel int
+
+	// Credit is shown on the map while the source is drawn
+	Credit Attribution
+
+	Client *http.Client
+}
+
+var _ AttributedSource = (*WMSSource)(nil)
+
+// NewWMSSource creates a source drawing the given layers as transparent PNG tiles
+func NewWMSSource(id, baseURL string, layers ...string) *WMSSource {
+	return &WMSSource{
+		ID: id,
+		Request: wms.GetMap{
+			BaseURL:     baseURL,
+			Version:     wms.Version130,
+			Layers:      layers,
+			Format:      "image/png",
+			Transparent: true,
+			CRS:         "EPSG:3857",
+		},
+		UserAgent:    DefaultUserAgent,
+		MinZoomLevel: 0,
+		MaxZoomLevel: MaxZoomLevel,
+		Client:       &http.Client{},
+	}
+}
+
+// wmsCRSs are the CRSs tiles can be requested in, in order of preference.
+// EPSG:900913 is an older name for Web Mercator.
+var wmsCRSs = []string{"EPSG:3857", "EPSG:900913", "EPSG:4326"}
+
+// NewWMSSourceFromCapabilities creates a source for layers listed in a
+// service's capabilities, using its GetMap endpoint, version and a supported
+// image format. The CRS is the most preferred one every layer supports,
+// counting those inherited from parent layers. baseURL is the service URL the
+// capabilities were fetched from, used when they list no GetMap endpoint.
+func NewWMSSourceFromCapabilities(id, baseURL string, caps *wms.Capabilities, layers ...string) (*WMSSource, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("no WMS layers given")
+	}
+	crsOK := make([]bool, len(wmsCRSs))
+	for i := range crsOK {
+		crsOK[i] = true
+	}
+	var credit Attribution
+	for _, name := range layers {
+		layer := caps.Layer(name)
+		if layer == nil {
+			return nil, fmt.Errorf("WMS layer %q not found in capabilities", name)
+		}
+		if credit.Text == "" && layer.AttributionTitle != "" {
+			credit = Attribution{Text: layer.AttributionTitle, URL: layer.AttributionURL}
+		}
+		for i, crs := range wmsCRSs {
+			crsOK[i] = crsOK[i] && layer.SupportsCRS(crs)
+		}
+	}
+	i := slices.Index(crsOK, true)
+	if i < 0 {
+		return nil, fmt.Errorf("WMS layers %s have no CRS in common among %s",
+			strings.Join(layers, ", "), strings.Join(wmsCRSs, ", "))
+	}
+	crs := wmsCRSs[i]
+
+	getMapURL := caps.GetMapURL
+	if getMapURL == "" {
+		getMapURL = baseURL
+	}
+	src := NewWMSSource(id, getMapURL, layers...)
+	src.Request.CRS = crs
+	src.Credit = credit
+	if caps.Version == wms.Version111 {
+		src.Request.Version = wms.Version111
+	}
+	if format := caps.PreferredFormat("image/png", "image/png8", "image/jpeg"); format != "" {
+		src.Request.Format = format
+	}
+	return src, nil
+}
+
+// Name implements TileSource
+func (s *WMSSource) Name() string {
+	return tileSourceName(s.ID, s.TilePixels)
+}
+
+// MinZoom implements TileSource
+func (s *WMSSource) MinZoom() int {
+	return s.MinZoomLevel
+}
+
+// MaxZoom implements TileSource
+func (s *WMSSource) MaxZoom() int {
+	return s.MaxZoomLevel
+}
+
+// Attribution implements AttributedSource
+func (s *WMSSource) Attribution() Attribution {
+	return s.Credit
+}
+
+// TileURL returns the GetMap URL covering a tile
+func (s *WMSSource) TileURL(key TileKey) (string, error) {
+	north, west := proj.TileCoordsToLatLon(float64(key.X), float64(key.Y), key.Zoom)
+	south, east := proj.TileCoordsToLatLon(float64(key.X+1), float64(key.Y+1), key.Zoom)
+
+	req := s.Request
+	req.Width, req.Height = TileSize, TileSize
+	if s.TilePixels > 0 {
+		req.Width, req.Height = s.TilePixels, s.TilePixels
+	}
+	if strings.EqualFold(req.CRS, "EPSG:4326") {
+		req.MinX, req.MinY, req.MaxX, req.MaxY = west, south, east, north
+	} else {
+		req.MinX, req.MinY = proj.LatLonToWebMercator(south, west)
+		req.MaxX, req.MaxY = proj.LatLonToWebMercator(north, east)
+	}
+	return req.URL()
+}
+
+// FetchTile implements TileSource
+func (s *WMSSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	if err := checkTileKey(s, key); err != nil {
+		return nil, err
+	}
+	tileURL, err := s.TileURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s failed: %w", tileURL, err)
+	}
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s failed: %w", tileURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch tile %s: %s", tileURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading tile %s failed: %w", tileURL, err)
+	}
+
+	// Errors come back as XML with a 200 status
+	if strings.Contains(resp.Header.Get("Content-Type"), "xml") {
+		if msg, ok := wms.ParseServiceException(data); ok {
+			return nil, fmt.Errorf("WMS error for tile %d/%d/%d: %s", key.Zoom, key.X, key.Y, msg)
+		}
+		return nil, fmt.Errorf("WMS returned %s instead of an image for tile %d/%d/%d",
+			resp.Header.Get("Content-Type"), key.Zoom, key.X, key.Y)
+	}
+	return data, nil
+}
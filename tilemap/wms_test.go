@@ -0,0 +1,122 @@
+package tilemap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpticalFlyer/goliath/wms"
+)
+
+const wmsCapabilities = `<?xml version="1.0" encoding="UTF-8"?>
+<WMS_Capabilities version="1.3.0" xmlns="http://www.opengis.net/wms" xmlns:xlink="http://www.w3.org/1999/xlink">
+  <Service><Name>WMS</Name><Title>County GIS</Title></Service>
+  <Capability>
+    <Request>
+      <GetMap>
+        <Format>image/jpeg</Format>
+        <Format>image/png</Format>
+        <DCPType><HTTP><Get><OnlineResource xlink:type="simple" xlink:href="https://gis.example.com/wms?"/></Get></HTTP></DCPType>
+      </GetMap>
+    </Request>
+    <Layer>
+      <Title>County</Title>
+      <CRS>EPSG:4326</CRS>
+      <Layer>
+        <Name>roads</Name>
+        <Title>Roads</Title>
+        <CRS>EPSG:3857</CRS>
+      </Layer>
+      <Layer>
+        <Name>parcels</Name>
+        <Title>Parcels</Title>
+        <CRS>EPSG:900913</CRS>
+      </Layer>
+      <Layer>
+        <Name>zoning</Name>
+        <Title>Zoning</Title>
+        <CRS>EPSG:900913</CRS>
+        <CRS>EPSG:3857</CRS>
+      </Layer>
+    </Layer>
+    <Layer>
+      <Name>survey</Name>
+      <Title>Survey</Title>
+      <CRS>EPSG:26913</CRS>
+    </Layer>
+  </Capability>
+</WMS_Capabilities>`
+
+func TestNewWMSSourceFromCapabilities(t *testing.T) {
+	caps, err := wms.ParseCapabilities(strings.NewReader(wmsCapabilities))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		layers  []string
+		wantCRS string // Empty if an error is expected
+	}{
+		{name: "Web Mercator", layers: []string{"roads"}, wantCRS: "EPSG:3857"},
+		{name: "Old Web Mercator name", layers: []string{"parcels"}, wantCRS: "EPSG:900913"},
+		{name: "Current name preferred", layers: []string{"zoning"}, wantCRS: "EPSG:3857"},
+		{name: "Shared old name", layers: []string{"zoning", "parcels"}, wantCRS: "EPSG:900913"},
+		{name: "Only the inherited CRS in common", layers: []string{"roads", "parcels"}, wantCRS: "EPSG:4326"},
+		{name: "No CRS in common", layers: []string{"roads", "survey"}},
+		{name: "No usable CRS", layers: []string{"survey"}},
+		{name: "Unknown layer", layers: []string{"roads", "wells"}},
+		{name: "No layers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := NewWMSSourceFromCapabilities("county", "https://gis.example.com/capabilities", caps, tt.layers...)
+			if tt.wantCRS == "" {
+				if err == nil {
+					t.Errorf("got CRS %s; want an error", src.Request.CRS)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if src.Request.CRS != tt.wantCRS {
+				t.Errorf("CRS = %s; want %s", src.Request.CRS, tt.wantCRS)
+			}
+			if src.Request.Format != "image/png" {
+				t.Errorf("format = %s; want image/png", src.Request.Format)
+			}
+			if src.Request.BaseURL != "https://gis.example.com/wms?" {
+				t.Errorf("base URL = %s; want the GetMap endpoint", src.Request.BaseURL)
+			}
+		})
+	}
+}
+
+func TestNewWMSSourceFromCapabilitiesWithoutGetMapURL(t *testing.T) {
+	noEndpoint := strings.Replace(wmsCapabilities,
+		`<DCPType><HTTP><Get><OnlineResource xlink:type="simple" xlink:href="https://gis.example.com/wms?"/></Get></HTTP></DCPType>`, "", 1)
+	caps, err := wms.ParseCapabilities(strings.NewReader(noEndpoint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.GetMapURL != "" {
+		t.Fatalf("GetMapURL = %q; want none", caps.GetMapURL)
+	}
+
+	const baseURL = "https://gis.example.com/arcgis/services/County/MapServer/WMSServer"
+	src, err := NewWMSSourceFromCapabilities("county", baseURL, caps, "roads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Request.BaseURL != baseURL {
+		t.Errorf("base URL = %q; want %q", src.Request.BaseURL, baseURL)
+	}
+	tileURL, err := src.TileURL(TileKey{Zoom: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(tileURL, baseURL+"?") {
+		t.Errorf("tile URL %s doesn't use the service URL", tileURL)
+	}
+}
@@ -0,0 +1,200 @@
+package tilemap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/OpticalFlyer/goliath/wmts"
+)
+
+// WMTSSource fetches tiles from an OGC Web Map Tile Service layer whose tile
+// matrix set lines up with the Web Mercator grid, so tile rows and columns
+// are the same as slippy map y and x.
+type WMTSSource struct {
+	ID string
+
+	// Request holds the layer, style, format, tile matrix set and dimension
+	// values. The tile matrix, row and column are set per tile.
+	Request wmts.GetTile
+
+	// Template is the RESTful tile URL template. When it is empty, tiles are
+	// requested from the KVP endpoint at BaseURL.
+	Template string
+	BaseURL  string
+
+	// Matrices maps zoom levels to tile matrix identifiers
+	Matrices map[int]string
+	// Limits holds the tile range the layer covers at each zoom level, for
+	// layers that don't cover the whole world
+	Limits map[int]wmts.TileMatrixLimits
+
+	UserAgent string
+	Headers   map[string]string
+
+	MinZoomLevel int
+	MaxZoomLevel int
+
+	// Credit is shown on the map while the source is drawn
+	Credit Attribution
+
+	Client *http.Client
+}
+
+var (
+	_ ConditionalSource = (*WMTSSource)(nil)
+	_ AttributedSource  = (*WMTSSource)(nil)
+)
+
+// NewWMTSSource creates a source for a layer listed in a service's
+// capabilities. An empty format picks PNG or JPEG if the layer offers them.
+func NewWMTSSource(id string, caps *wmts.Capabilities, layerID, format string) (*WMTSSource, error) {
+	layer := caps.Layer(layerID)
+	if layer == nil {
+		return nil, fmt.Errorf("WMTS layer %q not found in capabilities", layerID)
+	}
+	set, link, ok := caps.WebMercatorSet(layer)
+	if !ok {
+		return nil, fmt.Errorf("WMTS layer %q has no tile matrix set compatible with Web Mercator", layerID)
+	}
+
+	if format == "" {
+		format = preferredWMTSFormat(layer.Formats)
+	}
+	s := &WMTSSource{
+		ID: id,
+		Request: wmts.GetTile{
+			Layer:         layer.Identifier,
+			Style:         layer.DefaultStyle(),
+			Format:        format,
+			TileMatrixSet: set.Identifier,
+			Dimensions:    make(map[string]string),
+		},
+		Template:     layer.TileTemplate(format),
+		BaseURL:      caps.GetTileURL,
+		Matrices:     make(map[int]string),
+		Limits:       make(map[int]wmts.TileMatrixLimits),
+		UserAgent:    DefaultUserAgent,
+		MinZoomLevel: MaxZoomLevel,
+		MaxZoomLevel: 0,
+		Credit:       Attribution{Text: caps.Provider, URL: caps.ProviderSite},
+		Client:       &http.Client{},
+	}
+	if s.Template == "" && s.BaseURL == "" {
+		return nil, fmt.Errorf("WMTS layer %q has no tile URL for %s", layerID, format)
+	}
+	for _, dim := range layer.Dimensions {
+		s.Request.Dimensions[dim.Identifier] = dim.Default
+	}
+
+	for zoom, matrix := range set.WebMercatorZooms() {
+		if zoom > MaxZoomLevel {
+			continue
+		}
+		s.Matrices[zoom] = matrix.Identifier
+		if lim, ok := link.MatrixLimits(matrix.Identifier); ok {
+			s.Limits[zoom] = lim
+		}
+		if zoom < s.MinZoomLevel {
+			s.MinZoomLevel = zoom
+		}
+		if zoom > s.MaxZoomLevel {
+			s.MaxZoomLevel = zoom
+		}
+	}
+	if len(s.Matrices) == 0 {
+		return nil, fmt.Errorf("WMTS layer %q has no tile matrices up to zoom %d", layerID, MaxZoomLevel)
+	}
+	return s, nil
+}
+
+// preferredWMTSFormat picks PNG, then JPEG, then whatever comes first
+func preferredWMTSFormat(formats []string) string {
+	for _, want := range []string{"image/png", "image/jpeg"} {
+		for _, f := range formats {
+			if f == want {
+				return f
+			}
+		}
+	}
+	if len(formats) > 0 {
+		return formats[0]
+	}
+	return "image/png"
+}
+
+// Name implements TileSource
+func (s *WMTSSource) Name() string {
+	return s.ID
+}
+
+// MinZoom implements TileSource
+func (s *WMTSSource) MinZoom() int {
+	return s.MinZoomLevel
+}
+
+// MaxZoom implements TileSource
+func (s *WMTSSource) MaxZoom() int {
+	return s.MaxZoomLevel
+}
+
+// Attribution implements AttributedSource
+func (s *WMTSSource) Attribution() Attribution {
+	return s.Credit
+}
+
+// TileURL returns the GetTile URL for a tile. Tiles at zooms without a matrix
+// or outside the layer's limits wrap ErrTileNotFound.
+func (s *WMTSSource) TileURL(key TileKey) (string, error) {
+	matrix, ok := s.Matrices[key.Zoom]
+	if !ok {
+		return "", fmt.Errorf("no tile matrix for zoom %d in source %s: %w", key.Zoom, s.ID, ErrTileNotFound)
+	}
+	if lim, ok := s.Limits[key.Zoom]; ok {
+		if key.Y < lim.MinTileRow || key.Y > lim.MaxTileRow || key.X < lim.MinTileCol || key.X > lim.MaxTileCol {
+			return "", fmt.Errorf("tile %d/%d/%d outside the extent of source %s: %w", key.Zoom, key.X, key.Y, s.ID, ErrTileNotFound)
+		}
+	}
+
+	req := s.Request
+	req.TileMatrix = matrix
+	req.TileRow, req.TileCol = key.Y, key.X
+	if s.Template != "" {
+		return req.RESTURL(s.Template), nil
+	}
+	return req.KVPURL(s.BaseURL)
+}
+
+// FetchTile implements TileSource
+func (s *WMTSSource) FetchTile(ctx context.Context, key TileKey) ([]byte, error) {
+	tile, _, err := s.FetchTileConditional(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tile.Data, nil
+}
+
+// FetchTileConditional implements ConditionalSource
+func (s *WMTSSource) FetchTileConditional(ctx context.Context, key TileKey, cached *CachedTile) (*CachedTile, bool, error) {
+	if err := checkTileKey(s, key); err != nil {
+		return nil, false, err
+	}
+	tileURL, err := s.TileURL(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tileURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("creating request for %s failed: %w", tileURL, err)
+	}
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+	return fetchConditional(s.Client, req, cached)
+}
@@ -0,0 +1,125 @@
+package tilemap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpticalFlyer/goliath/wmts"
+)
+
+const wmtsCapabilities = `<?xml version="1.0" encoding="UTF-8"?>
+<Capabilities xmlns="http://www.opengis.net/wmts/1.0" xmlns:ows="http://www.opengis.net/ows/1.1" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.0.0">
+  <Contents>
+    <Layer>
+      <ows:Identifier>ortho</ows:Identifier>
+      <Format>image/png</Format>
+      <TileMatrixSetLink>
+        <TileMatrixSet>GoogleMapsCompatible</TileMatrixSet>
+        <TileMatrixSetLimits>
+          <TileMatrixLimits>
+            <TileMatrix>2</TileMatrix>
+            <MinTileRow>1</MinTileRow><MaxTileRow>1</MaxTileRow>
+            <MinTileCol>0</MinTileCol><MaxTileCol>1</MaxTileCol>
+          </TileMatrixLimits>
+        </TileMatrixSetLimits>
+      </TileMatrixSetLink>
+      <ResourceURL format="image/png" resourceType="tile"
+        template="https://gis.example.gov/rest/ortho/{TileMatrix}/{TileRow}/{TileCol}.png"/>
+    </Layer>
+    <Layer>
+      <ows:Identifier>detail</ows:Identifier>
+      <Format>image/png</Format>
+      <TileMatrixSetLink><TileMatrixSet>Detail</TileMatrixSet></TileMatrixSetLink>
+      <ResourceURL format="image/png" resourceType="tile"
+        template="https://gis.example.gov/rest/detail/{TileMatrix}/{TileRow}/{TileCol}.png"/>
+    </Layer>
+    <TileMatrixSet>
+      <ows:Identifier>GoogleMapsCompatible</ows:Identifier>
+      <ows:SupportedCRS>urn:ogc:def:crs:EPSG::3857</ows:SupportedCRS>
+      <TileMatrix>
+        <ows:Identifier>0</ows:Identifier>
+        <ScaleDenominator>559082264.0287178</ScaleDenominator>
+        <TopLeftCorner>-20037508.3427892 20037508.3427892</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>1</MatrixWidth><MatrixHeight>1</MatrixHeight>
+      </TileMatrix>
+      <TileMatrix>
+        <ows:Identifier>2</ows:Identifier>
+        <ScaleDenominator>139770566.0071794</ScaleDenominator>
+        <TopLeftCorner>-20037508.3427892 20037508.3427892</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>4</MatrixWidth><MatrixHeight>4</MatrixHeight>
+      </TileMatrix>
+    </TileMatrixSet>
+    <TileMatrixSet>
+      <ows:Identifier>Detail</ows:Identifier>
+      <ows:SupportedCRS>urn:ogc:def:crs:EPSG::3857</ows:SupportedCRS>
+      <TileMatrix>
+        <ows:Identifier>20</ows:Identifier>
+        <ScaleDenominator>533.182395962446</ScaleDenominator>
+        <TopLeftCorner>-20037508.3427892 20037508.3427892</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>4</MatrixWidth><MatrixHeight>4</MatrixHeight>
+      </TileMatrix>
+    </TileMatrixSet>
+  </Contents>
+</Capabilities>`
+
+func TestWMTSSourceTileURL(t *testing.T) {
+	caps, err := wmts.ParseCapabilities(strings.NewReader(wmtsCapabilities))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := NewWMTSSource("ortho", caps, "ortho", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.MinZoom() != 0 || src.MaxZoom() != 2 {
+		t.Errorf("zoom range = %d-%d; want 0-2", src.MinZoom(), src.MaxZoom())
+	}
+
+	tests := []struct {
+		name string
+		key  TileKey
+		want string // Empty if the tile should be missing
+	}{
+		{name: "World", key: TileKey{Zoom: 0}, want: "https://gis.example.gov/rest/ortho/0/0/0.png"},
+		{name: "Inside limits", key: TileKey{Zoom: 2, X: 1, Y: 1}, want: "https://gis.example.gov/rest/ortho/2/1/1.png"},
+		{name: "Outside limits", key: TileKey{Zoom: 2, X: 2, Y: 1}},
+		{name: "No matrix", key: TileKey{Zoom: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := src.TileURL(tt.key)
+			if tt.want == "" {
+				if !errors.Is(err, ErrTileNotFound) {
+					t.Errorf("got %q, %v; want ErrTileNotFound", got, err)
+				}
+				// Missing tiles fail before any request is made
+				if _, err := src.FetchTile(context.Background(), tt.key); !errors.Is(err, ErrTileNotFound) {
+					t.Errorf("FetchTile error = %v; want ErrTileNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TileURL = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWMTSSourceBeyondMaxZoom(t *testing.T) {
+	caps, err := wmts.ParseCapabilities(strings.NewReader(wmtsCapabilities))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src, err := NewWMTSSource("detail", caps, "detail", ""); err == nil {
+		t.Errorf("got zoom range %d-%d; want an error", src.MinZoom(), src.MaxZoom())
+	}
+}
@@ -0,0 +1,25 @@
+package tilemap
+
+import "math"
+
+// WrapTileX wraps a tile X coordinate into [0, 2^zoom) so tiles repeat horizontally
+func WrapTileX(x, zoom int) int {
+	n := 1 << zoom
+	return ((x % n) + n) % n
+}
+
+// NormalizeLon wraps a longitude into [-180, 180)
+func NormalizeLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+// UnwrapLon shifts lon by whole turns so it lies within 180° of refLon.
+// Unwrapping each vertex against the previous one keeps lines that cross
+// the antimeridian continuous.
+func UnwrapLon(lon, refLon float64) float64 {
+	return refLon + NormalizeLon(lon-refLon)
+}
@@ -0,0 +1,80 @@
+package tilemap
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWrapTileX(t *testing.T) {
+	tests := []struct {
+		name string
+		x    int
+		zoom int
+		want int
+	}{
+		{name: "Zoom 0", x: 0, zoom: 0, want: 0},
+		{name: "Zoom 0 east copy", x: 3, zoom: 0, want: 0},
+		{name: "Inside the world", x: 5, zoom: 3, want: 5},
+		{name: "Last column", x: 7, zoom: 3, want: 7},
+		{name: "First column east of the antimeridian", x: 8, zoom: 3, want: 0},
+		{name: "Last column west of the antimeridian", x: -1, zoom: 3, want: 7},
+		{name: "Two worlds west", x: -16, zoom: 3, want: 0},
+		{name: "Two worlds east", x: 17, zoom: 3, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapTileX(tt.x, tt.zoom); got != tt.want {
+				t.Errorf("WrapTileX(%d, %d) = %d; want %d", tt.x, tt.zoom, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLon(t *testing.T) {
+	tests := []struct {
+		name string
+		lon  float64
+		want float64
+	}{
+		{name: "Prime meridian", lon: 0, want: 0},
+		{name: "Inside the range", lon: 123.5, want: 123.5},
+		{name: "West antimeridian", lon: -180, want: -180},
+		{name: "East antimeridian", lon: 180, want: -180},
+		{name: "Just east of the antimeridian", lon: 180.5, want: -179.5},
+		{name: "Just west of the antimeridian", lon: -180.5, want: 179.5},
+		{name: "One turn", lon: 360, want: 0},
+		{name: "Several turns west", lon: -725, want: -5},
+		{name: "Several turns east", lon: 900, want: -180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLon(tt.lon); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("NormalizeLon(%g) = %g; want %g", tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapLon(t *testing.T) {
+	tests := []struct {
+		name        string
+		lon, refLon float64
+		want        float64
+	}{
+		{name: "Same side", lon: 10, refLon: 20, want: 10},
+		{name: "Across the antimeridian eastward", lon: -179, refLon: 179, want: 181},
+		{name: "Across the antimeridian westward", lon: 179, refLon: -179, want: -181},
+		{name: "Reference off the world", lon: -170, refLon: 540, want: 550},
+		{name: "Half a turn away", lon: 0, refLon: 180, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnwrapLon(tt.lon, tt.refLon); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("UnwrapLon(%g, %g) = %g; want %g", tt.lon, tt.refLon, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,83 +2,143 @@ package tilemap
 
 import (
 	"math"
+	"time"
 
 	"github.com/OpticalFlyer/goliath/proj"
 )
 
-// ZoomIn increases the zoom level if not at max zoom
+// DefaultZoomDuration is the default length of animated zoom transitions
+const DefaultZoomDuration = 250 * time.Millisecond
+
+// zoomAnimation tracks an in-progress eased zoom anchored at a screen point
+type zoomAnimation struct {
+	active           bool
+	from, to         float64
+	anchorX, anchorY float64
+	start            time.Time
+}
+
+// ZoomIn animates the zoom in by one level around the screen center
 func (tm *TileMap) ZoomIn() {
-	if tm.Zoom < MaxZoomLevel {
-		tm.Zoom++
-	}
+	tm.ZoomAtPoint(true, float64(tm.ScreenWidth)/2, float64(tm.ScreenHeight)/2)
 }
 
-// ZoomOut decreases the zoom level if not at minimum zoom
+// ZoomOut animates the zoom out by one level around the screen center
 func (tm *TileMap) ZoomOut() {
-	if tm.Zoom > 0 {
-		tm.Zoom--
-	}
+	tm.ZoomAtPoint(false, float64(tm.ScreenWidth)/2, float64(tm.ScreenHeight)/2)
 }
 
-// ScreenToWorld converts screen coordinates to tile coordinates
-func (tm *TileMap) ScreenToWorld(screenX, screenY float64) (tileX, tileY float64) {
-	// Get current center in tile coordinates
-	centerTileX, centerTileY := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, tm.Zoom)
+// ZoomAtPoint animates a zoom of one whole level while keeping the given world
+// point at the same screen location
+func (tm *TileMap) ZoomAtPoint(zoomIn bool, screenX, screenY float64) {
+	tm.StopKinetic()
+	target := tm.targetZoom()
+	if zoomIn {
+		target = math.Floor(target + 1)
+	} else {
+		target = math.Ceil(target - 1)
+	}
+	tm.animateZoom(target, screenX, screenY)
+}
 
-	// Convert screen coords to tile coords relative to center
-	pixelsToTiles := 1.0 / TileSize
-	tileX = centerTileX + (screenX-float64(tm.ScreenWidth)/2)*pixelsToTiles
-	tileY = centerTileY + (screenY-float64(tm.ScreenHeight)/2)*pixelsToTiles
+// ZoomBy animates a zoom by a fractional number of levels anchored at a screen point.
+// Successive calls accumulate, so small trackpad wheel deltas zoom proportionally.
+func (tm *TileMap) ZoomBy(delta, screenX, screenY float64) {
+	tm.StopKinetic()
+	tm.animateZoom(tm.targetZoom()+delta, screenX, screenY)
+}
 
-	return tileX, tileY
+// targetZoom is the zoom level the map will settle at once any animation finishes
+func (tm *TileMap) targetZoom() float64 {
+	if tm.zoomAnim.active {
+		return tm.zoomAnim.to
+	}
+	return tm.Zoom
 }
 
-// ZoomAtPoint zooms the map while keeping the given world point at the same screen location
-func (tm *TileMap) ZoomAtPoint(zoomIn bool, screenX, screenY float64) {
-	if (zoomIn && tm.Zoom >= MaxZoomLevel) || (!zoomIn && tm.Zoom <= 0) {
+// animateZoom starts an eased transition from the current zoom to target
+func (tm *TileMap) animateZoom(target, screenX, screenY float64) {
+	target = tm.clampZoom(target)
+	if target == tm.Zoom {
+		tm.zoomAnim.active = false
 		return
 	}
 
-	// Get mouse position in tile coordinates before zoom
-	mouseWorldX, mouseWorldY := tm.ScreenToWorld(screenX, screenY)
+	// Don't zoom if cursor is above or below the world; it wraps horizontally
+	_, mouseWorldY := tm.ScreenToWorld(screenX, screenY)
+	maxTileCoord := float64(uint(1) << uint(tm.TileZoom()))
+	if mouseWorldY < 0 || mouseWorldY > maxTileCoord {
+		return
+	}
 
-	// Check if mouse is within world bounds
-	maxTileCoord := float64(uint(1) << uint(tm.Zoom))
-	if mouseWorldX < 0 || mouseWorldX > maxTileCoord ||
-		mouseWorldY < 0 || mouseWorldY > maxTileCoord {
-		return // Don't zoom if cursor is outside world bounds
+	if tm.ZoomDuration <= 0 {
+		tm.SetZoomAt(target, screenX, screenY)
+		tm.zoomAnim.active = false
+		return
 	}
 
-	// Change zoom level
-	oldZoom := tm.Zoom
-	if zoomIn {
-		tm.Zoom++
-	} else {
-		tm.Zoom--
+	tm.zoomAnim = zoomAnimation{
+		active:  true,
+		from:    tm.Zoom,
+		to:      target,
+		anchorX: screenX,
+		anchorY: screenY,
+		start:   time.Now(),
+	}
+}
+
+// Update advances any running zoom animation and kinetic panning. Call it once per tick.
+func (tm *TileMap) Update() {
+	tm.updateKinetic()
+
+	if !tm.zoomAnim.active {
+		return
 	}
 
-	// Calculate the scale factor between zoom levels
-	scaleFactor := math.Pow(2, float64(tm.Zoom-oldZoom))
+	t := float64(time.Since(tm.zoomAnim.start)) / float64(tm.ZoomDuration)
+	if t >= 1 {
+		tm.zoomAnim.active = false
+		tm.SetZoomAt(tm.zoomAnim.to, tm.zoomAnim.anchorX, tm.zoomAnim.anchorY)
+		return
+	}
+
+	// Ease out cubic
+	eased := 1 - math.Pow(1-t, 3)
+	zoom := tm.zoomAnim.from + (tm.zoomAnim.to-tm.zoomAnim.from)*eased
+	tm.SetZoomAt(zoom, tm.zoomAnim.anchorX, tm.zoomAnim.anchorY)
+}
+
+// IsZooming reports whether a zoom animation is in progress
+func (tm *TileMap) IsZooming() bool {
+	return tm.zoomAnim.active
+}
+
+// SetZoomAt immediately sets the zoom level while keeping the world point under
+// the given screen location fixed
+func (tm *TileMap) SetZoomAt(zoom, screenX, screenY float64) {
+	zoom = tm.clampZoom(zoom)
+
+	// Work in zoom 0 tile coordinates, where the world is a single unit square
+	centerX, centerY := proj.LatLonToTileCoords(tm.CenterLat, tm.CenterLon, 0)
+	offsetX, offsetY := tm.screenToMapOffset(screenX-float64(tm.ScreenWidth)/2, screenY-float64(tm.ScreenHeight)/2)
 
-	// Convert mouse world position to the new zoom level
-	mouseWorldXNewZoom := mouseWorldX * scaleFactor
-	mouseWorldYNewZoom := mouseWorldY * scaleFactor
+	// World point under the anchor before the zoom
+	oldWorldSize := TileSize * math.Exp2(tm.Zoom)
+	anchorX := centerX + offsetX/oldWorldSize
+	anchorY := centerY + offsetY/oldWorldSize
 
-	// Convert screen position to tile offset at new zoom level
-	pixelsToTiles := 1.0 / TileSize
-	screenTileOffsetX := (screenX - float64(tm.ScreenWidth)/2) * pixelsToTiles
-	screenTileOffsetY := (screenY - float64(tm.ScreenHeight)/2) * pixelsToTiles
+	// New center keeps the anchor at the same screen location
+	newWorldSize := TileSize * math.Exp2(zoom)
+	newCenterX := anchorX - offsetX/newWorldSize
+	newCenterY := anchorY - offsetY/newWorldSize
 
-	// Calculate new center in tile coordinates
-	newCenterTileX := mouseWorldXNewZoom - screenTileOffsetX
-	newCenterTileY := mouseWorldYNewZoom - screenTileOffsetY
+	tm.Zoom = zoom
 
 	// Convert back to lat/lon
-	maxTileCoord = float64(uint(1) << uint(tm.Zoom))
-	lon := (newCenterTileX/maxTileCoord)*360.0 - 180.0
-	lat := math.Atan(math.Sinh(math.Pi*(1-2*newCenterTileY/maxTileCoord))) * 180.0 / math.Pi
+	lat, lon := proj.TileCoordsToLatLon(newCenterX, newCenterY, 0)
 
-	// Clamp to valid ranges
-	tm.CenterLon = math.Max(-180.0, math.Min(180.0, lon))
-	tm.CenterLat = math.Max(-85.0511, math.Min(85.0511, lat))
+	// Wrap longitude, clamp latitude and apply view limits
+	tm.CenterLon = lon
+	tm.CenterLat = lat
+	tm.Viewport = tm.Viewport.Constrain()
 }
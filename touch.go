@@ -4,6 +4,8 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/OpticalFlyer/goliath/ui"
 )
 
 func (g *Goliath) handleTouchEvents() {
@@ -17,12 +19,14 @@ func (g *Goliath) handleTouchEvents() {
 		g.lastTouchY = make(map[ebiten.TouchID]float64)
 	}
 
-	// Handle touch start
+	// Handle touch start; any new finger stops kinetic motion
 	for _, id := range touches {
 		if _, exists := g.lastTouchX[id]; !exists {
-			x, y := ebiten.TouchPosition(id)
+			x, y := ui.TouchPosition(id)
 			g.lastTouchX[id] = float64(x)
 			g.lastTouchY[id] = float64(y)
+			g.tileMap.BeginDrag()
+			g.touchPanning = false
 		}
 	}
 
@@ -35,25 +39,29 @@ func (g *Goliath) handleTouchEvents() {
 	}
 
 	switch len(touches) {
+	case 0: // Released - let a single-finger pan glide
+		if g.touchPanning {
+			g.touchPanning = false
+			g.tileMap.EndDrag()
+		}
+
 	case 1: // Single touch - pan
 		id := touches[0]
-		x, y := ebiten.TouchPosition(id)
+		x, y := ui.TouchPosition(id)
 		if lastX, ok := g.lastTouchX[id]; ok {
 			if lastY, ok := g.lastTouchY[id]; ok {
-				dx := float64(x) - lastX
-				dy := float64(y) - lastY
-				if dx != 0 || dy != 0 {
-					g.tileMap.PanBy(dx, dy)
-				}
+				g.tileMap.DragBy(float64(x)-lastX, float64(y)-lastY)
+				g.touchPanning = true
 			}
 		}
 		g.lastTouchX[id] = float64(x)
 		g.lastTouchY[id] = float64(y)
 
-	case 2: // Two finger touch - pinch to zoom
+	case 2: // Two finger touch - pinch to zoom, twist to rotate
+		g.touchPanning = false
 		id1, id2 := touches[0], touches[1]
-		x1, y1 := ebiten.TouchPosition(id1)
-		x2, y2 := ebiten.TouchPosition(id2)
+		x1, y1 := ui.TouchPosition(id1)
+		x2, y2 := ui.TouchPosition(id2)
 
 		currentDist := distance(float64(x1), float64(y1), float64(x2), float64(y2))
 
@@ -65,10 +73,16 @@ func (g *Goliath) handleTouchEvents() {
 				midX := (float64(x1) + float64(x2)) / 2
 				midY := (float64(y1) + float64(y2)) / 2
 
-				if currentDist > prevDist*1.1 { // Zoom in
-					g.tileMap.ZoomAtPoint(true, midX, midY)
-				} else if currentDist < prevDist*0.9 { // Zoom out
-					g.tileMap.ZoomAtPoint(false, midX, midY)
+				// Zoom continuously with the pinch; doubling the finger spread zooms in one level
+				if prevDist > 0 && currentDist > 0 && currentDist != prevDist {
+					g.tileMap.SetZoomAt(g.tileMap.Zoom+math.Log2(currentDist/prevDist), midX, midY)
+				}
+
+				// Rotate with the change in angle of the line between the fingers
+				prevAngle := math.Atan2(g.lastTouchY[id2]-g.lastTouchY[id1], g.lastTouchX[id2]-g.lastTouchX[id1])
+				angle := math.Atan2(float64(y2-y1), float64(x2-x1))
+				if twist := angleDelta(prevAngle, angle); twist != 0 {
+					g.tileMap.RotateBy(twist)
 				}
 			}
 		}
@@ -88,6 +102,18 @@ func containsTouchID(ids []ebiten.TouchID, id ebiten.TouchID) bool {
 	return false
 }
 
+// Helper function to calculate the signed change between two angles in radians,
+// returned in degrees within (-180, 180]
+func angleDelta(from, to float64) float64 {
+	delta := math.Mod((to-from)*180/math.Pi, 360)
+	if delta > 180 {
+		delta -= 360
+	} else if delta <= -180 {
+		delta += 360
+	}
+	return delta
+}
+
 // Helper function to calculate distance between two points
 func distance(x1, y1, x2, y2 float64) float64 {
 	dx := x2 - x1
@@ -4,9 +4,16 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+// Size of the debug font used for text
+const (
+	charWidth  = 6
+	lineHeight = 16
+)
+
 var _ Component = (*Button)(nil)
 
 type Button struct {
@@ -66,9 +73,21 @@ func (b *Button) Draw(screen *ebiten.Image) {
 	// Draw border
 	vector.StrokeRect(screen, float32(absoluteX), float32(absoluteY),
 		float32(b.width), float32(b.height), 1, color.Black, true)
+
+	// Draw the label centered
+	textX := absoluteX + (b.width-float64(len(b.text)*charWidth))/2
+	textY := absoluteY + (b.height-lineHeight)/2
+	ebitenutil.DebugPrintAt(screen, b.text, int(textX), int(textY))
 }
 
+// HandleInput hit tests a press or release at screen coordinates. A click is a
+// press followed by a release on the button.
 func (b *Button) HandleInput(x, y float64, pressed bool) bool {
+	// Input arrives in screen coordinates; the button is placed relative to its parent
+	parentBounds := b.parent.Bounds()
+	x -= parentBounds.X
+	y -= parentBounds.Y
+
 	// Check if point is within button bounds
 	if x >= b.x && x <= b.x+b.width &&
 		y >= b.y && y <= b.y+b.height {
@@ -0,0 +1,37 @@
+package ui
+
+import "testing"
+
+func TestButtonHandleInputInOffsetParent(t *testing.T) {
+	tests := []struct {
+		name       string
+		press      [2]float64 // Screen position of the press
+		release    [2]float64 // Screen position of the release
+		wantPress  bool       // Whether the press lands on the button
+		wantClicks int
+	}{
+		{name: "Click inside", press: [2]float64{215, 145}, release: [2]float64{215, 145}, wantPress: true, wantClicks: 1},
+		{name: "Click on the far corner", press: [2]float64{310, 170}, release: [2]float64{310, 170}, wantPress: true, wantClicks: 1},
+		{name: "Click at the parent-relative position", press: [2]float64{15, 45}, release: [2]float64{15, 45}},
+		{name: "Left of the button", press: [2]float64{205, 145}, release: [2]float64{205, 145}},
+		{name: "Below the button", press: [2]float64{215, 175}, release: [2]float64{215, 175}},
+		{name: "Released outside", press: [2]float64{215, 145}, release: [2]float64{400, 145}, wantPress: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clicks := 0
+			panel := NewPanel(200, 100, 300, 200, "Tools")
+			button := NewButton(10, 40, "Measure", func() { clicks++ })
+			panel.AddChild(button)
+
+			if got := button.HandleInput(tt.press[0], tt.press[1], true); got != tt.wantPress {
+				t.Errorf("press handled = %v; want %v", got, tt.wantPress)
+			}
+			button.HandleInput(tt.release[0], tt.release[1], false)
+			if clicks != tt.wantClicks {
+				t.Errorf("clicks = %d; want %d", clicks, tt.wantClicks)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 // Controller is the root container for the UI system
@@ -64,6 +65,12 @@ func (c *Controller) Update() error {
 			return err
 		}
 	}
+
+	// Deliver mouse releases so buttons can complete their clicks
+	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+		x, y := CursorPosition()
+		c.HandleInput(float64(x), float64(y), false)
+	}
 	return nil
 }
 
@@ -114,7 +121,7 @@ func (c *Controller) drawDebugInfo(screen *ebiten.Image) {
 
 func (c *Controller) IsInteractingWithUI() bool {
 	// Check mouse interaction
-	x, y := ebiten.CursorPosition()
+	x, y := CursorPosition()
 	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 		return c.HandleInput(float64(x), float64(y), true)
 	}
@@ -123,7 +130,7 @@ func (c *Controller) IsInteractingWithUI() bool {
 	touches := make([]ebiten.TouchID, 0, 8)
 	touches = ebiten.AppendTouchIDs(touches)
 	for _, id := range touches {
-		x, y := ebiten.TouchPosition(id)
+		x, y := TouchPosition(id)
 		if c.HandleInput(float64(x), float64(y), true) {
 			return true
 		}
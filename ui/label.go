@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+var _ Component = (*Label)(nil)
+
+// Label displays one or more lines of text
+type Label struct {
+	x, y   float64
+	text   string
+	parent Container
+}
+
+func NewLabel(x, y float64, text string) *Label {
+	return &Label{x: x, y: y, text: text}
+}
+
+func (l *Label) SetParent(parent Container) {
+	l.parent = parent
+}
+
+func (l *Label) GetParent() Container {
+	return l.parent
+}
+
+// SetText replaces the label's text; lines are separated by \n
+func (l *Label) SetText(text string) {
+	l.text = text
+}
+
+// Text returns the label's text
+func (l *Label) Text() string {
+	return l.text
+}
+
+func (l *Label) Update() error {
+	return nil
+}
+
+func (l *Label) Draw(screen *ebiten.Image) {
+	parentBounds := l.parent.Bounds()
+	ebitenutil.DebugPrintAt(screen, l.text, int(l.x+parentBounds.X), int(l.y+parentBounds.Y))
+}
+
+// HandleInput implements Component; labels don't take input
+func (l *Label) HandleInput(x, y float64, pressed bool) bool {
+	return false
+}
+
+func (l *Label) Bounds() Rectangle {
+	lines := strings.Split(l.text, "\n")
+	width := 0
+	for _, line := range lines {
+		if w := len([]rune(line)) * charWidth; w > width {
+			width = w
+		}
+	}
+	return Rectangle{
+		X:      l.x,
+		Y:      l.y,
+		Width:  float64(width),
+		Height: float64(len(lines) * lineHeight),
+	}
+}
@@ -340,7 +340,7 @@ func (p *Panel) getResizeArea(x, y float64) ResizeState {
 }
 
 func (p *Panel) updateCursor() {
-	x, y := ebiten.CursorPosition()
+	x, y := CursorPosition()
 	resizeState := p.getResizeArea(float64(x), float64(y))
 
 	switch resizeState {
@@ -362,7 +362,7 @@ func (p *Panel) updateCursor() {
 }
 
 func (p *Panel) Update() error {
-	x, y := ebiten.CursorPosition()
+	x, y := CursorPosition()
 	fx, fy := float64(x), float64(y)
 
 	p.updateCursor()
@@ -499,7 +499,7 @@ func (p *Panel) Update() error {
 		if !p.isTouchDragging {
 			// Check for new touch in title bar
 			for _, id := range touches {
-				x, y := ebiten.TouchPosition(id)
+				x, y := TouchPosition(id)
 				fx, fy := float64(x), float64(y)
 
 				if p.isInTitleBar(fx, fy) {
@@ -534,7 +534,7 @@ func (p *Panel) Update() error {
 			}
 		} else {
 			// Handle ongoing touch drag
-			x, y := ebiten.TouchPosition(p.touchID)
+			x, y := TouchPosition(p.touchID)
 			fx, fy := float64(x), float64(y)
 
 			// Check if this touch ID is still active
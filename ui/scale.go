@@ -0,0 +1,33 @@
+package ui
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// deviceScale is the number of device pixels per logical pixel. On HiDPI
+// displays the game screen is in device pixels, while UI layout and input
+// stay in logical pixels.
+var deviceScale = 1.0
+
+// SetDeviceScale sets the number of device pixels per logical pixel
+func SetDeviceScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+	deviceScale = scale
+}
+
+// DeviceScale returns the number of device pixels per logical pixel
+func DeviceScale() float64 {
+	return deviceScale
+}
+
+// CursorPosition returns the mouse cursor position in logical pixels
+func CursorPosition() (int, int) {
+	x, y := ebiten.CursorPosition()
+	return int(float64(x) / deviceScale), int(float64(y) / deviceScale)
+}
+
+// TouchPosition returns the position of a touch in logical pixels
+func TouchPosition(id ebiten.TouchID) (int, int) {
+	x, y := ebiten.TouchPosition(id)
+	return int(float64(x) / deviceScale), int(float64(y) / deviceScale)
+}
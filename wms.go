@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+	"github.com/OpticalFlyer/goliath/wms"
+)
+
+// capabilitiesTimeout bounds the WMS and WMTS GetCapabilities requests made at startup
+const capabilitiesTimeout = 15 * time.Second
+
+// newWMSSource creates a source for comma-separated lists of WMS layers and
+// styles. The service's capabilities pick the version, format and CRS; if they
+// can't be fetched, the defaults from tilemap.NewWMSSource are used.
+func newWMSSource(baseURL, layerList, styleList, version string) (*tilemap.WMSSource, error) {
+	layers := splitList(layerList)
+
+	var src *tilemap.WMSSource
+	ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+	defer cancel()
+	caps, err := wms.FetchCapabilities(ctx, nil, baseURL, version)
+	if err != nil {
+		log.Printf("Using WMS defaults: %v", err)
+		src = tilemap.NewWMSSource("wms", baseURL, layers...)
+		if version != "" {
+			src.Request.Version = version
+		}
+	} else if src, err = tilemap.NewWMSSourceFromCapabilities("wms", baseURL, caps, layers...); err != nil {
+		return nil, err
+	}
+
+	if styleList != "" {
+		src.Request.Styles = splitList(styleList)
+		if len(src.Request.Styles) != len(layers) {
+			return nil, fmt.Errorf("got %d WMS styles for %d layers", len(src.Request.Styles), len(layers))
+		}
+	}
+	return src, nil
+}
+
+// splitList splits a comma-separated flag value, trimming spaces
+func splitList(list string) []string {
+	items := strings.Split(list, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// printWMSLayers lists the layers a service offers, for choosing -wms-layers
+func printWMSLayers(baseURL, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+	defer cancel()
+	caps, err := wms.FetchCapabilities(ctx, nil, baseURL, version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (WMS %s)\n", caps.Title, caps.Version)
+	for _, layer := range caps.NamedLayers() {
+		fmt.Printf("  %-30s %s\n", layer.Name, layer.Title)
+	}
+	fmt.Println("Choose layers with -wms-layers name1,name2")
+	return nil
+}
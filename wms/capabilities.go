@@ -0,0 +1,290 @@
+package wms
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Capabilities is the parsed subset of a GetCapabilities response needed to
+// request maps
+type Capabilities struct {
+	Version   string
+	Title     string
+	GetMapURL string   // Endpoint for GetMap requests
+	Formats   []string // Image formats GetMap can return
+	Layers    []*Layer // Top-level layers; named layers may be nested inside
+}
+
+// Layer is a WMS layer. Layers without a Name are only groups and can't be requested.
+type Layer struct {
+	Name      string
+	Title     string
+	Abstract  string
+	CRS       []string // Supported CRSs, including those inherited from parent layers
+	Styles    []Style
+	Queryable bool
+	Opaque    bool
+
+	// Credit for the layer's data, inherited from parent layers
+	AttributionTitle string
+	AttributionURL   string
+
+	// Geographic extent in degrees, if given
+	HasBounds         bool
+	WestLon, SouthLat float64
+	EastLon, NorthLat float64
+
+	// Child layers
+	Layers []*Layer
+}
+
+// Style is a named rendering of a layer
+type Style struct {
+	Name  string
+	Title string
+}
+
+// SupportsCRS reports whether the layer can be drawn in a CRS
+func (l *Layer) SupportsCRS(crs string) bool {
+	return slices.ContainsFunc(l.CRS, func(c string) bool {
+		return strings.EqualFold(c, crs)
+	})
+}
+
+// NamedLayers returns every requestable layer, depth first
+func (c *Capabilities) NamedLayers() []*Layer {
+	var named []*Layer
+	var walk func(layers []*Layer)
+	walk = func(layers []*Layer) {
+		for _, l := range layers {
+			if l.Name != "" {
+				named = append(named, l)
+			}
+			walk(l.Layers)
+		}
+	}
+	walk(c.Layers)
+	return named
+}
+
+// Layer returns the named layer, or nil
+func (c *Capabilities) Layer(name string) *Layer {
+	for _, l := range c.NamedLayers() {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// PreferredFormat returns the first of the given formats that the server
+// supports, or "" if none are
+func (c *Capabilities) PreferredFormat(formats ...string) string {
+	for _, f := range formats {
+		if slices.Contains(c.Formats, f) {
+			return f
+		}
+	}
+	return ""
+}
+
+// xmlCapabilities matches both the 1.1.1 WMT_MS_Capabilities and the 1.3.0
+// WMS_Capabilities documents; element names are matched in any namespace
+type xmlCapabilities struct {
+	Version string `xml:"version,attr"`
+	Service struct {
+		Title string `xml:"Title"`
+	} `xml:"Service"`
+	Capability struct {
+		GetMap struct {
+			Formats []string `xml:"Format"`
+			Get     struct {
+				Href string `xml:"href,attr"`
+			} `xml:"DCPType>HTTP>Get>OnlineResource"`
+		} `xml:"Request>GetMap"`
+		Layers []xmlLayer `xml:"Layer"`
+	} `xml:"Capability"`
+}
+
+type xmlLayer struct {
+	Queryable string   `xml:"queryable,attr"`
+	Opaque    string   `xml:"opaque,attr"`
+	Name      string   `xml:"Name"`
+	Title     string   `xml:"Title"`
+	Abstract  string   `xml:"Abstract"`
+	CRS       []string `xml:"CRS"`
+	SRS       []string `xml:"SRS"`
+	Styles    []struct {
+		Name  string `xml:"Name"`
+		Title string `xml:"Title"`
+	} `xml:"Style"`
+	Attribution *struct {
+		Title          string `xml:"Title"`
+		OnlineResource struct {
+			Href string `xml:"href,attr"`
+		} `xml:"OnlineResource"`
+	} `xml:"Attribution"`
+
+	// 1.3.0 extent
+	GeoBBox *struct {
+		West  float64 `xml:"westBoundLongitude"`
+		East  float64 `xml:"eastBoundLongitude"`
+		South float64 `xml:"southBoundLatitude"`
+		North float64 `xml:"northBoundLatitude"`
+	} `xml:"EX_GeographicBoundingBox"`
+
+	// 1.1.1 extent
+	LatLonBBox *struct {
+		MinX float64 `xml:"minx,attr"`
+		MinY float64 `xml:"miny,attr"`
+		MaxX float64 `xml:"maxx,attr"`
+		MaxY float64 `xml:"maxy,attr"`
+	} `xml:"LatLonBoundingBox"`
+
+	Layers []xmlLayer `xml:"Layer"`
+}
+
+// ParseCapabilities reads a GetCapabilities response
+func ParseCapabilities(r io.Reader) (*Capabilities, error) {
+	var doc xmlCapabilities
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing WMS capabilities failed: %w", err)
+	}
+
+	caps := &Capabilities{
+		Version:   doc.Version,
+		Title:     strings.TrimSpace(doc.Service.Title),
+		GetMapURL: strings.TrimSpace(doc.Capability.GetMap.Get.Href),
+	}
+	for _, f := range doc.Capability.GetMap.Formats {
+		caps.Formats = append(caps.Formats, strings.TrimSpace(f))
+	}
+	for i := range doc.Capability.Layers {
+		caps.Layers = append(caps.Layers, convertLayer(&doc.Capability.Layers[i], nil))
+	}
+	return caps, nil
+}
+
+// charsetReader decodes the Latin-1 documents some older servers send
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", label)
+}
+
+// convertLayer converts a layer and its children, applying the inheritance
+// rules for CRSs, styles and extents
+func convertLayer(x *xmlLayer, parent *Layer) *Layer {
+	l := &Layer{
+		Name:      strings.TrimSpace(x.Name),
+		Title:     strings.TrimSpace(x.Title),
+		Abstract:  strings.TrimSpace(x.Abstract),
+		Queryable: x.Queryable == "1" || x.Queryable == "true",
+		Opaque:    x.Opaque == "1" || x.Opaque == "true",
+	}
+	if parent != nil {
+		l.CRS = slices.Clone(parent.CRS)
+		l.Styles = slices.Clone(parent.Styles)
+		l.AttributionTitle, l.AttributionURL = parent.AttributionTitle, parent.AttributionURL
+		l.HasBounds = parent.HasBounds
+		l.WestLon, l.SouthLat, l.EastLon, l.NorthLat = parent.WestLon, parent.SouthLat, parent.EastLon, parent.NorthLat
+	}
+
+	// 1.1.1 servers sometimes list several SRSs in one element
+	for _, list := range append(x.CRS, x.SRS...) {
+		for _, crs := range strings.Fields(list) {
+			if !l.SupportsCRS(crs) {
+				l.CRS = append(l.CRS, crs)
+			}
+		}
+	}
+	for _, s := range x.Styles {
+		l.Styles = append(l.Styles, Style{Name: strings.TrimSpace(s.Name), Title: strings.TrimSpace(s.Title)})
+	}
+
+	if x.Attribution != nil {
+		l.AttributionTitle = strings.TrimSpace(x.Attribution.Title)
+		l.AttributionURL = strings.TrimSpace(x.Attribution.OnlineResource.Href)
+	}
+
+	switch {
+	case x.GeoBBox != nil:
+		l.HasBounds = true
+		l.WestLon, l.SouthLat, l.EastLon, l.NorthLat = x.GeoBBox.West, x.GeoBBox.South, x.GeoBBox.East, x.GeoBBox.North
+	case x.LatLonBBox != nil:
+		l.HasBounds = true
+		l.WestLon, l.SouthLat, l.EastLon, l.NorthLat = x.LatLonBBox.MinX, x.LatLonBBox.MinY, x.LatLonBBox.MaxX, x.LatLonBBox.MaxY
+	}
+
+	for i := range x.Layers {
+		l.Layers = append(l.Layers, convertLayer(&x.Layers[i], l))
+	}
+	return l
+}
+
+// CapabilitiesURL returns the GetCapabilities URL for a service
+func CapabilitiesURL(baseURL, version string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing WMS URL failed: %w", err)
+	}
+	if version == "" {
+		version = Version130
+	}
+	q := u.Query()
+	for key := range q {
+		switch strings.ToUpper(key) {
+		case "SERVICE", "VERSION", "REQUEST":
+			q.Del(key)
+		}
+	}
+	q.Set("SERVICE", "WMS")
+	q.Set("VERSION", version)
+	q.Set("REQUEST", "GetCapabilities")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// FetchCapabilities downloads and parses a service's capabilities. A nil
+// client means http.DefaultClient.
+func FetchCapabilities(ctx context.Context, client *http.Client, baseURL, version string) (*Capabilities, error) {
+	capsURL, err := CapabilitiesURL(baseURL, version)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", capsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating capabilities request failed: %w", err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("capabilities request returned status: %d", resp.StatusCode)
+	}
+	return ParseCapabilities(resp.Body)
+}
@@ -0,0 +1,138 @@
+// Package wms builds OGC Web Map Service requests and parses WMS capabilities
+// documents, for versions 1.1.1 and 1.3.0.
+package wms
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Supported protocol versions
+const (
+	Version111 = "1.1.1"
+	Version130 = "1.3.0"
+)
+
+// GetMap describes a GetMap request
+type GetMap struct {
+	BaseURL     string
+	Version     string // Version111 or Version130; empty means Version130
+	Layers      []string
+	Styles      []string // One per layer, or empty for default styles
+	Format      string   // Image MIME type; empty means image/png
+	Transparent bool
+	CRS         string // Coordinate reference system, such as EPSG:3857
+
+	// Area to draw in CRS units, always given x/easting/longitude first.
+	// URL swaps the axes when the version and CRS require it.
+	MinX, MinY float64
+	MaxX, MaxY float64
+
+	Width, Height int
+}
+
+// AxisOrderFlipped reports whether a CRS puts latitude (northing) before
+// longitude (easting) in BBOX parameters. WMS 1.3.0 follows the axis order
+// of the EPSG definition, which is lat/lon for geographic CRSs; 1.1.1 is
+// always lon/lat.
+func AxisOrderFlipped(version, crs string) bool {
+	if version == Version111 {
+		return false
+	}
+	switch strings.ToUpper(crs) {
+	case "EPSG:4326", "EPSG:4269", "EPSG:4267", "EPSG:4258", "EPSG:4283", "EPSG:4617":
+		return true
+	}
+	return false
+}
+
+// URL returns the request URL. Parameters already in BaseURL, such as a map
+// file or API key, are kept.
+func (r GetMap) URL() (string, error) {
+	u, err := url.Parse(r.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing WMS URL failed: %w", err)
+	}
+	if len(r.Layers) == 0 {
+		return "", fmt.Errorf("WMS GetMap needs at least one layer")
+	}
+	if len(r.Styles) != 0 && len(r.Styles) != len(r.Layers) {
+		return "", fmt.Errorf("WMS GetMap has %d styles for %d layers", len(r.Styles), len(r.Layers))
+	}
+
+	version := r.Version
+	if version == "" {
+		version = Version130
+	}
+	format := r.Format
+	if format == "" {
+		format = "image/png"
+	}
+
+	q := u.Query()
+	// Keys are case-insensitive, so drop any that would clash
+	for key := range q {
+		switch strings.ToUpper(key) {
+		case "SERVICE", "VERSION", "REQUEST", "LAYERS", "STYLES", "FORMAT", "TRANSPARENT",
+			"CRS", "SRS", "BBOX", "WIDTH", "HEIGHT":
+			q.Del(key)
+		}
+	}
+
+	minX, minY, maxX, maxY := r.MinX, r.MinY, r.MaxX, r.MaxY
+	if AxisOrderFlipped(version, r.CRS) {
+		minX, minY, maxX, maxY = minY, minX, maxY, maxX
+	}
+
+	q.Set("SERVICE", "WMS")
+	q.Set("VERSION", version)
+	q.Set("REQUEST", "GetMap")
+	q.Set("LAYERS", strings.Join(r.Layers, ","))
+	q.Set("STYLES", strings.Join(r.Styles, ","))
+	q.Set("FORMAT", format)
+	q.Set("TRANSPARENT", strings.ToUpper(strconv.FormatBool(r.Transparent)))
+	if version == Version111 {
+		q.Set("SRS", r.CRS)
+	} else {
+		q.Set("CRS", r.CRS)
+	}
+	q.Set("BBOX", strings.Join([]string{formatCoord(minX), formatCoord(minY), formatCoord(maxX), formatCoord(maxY)}, ","))
+	q.Set("WIDTH", strconv.Itoa(r.Width))
+	q.Set("HEIGHT", strconv.Itoa(r.Height))
+
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// formatCoord formats a coordinate without exponent notation
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseServiceException extracts the messages from a ServiceExceptionReport,
+// which servers send instead of an image when a request fails
+func ParseServiceException(data []byte) (string, bool) {
+	var report struct {
+		XMLName    xml.Name
+		Exceptions []struct {
+			Code    string `xml:"code,attr"`
+			Message string `xml:",chardata"`
+		} `xml:"ServiceException"`
+	}
+	if err := xml.Unmarshal(data, &report); err != nil || report.XMLName.Local != "ServiceExceptionReport" {
+		return "", false
+	}
+
+	var messages []string
+	for _, e := range report.Exceptions {
+		msg := strings.TrimSpace(e.Message)
+		if e.Code != "" {
+			msg = e.Code + ": " + msg
+		}
+		messages = append(messages, msg)
+	}
+	return strings.Join(messages, "; "), true
+}
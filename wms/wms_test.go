@@ -0,0 +1,242 @@
+package wms
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetMapURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      GetMap
+		wantCRS  string // Query key holding the CRS
+		wantBBox string
+	}{
+		{
+			name: "1.3.0 Web Mercator keeps x/y order",
+			req: GetMap{
+				Version: Version130, CRS: "EPSG:3857",
+				MinX: -20037508.34, MinY: 0, MaxX: 0, MaxY: 20037508.34,
+			},
+			wantCRS:  "CRS",
+			wantBBox: "-20037508.34,0,0,20037508.34",
+		},
+		{
+			name: "1.3.0 EPSG:4326 swaps to lat/lon",
+			req: GetMap{
+				Version: Version130, CRS: "EPSG:4326",
+				MinX: -105.5, MinY: 39.5, MaxX: -105, MaxY: 40,
+			},
+			wantCRS:  "CRS",
+			wantBBox: "39.5,-105.5,40,-105",
+		},
+		{
+			name: "1.1.1 EPSG:4326 stays lon/lat",
+			req: GetMap{
+				Version: Version111, CRS: "EPSG:4326",
+				MinX: -105.5, MinY: 39.5, MaxX: -105, MaxY: 40,
+			},
+			wantCRS:  "SRS",
+			wantBBox: "-105.5,39.5,-105,40",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.BaseURL = "https://gis.example.com/wms?map=parcels&request=GetCapabilities"
+			req.Layers = []string{"parcels", "row"}
+			req.Styles = []string{"", "outline"}
+			req.Transparent = true
+			req.Width, req.Height = 256, 256
+
+			got, err := req.URL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := u.Query()
+
+			want := map[string]string{
+				"map":         "parcels",
+				"SERVICE":     "WMS",
+				"VERSION":     tt.req.Version,
+				"REQUEST":     "GetMap",
+				"LAYERS":      "parcels,row",
+				"STYLES":      ",outline",
+				"FORMAT":      "image/png",
+				"TRANSPARENT": "TRUE",
+				tt.wantCRS:    tt.req.CRS,
+				"BBOX":        tt.wantBBox,
+				"WIDTH":       "256",
+				"HEIGHT":      "256",
+			}
+			for key, value := range want {
+				if q.Get(key) != value {
+					t.Errorf("%s = %q; want %q", key, q.Get(key), value)
+				}
+			}
+			if _, ok := q["request"]; ok {
+				t.Error("lowercase request parameter from the base URL was kept")
+			}
+		})
+	}
+}
+
+func TestGetMapURLErrors(t *testing.T) {
+	if _, err := (GetMap{BaseURL: "https://example.com/wms"}).URL(); err == nil {
+		t.Error("no layers: expected an error")
+	}
+	req := GetMap{BaseURL: "https://example.com/wms", Layers: []string{"a", "b"}, Styles: []string{"x"}}
+	if _, err := req.URL(); err == nil {
+		t.Error("mismatched styles: expected an error")
+	}
+}
+
+const capabilities130 = `<?xml version="1.0" encoding="UTF-8"?>
+<WMS_Capabilities version="1.3.0" xmlns="http://www.opengis.net/wms" xmlns:xlink="http://www.w3.org/1999/xlink">
+  <Service><Name>WMS</Name><Title>County GIS</Title></Service>
+  <Capability>
+    <Request>
+      <GetMap>
+        <Format>image/png</Format>
+        <Format>image/jpeg</Format>
+        <DCPType><HTTP><Get><OnlineResource xlink:type="simple" xlink:href="https://gis.example.com/wms?"/></Get></HTTP></DCPType>
+      </GetMap>
+    </Request>
+    <Layer>
+      <Title>County</Title>
+      <Attribution>
+        <Title>County Assessor</Title>
+        <OnlineResource xlink:type="simple" xlink:href="https://assessor.example.com/"/>
+      </Attribution>
+      <CRS>EPSG:4326</CRS>
+      <CRS>EPSG:3857</CRS>
+      <EX_GeographicBoundingBox>
+        <westBoundLongitude>-105.7</westBoundLongitude>
+        <eastBoundLongitude>-105.0</eastBoundLongitude>
+        <southBoundLatitude>39.9</southBoundLatitude>
+        <northBoundLatitude>40.3</northBoundLatitude>
+      </EX_GeographicBoundingBox>
+      <Layer queryable="1">
+        <Name>parcels</Name>
+        <Title>Parcels</Title>
+        <Style><Name>default</Name><Title>Default</Title></Style>
+      </Layer>
+      <Layer queryable="0" opaque="1">
+        <Name>row</Name>
+        <Title>Right of way</Title>
+        <CRS>EPSG:26913</CRS>
+      </Layer>
+    </Layer>
+  </Capability>
+</WMS_Capabilities>`
+
+const capabilities111 = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<WMT_MS_Capabilities version="1.1.1">
+  <Service><Name>OGC:WMS</Name><Title>Old County Server</Title></Service>
+  <Capability>
+    <Request>
+      <GetMap>
+        <Format>image/png</Format>
+        <DCPType><HTTP><Get><OnlineResource xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="http://old.example.com/cgi-bin/mapserv?map=county"/></Get></HTTP></DCPType>
+      </GetMap>
+    </Request>
+    <Layer>
+      <Name>county</Name>
+      <Title>County</Title>
+      <SRS>EPSG:4326 EPSG:900913</SRS>
+      <LatLonBoundingBox minx="-105.7" miny="39.9" maxx="-105.0" maxy="40.3"/>
+      <Layer>
+        <Name>easements</Name>
+        <Title>Easements</Title>
+      </Layer>
+    </Layer>
+  </Capability>
+</WMT_MS_Capabilities>`
+
+func TestParseCapabilities130(t *testing.T) {
+	caps, err := ParseCapabilities(strings.NewReader(capabilities130))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if caps.Version != Version130 || caps.Title != "County GIS" {
+		t.Errorf("version, title = %q, %q", caps.Version, caps.Title)
+	}
+	if caps.GetMapURL != "https://gis.example.com/wms?" {
+		t.Errorf("GetMapURL = %q", caps.GetMapURL)
+	}
+	if got := caps.PreferredFormat("image/webp", "image/png"); got != "image/png" {
+		t.Errorf("PreferredFormat = %q; want image/png", got)
+	}
+
+	named := caps.NamedLayers()
+	if len(named) != 2 || named[0].Name != "parcels" || named[1].Name != "row" {
+		t.Fatalf("named layers = %v", named)
+	}
+
+	parcels := caps.Layer("parcels")
+	if !parcels.Queryable || len(parcels.Styles) != 1 || parcels.Styles[0].Name != "default" {
+		t.Errorf("parcels = %+v", parcels)
+	}
+	if !parcels.SupportsCRS("epsg:3857") {
+		t.Error("parcels should inherit EPSG:3857 from its parent")
+	}
+	if !parcels.HasBounds || parcels.WestLon != -105.7 || parcels.NorthLat != 40.3 {
+		t.Errorf("parcels bounds = %v %v %v %v", parcels.WestLon, parcels.SouthLat, parcels.EastLon, parcels.NorthLat)
+	}
+	if parcels.AttributionTitle != "County Assessor" || parcels.AttributionURL != "https://assessor.example.com/" {
+		t.Errorf("parcels attribution = %q, %q", parcels.AttributionTitle, parcels.AttributionURL)
+	}
+
+	row := caps.Layer("row")
+	if want := []string{"EPSG:4326", "EPSG:3857", "EPSG:26913"}; !reflect.DeepEqual(row.CRS, want) {
+		t.Errorf("row CRS = %v; want %v", row.CRS, want)
+	}
+	if !row.Opaque || row.Queryable {
+		t.Errorf("row = %+v", row)
+	}
+}
+
+func TestParseCapabilities111(t *testing.T) {
+	caps, err := ParseCapabilities(strings.NewReader(capabilities111))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if caps.Version != Version111 || caps.GetMapURL != "http://old.example.com/cgi-bin/mapserv?map=county" {
+		t.Errorf("version, GetMapURL = %q, %q", caps.Version, caps.GetMapURL)
+	}
+
+	easements := caps.Layer("easements")
+	if easements == nil {
+		t.Fatal("easements layer not found")
+	}
+	if !easements.SupportsCRS("EPSG:900913") || !easements.SupportsCRS("EPSG:4326") {
+		t.Errorf("easements CRS = %v", easements.CRS)
+	}
+	if !easements.HasBounds || easements.SouthLat != 39.9 || easements.EastLon != -105.0 {
+		t.Errorf("easements bounds = %v %v %v %v", easements.WestLon, easements.SouthLat, easements.EastLon, easements.NorthLat)
+	}
+}
+
+func TestParseServiceException(t *testing.T) {
+	report := `<?xml version="1.0"?>
+<ServiceExceptionReport version="1.3.0" xmlns="http://www.opengis.net/ogc">
+  <ServiceException code="LayerNotDefined">Layer "roads" is not defined</ServiceException>
+</ServiceExceptionReport>`
+
+	msg, ok := ParseServiceException([]byte(report))
+	if !ok || msg != `LayerNotDefined: Layer "roads" is not defined` {
+		t.Errorf("got %q, %v", msg, ok)
+	}
+	if _, ok := ParseServiceException([]byte("\x89PNG")); ok {
+		t.Error("PNG data parsed as a service exception")
+	}
+}
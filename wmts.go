@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/OpticalFlyer/goliath/tilemap"
+	"github.com/OpticalFlyer/goliath/wmts"
+)
+
+// fetchWMTSCapabilities fetches a WMTS service's capabilities at startup
+func fetchWMTSCapabilities(serviceURL string) (*wmts.Capabilities, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+	defer cancel()
+	return wmts.FetchCapabilities(ctx, nil, serviceURL)
+}
+
+// newWMTSSources creates a source for each of a comma-separated list of WMTS layers
+func newWMTSSources(caps *wmts.Capabilities, layerList string) ([]*tilemap.WMTSSource, error) {
+	var sources []*tilemap.WMTSSource
+	for _, layer := range splitList(layerList) {
+		src, err := tilemap.NewWMTSSource("wmts-"+layer, caps, layer, "")
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// printWMTSLayers lists the layers a service offers, for choosing -wmts-layers.
+// Only layers with a Web Mercator tile matrix set can be drawn.
+func printWMTSLayers(caps *wmts.Capabilities) {
+	fmt.Printf("%s (WMTS)\n", caps.Title)
+	for _, layer := range caps.Layers {
+		set, _, ok := caps.WebMercatorSet(layer)
+		matrixSet := "no Web Mercator tile matrix set"
+		if ok {
+			matrixSet = set.Identifier
+		}
+		fmt.Printf("  %-30s %s [%s]\n", layer.Identifier, layer.Title, matrixSet)
+	}
+	fmt.Println("Choose layers with -wmts-layers name1,name2")
+}
@@ -0,0 +1,416 @@
+// Package wmts parses OGC Web Map Tile Service 1.0.0 capabilities documents
+// and builds GetTile requests in both the KVP and RESTful encodings.
+package wmts
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version is the only published WMTS version
+const Version = "1.0.0"
+
+// Capabilities is the parsed subset of a GetCapabilities response needed to
+// request tiles
+type Capabilities struct {
+	Title string
+
+	// Provider names the organization running the service, for attribution
+	Provider     string
+	ProviderSite string
+
+	// GetTileURL is the KVP endpoint for GetTile requests, or "" if the
+	// service is RESTful only
+	GetTileURL string
+
+	Layers         []*Layer
+	TileMatrixSets []*TileMatrixSet
+}
+
+// Layer is a WMTS layer
+type Layer struct {
+	Identifier string
+	Title      string
+	Abstract   string
+	Formats    []string // Image MIME types
+	Styles     []Style
+	Dimensions []Dimension
+	Links      []TileMatrixSetLink
+	Resources  []ResourceURL
+
+	// Geographic extent in degrees, if given
+	HasBounds         bool
+	WestLon, SouthLat float64
+	EastLon, NorthLat float64
+}
+
+// Style is a named rendering of a layer
+type Style struct {
+	Identifier string
+	Title      string
+	IsDefault  bool
+}
+
+// Dimension is an extra axis of a layer, such as time
+type Dimension struct {
+	Identifier string
+	Default    string
+	Values     []string
+}
+
+// TileMatrixSetLink ties a layer to a tile matrix set, optionally limited to
+// part of each matrix
+type TileMatrixSetLink struct {
+	TileMatrixSet string
+	Limits        []TileMatrixLimits
+}
+
+// TileMatrixLimits is the range of tiles a layer has in one tile matrix
+type TileMatrixLimits struct {
+	TileMatrix             string
+	MinTileRow, MaxTileRow int
+	MinTileCol, MaxTileCol int
+}
+
+// ResourceURL is a RESTful URL template
+type ResourceURL struct {
+	Format       string
+	ResourceType string // "tile" for tiles
+	Template     string
+}
+
+// TileMatrixSet is a pyramid of tile matrices in one CRS
+type TileMatrixSet struct {
+	Identifier        string
+	SupportedCRS      string
+	WellKnownScaleSet string
+	TileMatrices      []TileMatrix
+}
+
+// TileMatrix is one level of a tile matrix set
+type TileMatrix struct {
+	Identifier       string
+	ScaleDenominator float64
+	TopLeftX         float64 // First axis of the CRS, as given in TopLeftCorner
+	TopLeftY         float64 // Second axis of the CRS
+	TileWidth        int
+	TileHeight       int
+	MatrixWidth      int
+	MatrixHeight     int
+}
+
+// Layer returns the layer with the given identifier, or nil
+func (c *Capabilities) Layer(identifier string) *Layer {
+	for _, l := range c.Layers {
+		if l.Identifier == identifier {
+			return l
+		}
+	}
+	return nil
+}
+
+// TileMatrixSet returns the tile matrix set with the given identifier, or nil
+func (c *Capabilities) TileMatrixSet(identifier string) *TileMatrixSet {
+	for _, s := range c.TileMatrixSets {
+		if s.Identifier == identifier {
+			return s
+		}
+	}
+	return nil
+}
+
+// WebMercatorSet returns the first of a layer's tile matrix sets that lines up
+// with the Web Mercator tile grid, along with the layer's link to it
+func (c *Capabilities) WebMercatorSet(l *Layer) (*TileMatrixSet, *TileMatrixSetLink, bool) {
+	for i := range l.Links {
+		set := c.TileMatrixSet(l.Links[i].TileMatrixSet)
+		if set != nil && len(set.WebMercatorZooms()) > 0 {
+			return set, &l.Links[i], true
+		}
+	}
+	return nil, nil, false
+}
+
+// DefaultStyle returns the identifier of the layer's default style, or the
+// first style if none is marked as default
+func (l *Layer) DefaultStyle() string {
+	for _, s := range l.Styles {
+		if s.IsDefault {
+			return s.Identifier
+		}
+	}
+	if len(l.Styles) > 0 {
+		return l.Styles[0].Identifier
+	}
+	return ""
+}
+
+// TileTemplate returns the RESTful tile URL template for a format, or for the
+// first tile resource if format is "". It returns "" if there is none.
+func (l *Layer) TileTemplate(format string) string {
+	for _, r := range l.Resources {
+		if strings.EqualFold(r.ResourceType, "tile") && (format == "" || r.Format == format) {
+			return r.Template
+		}
+	}
+	return ""
+}
+
+// MatrixLimits returns the layer's tile range in one matrix of the linked set
+func (link *TileMatrixSetLink) MatrixLimits(matrix string) (TileMatrixLimits, bool) {
+	for _, lim := range link.Limits {
+		if lim.TileMatrix == matrix {
+			return lim, true
+		}
+	}
+	return TileMatrixLimits{}, false
+}
+
+// xmlCapabilities matches a WMTS 1.0.0 Capabilities document; element names
+// are matched in any namespace, which covers the wmts and ows prefixes
+type xmlCapabilities struct {
+	Title    string `xml:"ServiceIdentification>Title"`
+	Provider struct {
+		Name string `xml:"ProviderName"`
+		Site struct {
+			Href string `xml:"href,attr"`
+		} `xml:"ProviderSite"`
+	} `xml:"ServiceProvider"`
+	Operations []struct {
+		Name string `xml:"name,attr"`
+		Gets []struct {
+			Href      string   `xml:"href,attr"`
+			Encodings []string `xml:"Constraint>AllowedValues>Value"`
+		} `xml:"DCP>HTTP>Get"`
+	} `xml:"OperationsMetadata>Operation"`
+	Layers         []xmlLayer         `xml:"Contents>Layer"`
+	TileMatrixSets []xmlTileMatrixSet `xml:"Contents>TileMatrixSet"`
+}
+
+type xmlLayer struct {
+	Identifier string `xml:"Identifier"`
+	Title      string `xml:"Title"`
+	Abstract   string `xml:"Abstract"`
+	BBox       *struct {
+		Lower string `xml:"LowerCorner"`
+		Upper string `xml:"UpperCorner"`
+	} `xml:"WGS84BoundingBox"`
+	Styles []struct {
+		IsDefault  string `xml:"isDefault,attr"`
+		Identifier string `xml:"Identifier"`
+		Title      string `xml:"Title"`
+	} `xml:"Style"`
+	Formats    []string `xml:"Format"`
+	Dimensions []struct {
+		Identifier string   `xml:"Identifier"`
+		Default    string   `xml:"Default"`
+		Values     []string `xml:"Value"`
+	} `xml:"Dimension"`
+	Links []struct {
+		TileMatrixSet string `xml:"TileMatrixSet"`
+		Limits        []struct {
+			TileMatrix string `xml:"TileMatrix"`
+			MinTileRow int    `xml:"MinTileRow"`
+			MaxTileRow int    `xml:"MaxTileRow"`
+			MinTileCol int    `xml:"MinTileCol"`
+			MaxTileCol int    `xml:"MaxTileCol"`
+		} `xml:"TileMatrixSetLimits>TileMatrixLimits"`
+	} `xml:"TileMatrixSetLink"`
+	Resources []struct {
+		Format       string `xml:"format,attr"`
+		ResourceType string `xml:"resourceType,attr"`
+		Template     string `xml:"template,attr"`
+	} `xml:"ResourceURL"`
+}
+
+type xmlTileMatrixSet struct {
+	Identifier        string `xml:"Identifier"`
+	SupportedCRS      string `xml:"SupportedCRS"`
+	WellKnownScaleSet string `xml:"WellKnownScaleSet"`
+	TileMatrices      []struct {
+		Identifier       string  `xml:"Identifier"`
+		ScaleDenominator float64 `xml:"ScaleDenominator"`
+		TopLeftCorner    string  `xml:"TopLeftCorner"`
+		TileWidth        int     `xml:"TileWidth"`
+		TileHeight       int     `xml:"TileHeight"`
+		MatrixWidth      int     `xml:"MatrixWidth"`
+		MatrixHeight     int     `xml:"MatrixHeight"`
+	} `xml:"TileMatrix"`
+}
+
+// ParseCapabilities reads a GetCapabilities response
+func ParseCapabilities(r io.Reader) (*Capabilities, error) {
+	var doc xmlCapabilities
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing WMTS capabilities failed: %w", err)
+	}
+
+	caps := &Capabilities{
+		Title:        strings.TrimSpace(doc.Title),
+		Provider:     strings.TrimSpace(doc.Provider.Name),
+		ProviderSite: strings.TrimSpace(doc.Provider.Site.Href),
+	}
+	for _, op := range doc.Operations {
+		if op.Name != "GetTile" {
+			continue
+		}
+		for _, get := range op.Gets {
+			// Gets without constraints accept any encoding
+			if len(get.Encodings) == 0 || slices.ContainsFunc(get.Encodings, isKVP) {
+				caps.GetTileURL = strings.TrimSpace(get.Href)
+				break
+			}
+		}
+	}
+
+	for _, x := range doc.Layers {
+		l := &Layer{
+			Identifier: strings.TrimSpace(x.Identifier),
+			Title:      strings.TrimSpace(x.Title),
+			Abstract:   strings.TrimSpace(x.Abstract),
+		}
+		for _, f := range x.Formats {
+			l.Formats = append(l.Formats, strings.TrimSpace(f))
+		}
+		for _, s := range x.Styles {
+			l.Styles = append(l.Styles, Style{
+				Identifier: strings.TrimSpace(s.Identifier),
+				Title:      strings.TrimSpace(s.Title),
+				IsDefault:  s.IsDefault == "true" || s.IsDefault == "1",
+			})
+		}
+		for _, d := range x.Dimensions {
+			dim := Dimension{Identifier: strings.TrimSpace(d.Identifier), Default: strings.TrimSpace(d.Default)}
+			for _, v := range d.Values {
+				dim.Values = append(dim.Values, strings.TrimSpace(v))
+			}
+			l.Dimensions = append(l.Dimensions, dim)
+		}
+		for _, xl := range x.Links {
+			link := TileMatrixSetLink{TileMatrixSet: strings.TrimSpace(xl.TileMatrixSet)}
+			for _, lim := range xl.Limits {
+				link.Limits = append(link.Limits, TileMatrixLimits{
+					TileMatrix: strings.TrimSpace(lim.TileMatrix),
+					MinTileRow: lim.MinTileRow, MaxTileRow: lim.MaxTileRow,
+					MinTileCol: lim.MinTileCol, MaxTileCol: lim.MaxTileCol,
+				})
+			}
+			l.Links = append(l.Links, link)
+		}
+		for _, r := range x.Resources {
+			l.Resources = append(l.Resources, ResourceURL{
+				Format:       strings.TrimSpace(r.Format),
+				ResourceType: strings.TrimSpace(r.ResourceType),
+				Template:     strings.TrimSpace(r.Template),
+			})
+		}
+		if x.BBox != nil {
+			west, south, okLower := parsePair(x.BBox.Lower)
+			east, north, okUpper := parsePair(x.BBox.Upper)
+			if okLower && okUpper {
+				l.HasBounds = true
+				l.WestLon, l.SouthLat, l.EastLon, l.NorthLat = west, south, east, north
+			}
+		}
+		caps.Layers = append(caps.Layers, l)
+	}
+
+	for _, x := range doc.TileMatrixSets {
+		set := &TileMatrixSet{
+			Identifier:        strings.TrimSpace(x.Identifier),
+			SupportedCRS:      strings.TrimSpace(x.SupportedCRS),
+			WellKnownScaleSet: strings.TrimSpace(x.WellKnownScaleSet),
+		}
+		for _, m := range x.TileMatrices {
+			topLeftX, topLeftY, ok := parsePair(m.TopLeftCorner)
+			if !ok {
+				return nil, fmt.Errorf("invalid TopLeftCorner %q in tile matrix %s", m.TopLeftCorner, m.Identifier)
+			}
+			set.TileMatrices = append(set.TileMatrices, TileMatrix{
+				Identifier:       strings.TrimSpace(m.Identifier),
+				ScaleDenominator: m.ScaleDenominator,
+				TopLeftX:         topLeftX,
+				TopLeftY:         topLeftY,
+				TileWidth:        m.TileWidth,
+				TileHeight:       m.TileHeight,
+				MatrixWidth:      m.MatrixWidth,
+				MatrixHeight:     m.MatrixHeight,
+			})
+		}
+		caps.TileMatrixSets = append(caps.TileMatrixSets, set)
+	}
+	return caps, nil
+}
+
+func isKVP(encoding string) bool {
+	return strings.EqualFold(strings.TrimSpace(encoding), "KVP")
+}
+
+// parsePair parses the two space-separated numbers of an OWS corner
+func parsePair(s string) (float64, float64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	a, errA := strconv.ParseFloat(fields[0], 64)
+	b, errB := strconv.ParseFloat(fields[1], 64)
+	return a, b, errA == nil && errB == nil
+}
+
+// CapabilitiesURL returns the GetCapabilities URL for a service. URLs of
+// RESTful capabilities documents, such as .../1.0.0/WMTSCapabilities.xml, and
+// URLs that already request capabilities are returned unchanged.
+func CapabilitiesURL(serviceURL string) (string, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing WMTS URL failed: %w", err)
+	}
+	if strings.HasSuffix(strings.ToLower(u.Path), ".xml") {
+		return serviceURL, nil
+	}
+	q := u.Query()
+	for key := range q {
+		switch strings.ToUpper(key) {
+		case "SERVICE", "VERSION", "REQUEST":
+			q.Del(key)
+		}
+	}
+	q.Set("SERVICE", "WMTS")
+	q.Set("VERSION", Version)
+	q.Set("REQUEST", "GetCapabilities")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// FetchCapabilities downloads and parses a service's capabilities. A nil
+// client means http.DefaultClient.
+func FetchCapabilities(ctx context.Context, client *http.Client, serviceURL string) (*Capabilities, error) {
+	capsURL, err := CapabilitiesURL(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", capsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating capabilities request failed: %w", err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("capabilities request returned status: %d", resp.StatusCode)
+	}
+	return ParseCapabilities(resp.Body)
+}
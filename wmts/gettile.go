@@ -0,0 +1,166 @@
+package wmts
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Web Mercator tile grid constants
+const (
+	// webMercatorExtent is half the width of the projected world in meters
+	webMercatorExtent = 20037508.342789244
+
+	// googleScale0 is the scale denominator of zoom 0 in the
+	// GoogleMapsCompatible set: one 256px tile of 0.28 mm pixels
+	googleScale0 = 559082264.0287178
+
+	// maxWebMercatorZoom is the deepest zoom level recognized
+	maxWebMercatorZoom = 30
+)
+
+// GetTile describes a GetTile request
+type GetTile struct {
+	Layer         string
+	Style         string
+	Format        string
+	TileMatrixSet string
+	TileMatrix    string
+	TileRow       int
+	TileCol       int
+
+	// Dimension values by identifier, such as Time
+	Dimensions map[string]string
+}
+
+// KVPURL returns the request URL for a KVP endpoint. Parameters already in
+// baseURL, such as an API key, are kept.
+func (t GetTile) KVPURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing WMTS URL failed: %w", err)
+	}
+
+	params := map[string]string{
+		"SERVICE":       "WMTS",
+		"VERSION":       Version,
+		"REQUEST":       "GetTile",
+		"LAYER":         t.Layer,
+		"STYLE":         t.Style,
+		"FORMAT":        t.Format,
+		"TILEMATRIXSET": t.TileMatrixSet,
+		"TILEMATRIX":    t.TileMatrix,
+		"TILEROW":       strconv.Itoa(t.TileRow),
+		"TILECOL":       strconv.Itoa(t.TileCol),
+	}
+	for name, value := range t.Dimensions {
+		params[strings.ToUpper(name)] = value
+	}
+
+	q := u.Query()
+	// Keys are case-insensitive, so drop any that would clash
+	for key := range q {
+		if _, ok := params[strings.ToUpper(key)]; ok {
+			q.Del(key)
+		}
+	}
+	for key, value := range params {
+		q.Set(key, value)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// RESTURL expands a ResourceURL template. Unknown variables are left as they are.
+func (t GetTile) RESTURL(template string) string {
+	values := map[string]string{
+		"style":         t.Style,
+		"tilematrixset": t.TileMatrixSet,
+		"tilematrix":    t.TileMatrix,
+		"tilerow":       strconv.Itoa(t.TileRow),
+		"tilecol":       strconv.Itoa(t.TileCol),
+	}
+	for name, value := range t.Dimensions {
+		values[strings.ToLower(name)] = value
+	}
+	return ExpandTemplate(template, values)
+}
+
+// ExpandTemplate replaces {Name} variables in a template with values keyed by
+// lowercase name. Variable names are matched case-insensitively.
+func ExpandTemplate(template string, values map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(template[:start])
+		if value, ok := values[strings.ToLower(template[start+1:end])]; ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
+
+// IsWebMercatorCRS reports whether a CRS identifier names Web Mercator. It
+// accepts EPSG:3857, OGC URNs and URIs, and the older codes for the same
+// projection.
+func IsWebMercatorCRS(crs string) bool {
+	code := crs[strings.LastIndexAny(crs, ":/")+1:]
+	switch code {
+	case "3857", "900913", "3785", "102100", "102113":
+		return true
+	}
+	return false
+}
+
+// WebMercatorZooms maps slippy map zoom levels to the tile matrices of the
+// set that line up with the standard Web Mercator tile grid. Tile rows and
+// columns in those matrices equal slippy map y and x. Matrices of 512px tiles
+// count when they cover the same grid at twice the resolution. It returns nil
+// if the set isn't in Web Mercator.
+func (s *TileMatrixSet) WebMercatorZooms() map[int]*TileMatrix {
+	if !IsWebMercatorCRS(s.SupportedCRS) {
+		return nil
+	}
+
+	var zooms map[int]*TileMatrix
+	for i := range s.TileMatrices {
+		m := &s.TileMatrices[i]
+		if (m.TileWidth != 256 && m.TileWidth != 512) || m.TileHeight != m.TileWidth || m.ScaleDenominator <= 0 {
+			continue
+		}
+		// Corners are given to varying precision; a meter is far below a pixel
+		if math.Abs(m.TopLeftX+webMercatorExtent) > 1 || math.Abs(m.TopLeftY-webMercatorExtent) > 1 {
+			continue
+		}
+		// Bigger tiles have proportionally smaller pixels at the same zoom
+		zf := math.Log2(googleScale0 * 256 / float64(m.TileWidth) / m.ScaleDenominator)
+		zoom := int(math.Round(zf))
+		if math.Abs(zf-float64(zoom)) > 0.01 || zoom < 0 || zoom > maxWebMercatorZoom {
+			continue
+		}
+		// Regional sets may cut the matrix short, but never past the world
+		if m.MatrixWidth > 1<<zoom || m.MatrixHeight > 1<<zoom {
+			continue
+		}
+		if zooms == nil {
+			zooms = make(map[int]*TileMatrix)
+		}
+		zooms[zoom] = m
+	}
+	return zooms
+}
@@ -0,0 +1,281 @@
+package wmts
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const capabilitiesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Capabilities xmlns="http://www.opengis.net/wmts/1.0" xmlns:ows="http://www.opengis.net/ows/1.1" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.0.0">
+  <ows:ServiceIdentification><ows:Title>State Imagery</ows:Title></ows:ServiceIdentification>
+  <ows:ServiceProvider>
+    <ows:ProviderName>State GIS Office</ows:ProviderName>
+    <ows:ProviderSite xlink:href="https://gis.example.gov/"/>
+  </ows:ServiceProvider>
+  <ows:OperationsMetadata>
+    <ows:Operation name="GetCapabilities">
+      <ows:DCP><ows:HTTP><ows:Get xlink:href="https://gis.example.gov/wmts?"/></ows:HTTP></ows:DCP>
+    </ows:Operation>
+    <ows:Operation name="GetTile">
+      <ows:DCP><ows:HTTP>
+        <ows:Get xlink:href="https://gis.example.gov/rest/">
+          <ows:Constraint name="GetEncoding"><ows:AllowedValues><ows:Value>RESTful</ows:Value></ows:AllowedValues></ows:Constraint>
+        </ows:Get>
+        <ows:Get xlink:href="https://gis.example.gov/wmts?">
+          <ows:Constraint name="GetEncoding"><ows:AllowedValues><ows:Value>KVP</ows:Value></ows:AllowedValues></ows:Constraint>
+        </ows:Get>
+      </ows:HTTP></ows:DCP>
+    </ows:Operation>
+  </ows:OperationsMetadata>
+  <Contents>
+    <Layer>
+      <ows:Title>Orthoimagery 2023</ows:Title>
+      <ows:Identifier>ortho</ows:Identifier>
+      <ows:WGS84BoundingBox>
+        <ows:LowerCorner>-109.06 36.99</ows:LowerCorner>
+        <ows:UpperCorner>-102.04 41.00</ows:UpperCorner>
+      </ows:WGS84BoundingBox>
+      <Style><ows:Title>Natural</ows:Title><ows:Identifier>natural</ows:Identifier></Style>
+      <Style isDefault="true"><ows:Title>Default</ows:Title><ows:Identifier>default</ows:Identifier></Style>
+      <Format>image/jpeg</Format>
+      <Format>image/png</Format>
+      <Dimension>
+        <ows:Identifier>Time</ows:Identifier>
+        <Default>2023</Default>
+        <Value>2021</Value>
+        <Value>2023</Value>
+      </Dimension>
+      <TileMatrixSetLink><TileMatrixSet>UTM13</TileMatrixSet></TileMatrixSetLink>
+      <TileMatrixSetLink>
+        <TileMatrixSet>GoogleMapsCompatible</TileMatrixSet>
+        <TileMatrixSetLimits>
+          <TileMatrixLimits>
+            <TileMatrix>2</TileMatrix>
+            <MinTileRow>1</MinTileRow><MaxTileRow>1</MaxTileRow>
+            <MinTileCol>0</MinTileCol><MaxTileCol>1</MaxTileCol>
+          </TileMatrixLimits>
+        </TileMatrixSetLimits>
+      </TileMatrixSetLink>
+      <ResourceURL format="image/jpeg" resourceType="tile"
+        template="https://gis.example.gov/rest/ortho/{Style}/{Time}/{TileMatrixSet}/{TileMatrix}/{TileRow}/{TileCol}.jpg"/>
+    </Layer>
+    <Layer>
+      <ows:Title>Counties</ows:Title>
+      <ows:Identifier>counties</ows:Identifier>
+      <Format>image/png</Format>
+      <TileMatrixSetLink><TileMatrixSet>UTM13</TileMatrixSet></TileMatrixSetLink>
+    </Layer>
+    <TileMatrixSet>
+      <ows:Identifier>GoogleMapsCompatible</ows:Identifier>
+      <ows:SupportedCRS>urn:ogc:def:crs:EPSG:6.18.3:3857</ows:SupportedCRS>
+      <WellKnownScaleSet>urn:ogc:def:wkss:OGC:1.0:GoogleMapsCompatible</WellKnownScaleSet>
+      <TileMatrix>
+        <ows:Identifier>0</ows:Identifier>
+        <ScaleDenominator>559082264.0287178</ScaleDenominator>
+        <TopLeftCorner>-20037508.3427892 20037508.3427892</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>1</MatrixWidth><MatrixHeight>1</MatrixHeight>
+      </TileMatrix>
+      <TileMatrix>
+        <ows:Identifier>1</ows:Identifier>
+        <ScaleDenominator>279541132.0143589</ScaleDenominator>
+        <TopLeftCorner>-2.0037508342787E7 2.0037508342787E7</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>2</MatrixWidth><MatrixHeight>2</MatrixHeight>
+      </TileMatrix>
+      <TileMatrix>
+        <ows:Identifier>2</ows:Identifier>
+        <ScaleDenominator>139770566.0071794</ScaleDenominator>
+        <TopLeftCorner>-20037508.3427892 20037508.3427892</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>4</MatrixWidth><MatrixHeight>4</MatrixHeight>
+      </TileMatrix>
+    </TileMatrixSet>
+    <TileMatrixSet>
+      <ows:Identifier>UTM13</ows:Identifier>
+      <ows:SupportedCRS>urn:ogc:def:crs:EPSG::26913</ows:SupportedCRS>
+      <TileMatrix>
+        <ows:Identifier>0</ows:Identifier>
+        <ScaleDenominator>5000000</ScaleDenominator>
+        <TopLeftCorner>100000 4600000</TopLeftCorner>
+        <TileWidth>256</TileWidth><TileHeight>256</TileHeight>
+        <MatrixWidth>3</MatrixWidth><MatrixHeight>2</MatrixHeight>
+      </TileMatrix>
+    </TileMatrixSet>
+  </Contents>
+</Capabilities>`
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities(strings.NewReader(capabilitiesXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if caps.Title != "State Imagery" {
+		t.Errorf("Title = %q", caps.Title)
+	}
+	if caps.Provider != "State GIS Office" || caps.ProviderSite != "https://gis.example.gov/" {
+		t.Errorf("provider = %q, %q", caps.Provider, caps.ProviderSite)
+	}
+	if caps.GetTileURL != "https://gis.example.gov/wmts?" {
+		t.Errorf("GetTileURL = %q; want the KVP endpoint", caps.GetTileURL)
+	}
+	if len(caps.Layers) != 2 || len(caps.TileMatrixSets) != 2 {
+		t.Fatalf("got %d layers and %d tile matrix sets", len(caps.Layers), len(caps.TileMatrixSets))
+	}
+
+	ortho := caps.Layer("ortho")
+	if ortho == nil {
+		t.Fatal("ortho layer not found")
+	}
+	if ortho.Title != "Orthoimagery 2023" || len(ortho.Formats) != 2 {
+		t.Errorf("ortho = %+v", ortho)
+	}
+	if got := ortho.DefaultStyle(); got != "default" {
+		t.Errorf("DefaultStyle = %q; want default", got)
+	}
+	if len(ortho.Dimensions) != 1 || ortho.Dimensions[0].Default != "2023" || len(ortho.Dimensions[0].Values) != 2 {
+		t.Errorf("dimensions = %+v", ortho.Dimensions)
+	}
+	if !ortho.HasBounds || ortho.WestLon != -109.06 || ortho.NorthLat != 41 {
+		t.Errorf("bounds = %v %v %v %v", ortho.WestLon, ortho.SouthLat, ortho.EastLon, ortho.NorthLat)
+	}
+	if got := ortho.TileTemplate("image/png"); got != "" {
+		t.Errorf("TileTemplate(image/png) = %q; want none", got)
+	}
+	if got := ortho.TileTemplate(""); !strings.HasSuffix(got, "{TileRow}/{TileCol}.jpg") {
+		t.Errorf("TileTemplate = %q", got)
+	}
+
+	set := caps.TileMatrixSet("GoogleMapsCompatible")
+	if set == nil || len(set.TileMatrices) != 3 {
+		t.Fatalf("GoogleMapsCompatible = %+v", set)
+	}
+	if m := set.TileMatrices[1]; m.TopLeftX != -20037508.342787 || m.MatrixWidth != 2 {
+		t.Errorf("matrix 1 = %+v", m)
+	}
+}
+
+func TestWebMercatorSet(t *testing.T) {
+	caps, err := ParseCapabilities(strings.NewReader(capabilitiesXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set, link, ok := caps.WebMercatorSet(caps.Layer("ortho"))
+	if !ok || set.Identifier != "GoogleMapsCompatible" {
+		t.Fatalf("ortho: got %v, %v", set, ok)
+	}
+	zooms := set.WebMercatorZooms()
+	for z := 0; z <= 2; z++ {
+		if m := zooms[z]; m == nil || m.Identifier != strconv.Itoa(z) {
+			t.Errorf("zoom %d = %+v", z, m)
+		}
+	}
+	lim, ok := link.MatrixLimits("2")
+	if !ok || lim.MinTileRow != 1 || lim.MaxTileCol != 1 {
+		t.Errorf("limits = %+v, %v", lim, ok)
+	}
+	if _, ok := link.MatrixLimits("1"); ok {
+		t.Error("matrix 1 should be unlimited")
+	}
+
+	if _, _, ok := caps.WebMercatorSet(caps.Layer("counties")); ok {
+		t.Error("counties only has a UTM set")
+	}
+}
+
+func TestIsWebMercatorCRS(t *testing.T) {
+	for crs, want := range map[string]bool{
+		"EPSG:3857":                                  true,
+		"urn:ogc:def:crs:EPSG::3857":                 true,
+		"urn:ogc:def:crs:EPSG:6.18.3:3857":           true,
+		"http://www.opengis.net/def/crs/EPSG/0/3857": true,
+		"EPSG:900913":                                true,
+		"EPSG:102100":                                true,
+		"urn:ogc:def:crs:EPSG::4326":                 false,
+		"EPSG:26913":                                 false,
+	} {
+		if got := IsWebMercatorCRS(crs); got != want {
+			t.Errorf("IsWebMercatorCRS(%q) = %v; want %v", crs, got, want)
+		}
+	}
+}
+
+func TestGetTileURLs(t *testing.T) {
+	req := GetTile{
+		Layer: "ortho", Style: "default", Format: "image/jpeg",
+		TileMatrixSet: "GoogleMapsCompatible", TileMatrix: "12",
+		TileRow: 1551, TileCol: 852,
+		Dimensions: map[string]string{"Time": "2023"},
+	}
+
+	rest := req.RESTURL("https://gis.example.gov/rest/ortho/{style}/{Time}/{TileMatrixSet}/{TileMatrix}/{TileRow}/{TileCol}.jpg?{Unknown}")
+	want := "https://gis.example.gov/rest/ortho/default/2023/GoogleMapsCompatible/12/1551/852.jpg?{Unknown}"
+	if rest != want {
+		t.Errorf("RESTURL = %q; want %q", rest, want)
+	}
+
+	kvp, err := req.KVPURL("https://gis.example.gov/wmts?token=abc&request=GetCapabilities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(kvp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	for key, value := range map[string]string{
+		"token":         "abc",
+		"SERVICE":       "WMTS",
+		"REQUEST":       "GetTile",
+		"LAYER":         "ortho",
+		"TILEMATRIXSET": "GoogleMapsCompatible",
+		"TILEMATRIX":    "12",
+		"TILEROW":       "1551",
+		"TILECOL":       "852",
+		"TIME":          "2023",
+	} {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q; want %q", key, q.Get(key), value)
+		}
+	}
+	if _, ok := q["request"]; ok {
+		t.Error("lowercase request parameter from the base URL was kept")
+	}
+}
+
+func TestCapabilitiesURL(t *testing.T) {
+	rest := "https://gis.example.gov/rest/1.0.0/WMTSCapabilities.xml"
+	if got, _ := CapabilitiesURL(rest); got != rest {
+		t.Errorf("CapabilitiesURL(%q) = %q", rest, got)
+	}
+	got, err := CapabilitiesURL("https://gis.example.gov/wmts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://gis.example.gov/wmts?REQUEST=GetCapabilities&SERVICE=WMTS&VERSION=1.0.0"; got != want {
+		t.Errorf("CapabilitiesURL = %q; want %q", got, want)
+	}
+}
+
+func TestWebMercatorZooms512(t *testing.T) {
+	set := &TileMatrixSet{
+		Identifier:   "WebMercator512",
+		SupportedCRS: "EPSG:3857",
+		TileMatrices: []TileMatrix{
+			// 512px tiles at zoom 3 have half the scale denominator of 256px ones
+			{Identifier: "z3", ScaleDenominator: googleScale0 / 16, TopLeftX: -webMercatorExtent, TopLeftY: webMercatorExtent,
+				TileWidth: 512, TileHeight: 512, MatrixWidth: 8, MatrixHeight: 8},
+			// Off the grid: 512px tiles at the 256px scale of zoom 3
+			{Identifier: "bad", ScaleDenominator: googleScale0 / 8, TopLeftX: -webMercatorExtent, TopLeftY: webMercatorExtent,
+				TileWidth: 512, TileHeight: 512, MatrixWidth: 8, MatrixHeight: 8},
+		},
+	}
+	zooms := set.WebMercatorZooms()
+	if len(zooms) != 1 || zooms[3] == nil || zooms[3].Identifier != "z3" {
+		t.Errorf("zooms = %v", zooms)
+	}
+}